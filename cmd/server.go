@@ -22,7 +22,11 @@ var RestCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		log.Info("Starting REST API server...")
 
-		app := api.Setup()
+		// stops the outbox relay on shutdown
+		relayCtx, stopRelay := context.WithCancel(context.Background())
+		defer stopRelay()
+
+		app := api.Setup(relayCtx)
 
 		done := make(chan os.Signal, 1)
 		signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
@@ -35,6 +39,7 @@ var RestCmd = &cobra.Command{
 
 		sig := <-done
 		log.Info("Signal received: %s. Shutting down server gracefully...", sig)
+		stopRelay()
 
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
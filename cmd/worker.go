@@ -10,6 +10,7 @@ import (
 
 	"github.com/spf13/cobra"
 	config "github.com/wildan3105/converto/configs"
+	"github.com/wildan3105/converto/pkg/converter"
 	"github.com/wildan3105/converto/pkg/infrastructure/filestorage"
 	"github.com/wildan3105/converto/pkg/infrastructure/mongodb"
 	"github.com/wildan3105/converto/pkg/infrastructure/rabbitmq"
@@ -47,12 +48,19 @@ var WorkerCmd = &cobra.Command{
 		}
 
 		consumer := rabbitmq.NewConsumer(connManager)
+		publisher := rabbitmq.NewPublisher(connManager)
 		conversionRepo := repository.NewMongoRepository(mongoClient, config.AppConfig.MongoDbName)
 		storage := filestorage.NewLocalFileStorage(config.AppConfig.BaseDirectory)
 
-		worker := rabbitMQWorker.NewWorker(consumer, conversionRepo, storage)
+		converters := converter.NewDefaultRegistry()
 
-		workerErr := worker.Start(ctx, config.AppConfig.RabbitMQQueueName)
+		worker := rabbitMQWorker.NewWorker(consumer, publisher, conversionRepo, storage, converters)
+
+		lockRepo := repository.NewMongoLockRepository(mongoClient, config.AppConfig.MongoDbName)
+		reaper := rabbitMQWorker.NewReaper(conversionRepo, lockRepo, publisher)
+		go reaper.Run(ctx)
+
+		workerErr := worker.Start(ctx, config.AppConfig.RabbitMQQueueName, config.AppConfig.WorkerConcurrency)
 
 		if workerErr != nil {
 			externalLog.Fatalf("Failed when consuming messages: %v", workerErr)
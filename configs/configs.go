@@ -2,6 +2,7 @@ package config
 
 import (
 	"log"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/kelseyhightower/envconfig"
@@ -12,14 +13,45 @@ import (
 type Config struct {
 	Port                 string `envconfig:"PORT" default:"3000"`
 	Environment          string `envconfig:"ENVIRONMENT" default:"local"`
+	AdminToken           string `envconfig:"ADMIN_TOKEN"`
 	MongoURI             string `envconfig:"MONGO_URI" required:"true"`
 	MongoDbName          string `envconfig:"DB_NAME" required:"true"`
 	MongoDbCollection    string `envconfig:"COLLECTION_NAME" required:"true"`
+	UploadCollection     string `envconfig:"UPLOAD_COLLECTION_NAME" default:"uploads"`
+	BatchCollection      string `envconfig:"BATCH_COLLECTION_NAME" default:"batches"`
+	LockCollection       string `envconfig:"LOCK_COLLECTION_NAME" default:"locks"`
 	RabbitMQURI          string `envconfig:"RABBITMQ_URI" required:"true"`
 	RabbitMQExchangeName string `envconfig:"RABBITMQ_EXCHANGE_NAME" required:"true"`
 	RabbitMQRoutingKey   string `envconfig:"RABBITMQ_ROUTING_KEY" required:"true"`
 	RabbitMQQueueName    string `envconfig:"RABBITMQ_QUEUE_NAME" required:"true"`
 	BaseDirectory        string `envconfig:"BASE_DIRECTORY" required:"true"`
+
+	ConverterStepCommand      string        `envconfig:"CONVERTER_STEP_COMMAND"`
+	ConverterIgesCommand      string        `envconfig:"CONVERTER_IGES_COMMAND"`
+	ConverterTimeout          time.Duration `envconfig:"CONVERTER_TIMEOUT" default:"10m"`
+	ConverterScratchDirectory string        `envconfig:"CONVERTER_SCRATCH_DIRECTORY"`
+
+	WorkerConcurrency  int           `envconfig:"WORKER_CONCURRENCY" default:"4"`
+	CancelPollInterval time.Duration `envconfig:"CANCEL_POLL_INTERVAL" default:"2s"`
+	LeaseDuration      time.Duration `envconfig:"LEASE_DURATION" default:"1m"`
+	ReaperInterval     time.Duration `envconfig:"REAPER_INTERVAL" default:"30s"`
+
+	RetryMaxAttempts int           `envconfig:"RETRY_MAX_ATTEMPTS" default:"3"`
+	RetryBaseDelay   time.Duration `envconfig:"RETRY_BASE_DELAY" default:"10s"`
+	RetryMaxDelay    time.Duration `envconfig:"RETRY_MAX_DELAY" default:"5m"`
+
+	OutboxPollInterval time.Duration `envconfig:"OUTBOX_POLL_INTERVAL" default:"1s"`
+	OutboxMaxAttempts  int           `envconfig:"OUTBOX_MAX_ATTEMPTS" default:"5"`
+
+	BatchMaxEntries          int     `envconfig:"BATCH_MAX_ENTRIES" default:"100"`
+	BatchMaxEntrySize        int64   `envconfig:"BATCH_MAX_ENTRY_SIZE" default:"268435456"`
+	BatchMaxTotalSize        int64   `envconfig:"BATCH_MAX_TOTAL_SIZE" default:"1073741824"`
+	BatchMaxCompressionRatio float64 `envconfig:"BATCH_MAX_COMPRESSION_RATIO" default:"100"`
+
+	PreviewWidth     int     `envconfig:"PREVIEW_WIDTH" default:"512"`
+	PreviewHeight    int     `envconfig:"PREVIEW_HEIGHT" default:"512"`
+	PreviewAzimuth   float64 `envconfig:"PREVIEW_AZIMUTH" default:"45"`
+	PreviewElevation float64 `envconfig:"PREVIEW_ELEVATION" default:"35.264"`
 }
 
 var AppConfig Config
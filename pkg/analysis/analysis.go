@@ -0,0 +1,112 @@
+// Package analysis computes the physical properties of a model used for quoting:
+// bounding box, volume, surface area, element counts and watertightness.
+package analysis
+
+import (
+	"math"
+	"time"
+
+	"github.com/wildan3105/converto/pkg/domain"
+	"github.com/wildan3105/converto/pkg/scene"
+)
+
+// Analyze measures the scene in millimeters.
+//
+// The volume is the absolute sum of the signed tetrahedron volumes of every
+// triangle, which is exact for closed, consistently oriented meshes and an
+// approximation otherwise. A body is watertight when, after merging vertices at
+// identical positions, every edge is shared by exactly two triangles traversing
+// it in opposite directions. The scene is watertight when all its bodies are.
+func Analyze(s *scene.Scene) domain.Analysis {
+	factor := s.Units.Millimeters()
+	if factor == 0 {
+		factor = 1
+	}
+
+	result := domain.Analysis{
+		Units:         string(scene.UnitsMillimeter),
+		TriangleCount: s.TriangleCount(),
+		VertexCount:   s.VertexCount(),
+		BodyCount:     len(s.Bodies),
+		Watertight:    len(s.Bodies) > 0,
+		AnalyzedAt:    time.Now(),
+	}
+
+	min := scene.Vec3{math.Inf(1), math.Inf(1), math.Inf(1)}
+	max := scene.Vec3{math.Inf(-1), math.Inf(-1), math.Inf(-1)}
+	var volume, area float64
+
+	for b := range s.Bodies {
+		body := &s.Bodies[b]
+		edges := newEdgeSet()
+		var bodyVolume float64
+
+		body.ForEachTriangle(func(p0, p1, p2 scene.Vec3) {
+			p0, p1, p2 = p0.Scale(factor), p1.Scale(factor), p2.Scale(factor)
+
+			min = min.Min(p0).Min(p1).Min(p2)
+			max = max.Max(p0).Max(p1).Max(p2)
+
+			area += p1.Sub(p0).Cross(p2.Sub(p0)).Length() / 2
+			bodyVolume += p0.Dot(p1.Cross(p2)) / 6
+
+			edges.addTriangle(p0, p1, p2)
+		})
+
+		volume += math.Abs(bodyVolume)
+		if !edges.closed() {
+			result.Watertight = false
+		}
+	}
+
+	if result.TriangleCount > 0 {
+		result.BoundingBox = domain.BoundingBox{
+			Min:  min,
+			Max:  max,
+			Size: max.Sub(min),
+		}
+	}
+	result.Volume = volume
+	result.SurfaceArea = area
+
+	return result
+}
+
+// edge is a directed edge between two vertex positions
+type edge [2]scene.Vec3
+
+// edgeSet counts the directed edges of a body
+type edgeSet struct {
+	counts map[edge]int
+	empty  bool
+}
+
+func newEdgeSet() *edgeSet {
+	return &edgeSet{counts: make(map[edge]int), empty: true}
+}
+
+// addTriangle records the three directed edges of a triangle; degenerate triangles are ignored
+func (e *edgeSet) addTriangle(p0, p1, p2 scene.Vec3) {
+	if p0 == p1 || p1 == p2 || p0 == p2 {
+		return
+	}
+
+	e.empty = false
+	e.counts[edge{p0, p1}]++
+	e.counts[edge{p1, p2}]++
+	e.counts[edge{p2, p0}]++
+}
+
+// closed reports whether every directed edge appears exactly once and is matched by its reverse
+func (e *edgeSet) closed() bool {
+	if e.empty {
+		return false
+	}
+
+	for directed, count := range e.counts {
+		if count != 1 || e.counts[edge{directed[1], directed[0]}] != 1 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,58 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wildan3105/converto/pkg/scene"
+	"github.com/wildan3105/converto/pkg/scene/scenetest"
+)
+
+func TestAnalyzeCube(t *testing.T) {
+	result := Analyze(scenetest.Cube(10))
+
+	assert.Equal(t, "mm", result.Units)
+	assert.InDelta(t, 1000, result.Volume, 1e-9)
+	assert.InDelta(t, 600, result.SurfaceArea, 1e-9)
+	assert.Equal(t, 12, result.TriangleCount)
+	assert.Equal(t, 8, result.VertexCount)
+	assert.Equal(t, 1, result.BodyCount)
+	assert.True(t, result.Watertight)
+	assert.Equal(t, [3]float64{0, 0, 0}, result.BoundingBox.Min)
+	assert.Equal(t, [3]float64{10, 10, 10}, result.BoundingBox.Size)
+}
+
+func TestAnalyzeConvertsUnitsAndAppliesTransforms(t *testing.T) {
+	s := scenetest.Cube(1)
+	s.Units = scene.UnitsCentimeter
+	s.Bodies[0].Transform = scene.Scaling(2)
+	s.Bodies[0].Transform[12] = 5
+
+	result := Analyze(s)
+
+	// a 2 cm cube moved 5 cm along X
+	assert.InDelta(t, 8000, result.Volume, 1e-6)
+	assert.InDelta(t, 2400, result.SurfaceArea, 1e-6)
+	assert.InDeltaSlice(t, []float64{50, 0, 0}, result.BoundingBox.Min[:], 1e-9)
+	assert.InDeltaSlice(t, []float64{70, 20, 20}, result.BoundingBox.Max[:], 1e-9)
+}
+
+func TestAnalyzeOpenMesh(t *testing.T) {
+	s := scenetest.Cube(10)
+	mesh := &s.Bodies[0].Meshes[0]
+	mesh.Indices = mesh.Indices[3:] // remove one triangle of the bottom face
+
+	result := Analyze(s)
+
+	assert.False(t, result.Watertight)
+	assert.Equal(t, 11, result.TriangleCount)
+	assert.InDelta(t, 550, result.SurfaceArea, 1e-9)
+}
+
+func TestAnalyzeEmptyScene(t *testing.T) {
+	result := Analyze(scene.New())
+
+	assert.False(t, result.Watertight)
+	assert.Zero(t, result.Volume)
+	assert.Equal(t, [3]float64{}, result.BoundingBox.Size)
+}
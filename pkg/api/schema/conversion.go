@@ -1,24 +1,109 @@
 package schema
 
 import (
+	"io"
 	"mime/multipart"
 	"time"
 
+	"github.com/wildan3105/converto/pkg/archive"
 	"github.com/wildan3105/converto/pkg/domain"
 )
 
-// CreateConversionRequest defines the payload for creating a conversion
+// CreateConversionRequest defines the payload for creating a conversion.
+// TargetFormat receives the target_format field; the handler reads its repeated or comma-separated
+// values from the form into Targets, checking every format against the converter registry.
+// Targets lists every requested output format with its options; one conversion is created per target.
+// Open reads the uploaded content, which may come from File or from an entry of an uploaded archive.
 type CreateConversionRequest struct {
-	File         *multipart.FileHeader `form:"file" binding:"required"`
-	TargetFormat string                `form:"target_format" binding:"required,oneof=.step .iges .stl .obj"`
+	File         *multipart.FileHeader         `form:"file" binding:"required"`
+	TargetFormat string                        `form:"target_format"`
+	Targets      []ConversionTarget            `form:"-"`
+	Open         func() (io.ReadCloser, error) `form:"-"`
 	FileSize     int64
 	FileName     string
+	SourceFormat string
 }
 
+// ConversionTarget is one output format requested for an upload, with the options for its converter
+type ConversionTarget struct {
+	Format  string
+	Options map[string]any
+}
+
+// CreateConversionResponse describes the upload and its conversions.
+// ID is only set when a single target format was requested.
 type CreateConversionResponse struct {
-	ID      string                  `json:"id"`
-	Status  domain.ConversionStatus `json:"status"`
-	Message string                  `json:"message"`
+	ID          string                  `json:"id,omitempty"`
+	UploadID    string                  `json:"upload_id"`
+	Status      domain.ConversionStatus `json:"status"`
+	Message     string                  `json:"message"`
+	Conversions []CreatedConversion     `json:"conversions"`
+}
+
+// CreatedConversion describes the conversion created for a target format. A conversion that could
+// not be created has no ID, the failed status and the reason in Error.
+type CreatedConversion struct {
+	ID           string                  `json:"id,omitempty"`
+	TargetFormat string                  `json:"target_format"`
+	Status       domain.ConversionStatus `json:"status"`
+	Error        string                  `json:"error,omitempty"`
+}
+
+// UploadResponse reports the combined state of every conversion created from an upload
+type UploadResponse struct {
+	ID               string                  `json:"id"`
+	OriginalFileName string                  `json:"original_file_name"`
+	OriginalFilePath string                  `json:"original_file_path"`
+	SourceFormat     string                  `json:"source_format,omitempty"`
+	Status           domain.ConversionStatus `json:"status"`
+	Progress         int                     `json:"progress"`
+	Conversions      []ConversionResponse    `json:"conversions"`
+	CreatedAt        time.Time               `json:"created_at"`
+}
+
+// CreateBatchRequest defines the payload for creating a batch: every file of the request, or of
+// the archives it contained, in order. Entries that cannot be converted carry the reason in Error
+// and no Conversion.
+type CreateBatchRequest struct {
+	Targets []ConversionTarget
+	Entries []BatchEntryRequest
+}
+
+type BatchEntryRequest struct {
+	Name       string
+	Conversion *CreateConversionRequest
+	Error      string
+}
+
+// BatchResponse describes a batch, its entries and a summary of their conversions
+type BatchResponse struct {
+	ID        string                  `json:"id"`
+	Status    domain.ConversionStatus `json:"status"`
+	Progress  int                     `json:"progress"`
+	Message   string                  `json:"message,omitempty"`
+	Summary   BatchSummary            `json:"summary"`
+	Entries   []BatchEntryResponse    `json:"entries"`
+	CreatedAt time.Time               `json:"created_at"`
+}
+
+// BatchSummary counts the entries of a batch and its conversions by status
+type BatchSummary struct {
+	Entries    int `json:"entries"`
+	Rejected   int `json:"rejected"`
+	Total      int `json:"total"`
+	Pending    int `json:"pending"`
+	InProgress int `json:"in_progress"`
+	Completed  int `json:"completed"`
+	Failed     int `json:"failed"`
+	Cancelled  int `json:"cancelled"`
+}
+
+type BatchEntryResponse struct {
+	Name        string                  `json:"name"`
+	UploadID    string                  `json:"upload_id,omitempty"`
+	Error       string                  `json:"error,omitempty"`
+	Status      domain.ConversionStatus `json:"status,omitempty"`
+	Conversions []CreatedConversion     `json:"conversions,omitempty"`
 }
 
 type ListConversionsResponse struct {
@@ -28,22 +113,81 @@ type ListConversionsResponse struct {
 }
 
 type ConversionResponse struct {
-	ID                string                  `json:"id"`
-	Status            domain.ConversionStatus `json:"status"`
-	Progress          int                     `json:"progress"`
-	OriginalFilePath  string                  `json:"original_file_path"`
-	ConvertedFilePath string                  `json:"converted_file_path,omitempty"`
+	ID                string                   `json:"id"`
+	UploadID          string                   `json:"upload_id,omitempty"`
+	TargetFormat      string                   `json:"target_format"`
+	Status            domain.ConversionStatus  `json:"status"`
+	Progress          int                      `json:"progress"`
+	CancelRequested   bool                     `json:"cancel_requested,omitempty"`
+	Error             *domain.ConversionError  `json:"error,omitempty"`
+	Attempts          []domain.Attempt         `json:"attempts,omitempty"`
+	Options           map[string]any           `json:"options,omitempty"`
+	OriginalFilePath  string                   `json:"original_file_path"`
+	ConvertedFilePath string                   `json:"converted_file_path,omitempty"`
+	PreviewFilePath   string                   `json:"preview_file_path,omitempty"`
+	Artifacts         []domain.Artifact        `json:"artifacts,omitempty"`
+	Validation        *domain.ValidationReport `json:"validation,omitempty"`
+	Analysis          *domain.Analysis         `json:"analysis,omitempty"`
 }
 
-type GetFileByConversionId struct {
-	Path     string
+// ArchiveDownload is a ZIP archive ready to be streamed: Write produces its content on the fly
+type ArchiveDownload struct {
 	FileName string
+	Write    func(w io.Writer) error
+}
+
+// ArchiveManifest is written as manifest.json at the end of a download archive
+type ArchiveManifest struct {
+	Type        string                      `json:"type"`
+	ID          string                      `json:"id"`
+	CreatedAt   time.Time                   `json:"created_at"`
+	Conversions []ArchiveManifestConversion `json:"conversions"`
+}
+
+type ArchiveManifestConversion struct {
+	ID           string                  `json:"id"`
+	OriginalName string                  `json:"original_name"`
+	TargetFormat string                  `json:"target_format"`
+	Files        []archive.ManifestEntry `json:"files"`
+}
+
+type GetFileByConversionId struct {
+	Path        string
+	FileName    string
+	ContentType string
 }
 
+// ConversionEvent is the message of a conversion job. Attempt counts the runs of the job since it was
+// created or manually retried, starting at 1; events published without it are first attempts.
 type ConversionEvent struct {
 	JobID        string
 	ConversionID string
 	Source       domain.JobSource
+	Options      map[string]any `json:",omitempty"`
+	Attempt      int            `json:",omitempty"`
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
 }
+
+// DeadLetterResponse describes a message of the dead-letter queue. Body is the message content,
+// embedded as JSON when it is valid JSON and as a string otherwise.
+type DeadLetterResponse struct {
+	ID             string         `json:"id"`
+	Reason         string         `json:"reason"`
+	Error          string         `json:"error"`
+	DeadLetteredAt time.Time      `json:"dead_lettered_at"`
+	ConversionID   string         `json:"conversion_id,omitempty"`
+	Attempt        int            `json:"attempt,omitempty"`
+	Headers        map[string]any `json:"headers,omitempty"`
+	Body           any            `json:"body"`
+}
+
+type ListDeadLettersResponse struct {
+	Total int                  `json:"total"`
+	Limit int                  `json:"limit"`
+	Data  []DeadLetterResponse `json:"data"`
+}
+
+type PurgeDeadLettersResponse struct {
+	Purged int `json:"purged"`
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"log"
 	"time"
 
@@ -8,15 +9,18 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/logger"
 
 	config "github.com/wildan3105/converto/configs"
+	"github.com/wildan3105/converto/pkg/converter"
 	"github.com/wildan3105/converto/pkg/handler"
 	"github.com/wildan3105/converto/pkg/infrastructure/filestorage"
 	"github.com/wildan3105/converto/pkg/infrastructure/mongodb"
 	"github.com/wildan3105/converto/pkg/infrastructure/rabbitmq"
+	"github.com/wildan3105/converto/pkg/outbox"
 	"github.com/wildan3105/converto/pkg/repository"
 	"github.com/wildan3105/converto/pkg/service"
 )
 
-func Setup() *fiber.App {
+// Setup connects to the dependencies and creates the app. The outbox relay runs until ctx is done.
+func Setup(ctx context.Context) *fiber.App {
 	app := fiber.New(fiber.Config{
 		BodyLimit:    1024 * 1024 * 1024, // 1 GB
 		ReadTimeout:  20 * time.Second,
@@ -43,13 +47,30 @@ func Setup() *fiber.App {
 	publisher := rabbitmq.NewPublisher(connManager)
 
 	conversionRepo := repository.NewMongoRepository(mongoClient, config.AppConfig.MongoDbName)
+	uploadRepo := repository.NewMongoUploadRepository(mongoClient, config.AppConfig.MongoDbName)
+	batchRepo := repository.NewMongoBatchRepository(mongoClient, config.AppConfig.MongoDbName)
 	storage := filestorage.NewLocalFileStorage(config.AppConfig.BaseDirectory)
 
-	conversionService := service.NewConversionService(conversionRepo, publisher, storage)
+	converters := converter.NewDefaultRegistry()
+
+	if err := conversionRepo.EnsureIndexes(ctx); err != nil {
+		log.Fatalf("Failed to create conversion indexes: %v", err)
+	}
+
+	// the relay publishes the jobs of the conversions created by the API until the server shuts down
+	lockRepo := repository.NewMongoLockRepository(mongoClient, config.AppConfig.MongoDbName)
+	relay := outbox.NewRelay(conversionRepo, lockRepo, publisher)
+	go relay.Run(ctx)
+
+	conversionService := service.NewConversionService(conversionRepo, uploadRepo, batchRepo, storage, converters)
 	healthService := service.NewHealthService(mongoClient, connManager)
 
+	deadLetters := rabbitmq.NewDeadLetterQueue(connManager, config.AppConfig.RabbitMQExchangeName, config.AppConfig.RabbitMQRoutingKey, config.AppConfig.RabbitMQQueueName)
+	deadLetterService := service.NewDeadLetterService(deadLetters, conversionService)
+
 	conversionHandler := handler.NewConversionHandler(conversionService)
 	healthHandler := handler.NewHealthHandler(healthService)
+	deadLetterHandler := handler.NewDeadLetterHandler(deadLetterService)
 
 	api := app.Group("/api")
 
@@ -59,7 +80,27 @@ func Setup() *fiber.App {
 	v1.Post("/conversions", conversionHandler.CreateConversion)
 	v1.Get("/conversions", conversionHandler.GetConversions)
 	v1.Get("/conversions/:id", conversionHandler.GetConversionByID)
+	v1.Post("/conversions/:id/cancel", conversionHandler.CancelConversion)
+	v1.Post("/conversions/:id/retry", conversionHandler.RetryConversion)
 	v1.Get("/conversions/:id/files", conversionHandler.GetFileByConversionId)
+	v1.Get("/conversions/:id/analysis", conversionHandler.GetAnalysisByConversionID)
+	v1.Get("/conversions/:id/archive", conversionHandler.GetArchiveByConversionID)
+	v1.Get("/uploads/:id", conversionHandler.GetUploadByID)
+	v1.Post("/batches", conversionHandler.CreateBatch)
+	v1.Get("/batches/:id", conversionHandler.GetBatchByID)
+	v1.Get("/batches/:id/archive", conversionHandler.GetArchiveByBatchID)
+
+	// the admin endpoints are only served when a token is configured to guard them
+	if config.AppConfig.AdminToken != "" {
+		admin := v1.Group("/admin", handler.RequireAdminToken(config.AppConfig.AdminToken))
+		admin.Get("/dead-letters", deadLetterHandler.ListDeadLetters)
+		admin.Delete("/dead-letters", deadLetterHandler.PurgeDeadLetters)
+		admin.Get("/dead-letters/:id", deadLetterHandler.GetDeadLetter)
+		admin.Delete("/dead-letters/:id", deadLetterHandler.DeleteDeadLetter)
+		admin.Post("/dead-letters/:id/replay", deadLetterHandler.ReplayDeadLetter)
+	} else {
+		log.Println("ADMIN_TOKEN is not set, admin endpoints are disabled")
+	}
 
 	return app
 }
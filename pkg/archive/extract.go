@@ -0,0 +1,184 @@
+// Package archive reads uploaded ZIP archives safely and writes download archives.
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+var (
+	// ErrUnsafePath is returned for entries whose name could escape the extraction directory
+	ErrUnsafePath = errors.New("unsafe entry path")
+	// ErrTooManyEntries is returned when an archive holds more files than allowed
+	ErrTooManyEntries = errors.New("too many entries")
+	// ErrEntryTooLarge is returned when an entry expands beyond the allowed size
+	ErrEntryTooLarge = errors.New("entry too large")
+	// ErrArchiveTooLarge is returned when the entries together expand beyond the allowed size
+	ErrArchiveTooLarge = errors.New("archive too large")
+	// ErrCompressionRatio is returned for entries compressed more than a genuine model would be
+	ErrCompressionRatio = errors.New("suspicious compression ratio")
+)
+
+// zipMagic starts the first local file header of a ZIP archive
+var zipMagic = []byte("PK\x03\x04")
+
+// Limits bounds what an archive may expand to, to protect against zip bombs
+type Limits struct {
+	MaxEntries          int
+	MaxEntrySize        int64
+	MaxTotalSize        int64
+	MaxCompressionRatio float64
+}
+
+// Entry is a regular file of a ZIP archive whose size has been verified
+type Entry struct {
+	// Name is the cleaned, slash-separated path of the entry inside the archive
+	Name string
+	// Size is the uncompressed size, measured while verifying the entry
+	Size int64
+	file *zip.File
+}
+
+// IsZip reports whether the leading bytes of a file are those of a ZIP archive
+func IsZip(header []byte) bool {
+	return bytes.HasPrefix(header, zipMagic)
+}
+
+// Open returns the uncompressed content of the entry. Reading stops with ErrEntryTooLarge
+// should the content differ from the size verified by ReadZip.
+func (e Entry) Open() (io.ReadCloser, error) {
+	rc, err := e.file.Open()
+	if err != nil {
+		return nil, err
+	}
+	return &limitedReadCloser{ReadCloser: rc, remaining: e.Size}, nil
+}
+
+// ReadZip lists the regular files of a ZIP archive. Directories, macOS resource forks and
+// hidden files are skipped. The archive is rejected when an entry name is absolute or climbs
+// out of the archive, when an entry is a symbolic link, or when the limits are exceeded. Sizes
+// are verified by decompressing every entry rather than trusted from the headers.
+func ReadZip(r io.ReaderAt, size int64, limits Limits) ([]Entry, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("invalid zip archive: %w", err)
+	}
+
+	var entries []Entry
+	var total int64
+
+	for _, f := range zr.File {
+		name, err := cleanName(f.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		if f.FileInfo().IsDir() || skipped(name) {
+			continue
+		}
+		if !f.Mode().IsRegular() {
+			return nil, fmt.Errorf("%w: %s is not a regular file", ErrUnsafePath, name)
+		}
+
+		if len(entries) >= limits.MaxEntries {
+			return nil, fmt.Errorf("%w: at most %d files are allowed", ErrTooManyEntries, limits.MaxEntries)
+		}
+
+		if err := checkRatio(name, f.UncompressedSize64, f.CompressedSize64, limits.MaxCompressionRatio); err != nil {
+			return nil, err
+		}
+
+		n, err := measure(f, min(limits.MaxEntrySize, limits.MaxTotalSize-total))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		if n > limits.MaxEntrySize {
+			return nil, fmt.Errorf("%w: %s is larger than %d bytes", ErrEntryTooLarge, name, limits.MaxEntrySize)
+		}
+		if total+n > limits.MaxTotalSize {
+			return nil, fmt.Errorf("%w: content is larger than %d bytes", ErrArchiveTooLarge, limits.MaxTotalSize)
+		}
+		// the header may understate the size, so the ratio is checked again on the measured size
+		if err := checkRatio(name, uint64(n), f.CompressedSize64, limits.MaxCompressionRatio); err != nil {
+			return nil, err
+		}
+
+		total += n
+		entries = append(entries, Entry{Name: name, Size: n, file: f})
+	}
+
+	return entries, nil
+}
+
+// cleanName normalizes an entry name and rejects names that are absolute or leave the archive
+func cleanName(name string) (string, error) {
+	slashed := strings.ReplaceAll(name, "\\", "/")
+	if slashed == "" || strings.ContainsRune(slashed, 0) || strings.HasPrefix(slashed, "/") ||
+		(len(slashed) >= 2 && slashed[1] == ':') {
+		return "", fmt.Errorf("%w: %q", ErrUnsafePath, name)
+	}
+
+	for _, element := range strings.Split(slashed, "/") {
+		if element == ".." {
+			return "", fmt.Errorf("%w: %q", ErrUnsafePath, name)
+		}
+	}
+
+	return path.Clean(slashed), nil
+}
+
+// skipped reports whether an entry is metadata added by archivers rather than a user file
+func skipped(name string) bool {
+	return strings.HasPrefix(name, "__MACOSX/") || strings.HasPrefix(path.Base(name), ".")
+}
+
+// checkRatio rejects entries that expand more than maxRatio times their compressed size
+func checkRatio(name string, uncompressed, compressed uint64, maxRatio float64) error {
+	if uncompressed == 0 {
+		return nil
+	}
+	if compressed == 0 || float64(uncompressed)/float64(compressed) > maxRatio {
+		return fmt.Errorf("%w: %s", ErrCompressionRatio, name)
+	}
+	return nil
+}
+
+// measure decompresses an entry and returns its size, stopping once it exceeds limit
+func measure(f *zip.File, limit int64) (int64, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return 0, err
+	}
+	defer rc.Close()
+
+	return io.Copy(io.Discard, io.LimitReader(rc, limit+1))
+}
+
+// limitedReadCloser fails reads going past the verified size of an entry
+type limitedReadCloser struct {
+	io.ReadCloser
+	remaining int64
+}
+
+func (l *limitedReadCloser) Read(p []byte) (int, error) {
+	if l.remaining <= 0 {
+		// anything beyond the verified size is an error, not a silent truncation
+		var b [1]byte
+		if n, _ := l.ReadCloser.Read(b[:]); n > 0 {
+			return 0, ErrEntryTooLarge
+		}
+		return 0, io.EOF
+	}
+
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.ReadCloser.Read(p)
+	l.remaining -= int64(n)
+	return n, err
+}
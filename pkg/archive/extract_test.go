@@ -0,0 +1,140 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"io/fs"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testLimits = Limits{
+	MaxEntries:          10,
+	MaxEntrySize:        1 << 20,
+	MaxTotalSize:        2 << 20,
+	MaxCompressionRatio: 100,
+}
+
+type testEntry struct {
+	name    string
+	content []byte
+	mode    fs.FileMode
+}
+
+func buildZip(t *testing.T, entries ...testEntry) *bytes.Reader {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, entry := range entries {
+		header := &zip.FileHeader{Name: entry.name, Method: zip.Deflate}
+		if entry.mode != 0 {
+			header.SetMode(entry.mode)
+		}
+		w, err := zw.CreateHeader(header)
+		require.NoError(t, err)
+		_, err = w.Write(entry.content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+
+	return bytes.NewReader(buf.Bytes())
+}
+
+// incompressible returns n bytes that deflate does not shrink
+func incompressible(n int) []byte {
+	b := make([]byte, n)
+	state := uint32(1)
+	for i := range b {
+		state = state*1664525 + 1013904223
+		b[i] = byte(state >> 24)
+	}
+	return b
+}
+
+func TestReadZip(t *testing.T) {
+	r := buildZip(t,
+		testEntry{name: "models/"},
+		testEntry{name: "models/a.shapr", content: incompressible(100)},
+		testEntry{name: "b.stl", content: incompressible(50)},
+		testEntry{name: "__MACOSX/models/._a.shapr", content: []byte("fork")},
+		testEntry{name: "models/.DS_Store", content: []byte("finder")},
+	)
+
+	entries, err := ReadZip(r, r.Size(), testLimits)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	assert.Equal(t, "models/a.shapr", entries[0].Name)
+	assert.Equal(t, int64(100), entries[0].Size)
+	assert.Equal(t, "b.stl", entries[1].Name)
+
+	rc, err := entries[0].Open()
+	require.NoError(t, err)
+	defer rc.Close()
+	content, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	assert.Equal(t, incompressible(100), content)
+}
+
+func TestReadZipRejectsUnsafeArchives(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []testEntry
+		limits  Limits
+		err     error
+	}{
+		{name: "parent directory", entries: []testEntry{{name: "../evil.shapr", content: []byte("x")}}, err: ErrUnsafePath},
+		{name: "nested parent directory", entries: []testEntry{{name: "a/../../evil.shapr", content: []byte("x")}}, err: ErrUnsafePath},
+		{name: "backslashes", entries: []testEntry{{name: `..\evil.shapr`, content: []byte("x")}}, err: ErrUnsafePath},
+		{name: "absolute path", entries: []testEntry{{name: "/etc/passwd", content: []byte("x")}}, err: ErrUnsafePath},
+		{name: "drive letter", entries: []testEntry{{name: "C:/evil.shapr", content: []byte("x")}}, err: ErrUnsafePath},
+		{name: "symbolic link", entries: []testEntry{{name: "link", content: []byte("/etc/passwd"), mode: fs.ModeSymlink | 0o777}}, err: ErrUnsafePath},
+		{
+			name:    "too many entries",
+			entries: []testEntry{{name: "a", content: []byte("a")}, {name: "b", content: []byte("b")}},
+			limits:  Limits{MaxEntries: 1, MaxEntrySize: 10, MaxTotalSize: 10, MaxCompressionRatio: 100},
+			err:     ErrTooManyEntries,
+		},
+		{
+			name:    "large entry",
+			entries: []testEntry{{name: "a", content: incompressible(2000)}},
+			limits:  Limits{MaxEntries: 10, MaxEntrySize: 1000, MaxTotalSize: 10000, MaxCompressionRatio: 100},
+			err:     ErrEntryTooLarge,
+		},
+		{
+			name:    "large total",
+			entries: []testEntry{{name: "a", content: incompressible(800)}, {name: "b", content: incompressible(800)}},
+			limits:  Limits{MaxEntries: 10, MaxEntrySize: 1000, MaxTotalSize: 1000, MaxCompressionRatio: 100},
+			err:     ErrArchiveTooLarge,
+		},
+		{name: "zip bomb", entries: []testEntry{{name: "bomb.stl", content: make([]byte, 1<<20)}}, err: ErrCompressionRatio},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			limits := test.limits
+			if limits == (Limits{}) {
+				limits = testLimits
+			}
+
+			r := buildZip(t, test.entries...)
+			_, err := ReadZip(r, r.Size(), limits)
+			assert.ErrorIs(t, err, test.err)
+		})
+	}
+}
+
+func TestReadZipInvalidArchive(t *testing.T) {
+	r := bytes.NewReader([]byte("PK\x03\x04 not really a zip"))
+	_, err := ReadZip(r, r.Size(), testLimits)
+	assert.Error(t, err)
+}
+
+func TestIsZip(t *testing.T) {
+	assert.True(t, IsZip([]byte("PK\x03\x04rest")))
+	assert.False(t, IsZip([]byte("SHPR")))
+}
@@ -0,0 +1,61 @@
+package archive
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// ManifestEntry describes a file written to an archive
+type ManifestEntry struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Writer streams a ZIP archive to an io.Writer, computing the checksum of every file while it is written
+type Writer struct {
+	zw       *zip.Writer
+	modified time.Time
+}
+
+// NewWriter creates a Writer streaming to w
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{zw: zip.NewWriter(w), modified: time.Now()}
+}
+
+// Add compresses the content of r into the archive under name and returns its size and SHA-256 checksum
+func (w *Writer) Add(name string, r io.Reader) (ManifestEntry, error) {
+	dest, err := w.zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: w.modified})
+	if err != nil {
+		return ManifestEntry{}, err
+	}
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(dest, hash), r)
+	if err != nil {
+		return ManifestEntry{}, err
+	}
+
+	return ManifestEntry{Name: name, Size: size, SHA256: hex.EncodeToString(hash.Sum(nil))}, nil
+}
+
+// AddJSON writes v into the archive under name as indented JSON
+func (w *Writer) AddJSON(name string, v any) error {
+	dest, err := w.zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: w.modified})
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(dest)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// Close writes the archive's central directory; it does not close the underlying writer
+func (w *Writer) Close() error {
+	return w.zw.Close()
+}
@@ -0,0 +1,41 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+
+	entry, err := w.Add("cube/cube.obj", strings.NewReader("hello"))
+	require.NoError(t, err)
+	assert.Equal(t, ManifestEntry{
+		Name:   "cube/cube.obj",
+		Size:   5,
+		SHA256: "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+	}, entry)
+
+	require.NoError(t, w.AddJSON("manifest.json", map[string]any{"files": []ManifestEntry{entry}}))
+	require.NoError(t, w.Close())
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	require.Len(t, zr.File, 2)
+	assert.Equal(t, "cube/cube.obj", zr.File[0].Name)
+	assert.Equal(t, "manifest.json", zr.File[1].Name)
+
+	rc, err := zr.File[0].Open()
+	require.NoError(t, err)
+	defer rc.Close()
+	content, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(content))
+}
@@ -0,0 +1,53 @@
+package converter
+
+import (
+	"context"
+	"io"
+
+	"github.com/wildan3105/converto/pkg/scene"
+)
+
+// ProgressFunc receives the conversion progress as a percentage between 0 and 100
+type ProgressFunc func(progress int)
+
+// Options holds converter-specific parameters supplied with a conversion request
+type Options map[string]any
+
+// Output creates the files produced by a conversion.
+// The first file created is the primary artifact; others (e.g. an OBJ's .mtl) are companions.
+type Output interface {
+	Create(name string) (io.WriteCloser, error)
+}
+
+// Job describes a single conversion handed over to a Converter.
+// SourceFormat is the detected format of the input (e.g. ".stl").
+// Scene, when set, is the input already decoded; converters working on the scene model
+// use it instead of decoding Input, and may modify it.
+// OutputName is the file name of the primary artifact, companions are named after it.
+type Job struct {
+	Input        io.Reader
+	Scene        *scene.Scene
+	InputSize    int64
+	SourceFormat string
+	Output       Output
+	OutputName   string
+	TargetFormat string
+	Options      Options
+	Progress     ProgressFunc
+}
+
+// Converter converts an input model into a target format
+type Converter interface {
+	Convert(ctx context.Context, job Job) error
+	// SourceFormats lists the input formats the converter accepts
+	SourceFormats() []string
+	// OptionSchema describes the options the converter accepts
+	OptionSchema() Schema
+}
+
+// report calls the job's progress callback when one is set
+func (j Job) report(progress int) {
+	if j.Progress != nil {
+		j.Progress(progress)
+	}
+}
@@ -0,0 +1,75 @@
+package converter
+
+import (
+	"context"
+	"io"
+)
+
+// CopyConverter writes the input unchanged to the output.
+// It stands in for formats that have no real converter yet.
+type CopyConverter struct{}
+
+// NewCopyConverter creates a new instance of CopyConverter
+func NewCopyConverter() *CopyConverter {
+	return &CopyConverter{}
+}
+
+// SourceFormats returns the formats CopyConverter accepts, only .shapr
+func (c *CopyConverter) SourceFormats() []string {
+	return []string{".shapr"}
+}
+
+// OptionSchema returns the schema of the vendor converter CopyConverter stands
+// in for, so requests stay valid once a command is configured. Copying ignores the options.
+func (c *CopyConverter) OptionSchema() Schema {
+	return execSchema
+}
+
+// Convert copies the input in 1 MB chunks, reporting progress every 10%
+func (c *CopyConverter) Convert(ctx context.Context, job Job) error {
+	output, err := job.Output.Create(job.OutputName)
+	if err != nil {
+		return err
+	}
+	defer output.Close()
+
+	buffer := make([]byte, 1024*1024) // 1 MB buffer
+	var copiedBytes int64
+	lastReportedProgress := 0
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		n, err := job.Input.Read(buffer)
+		if n > 0 {
+			if _, writeErr := output.Write(buffer[:n]); writeErr != nil {
+				return writeErr
+			}
+			copiedBytes += int64(n)
+
+			if job.InputSize > 0 {
+				progress := int((copiedBytes * 100) / job.InputSize)
+				if progress >= lastReportedProgress+10 {
+					job.report(progress)
+					lastReportedProgress = progress
+				}
+			}
+		}
+
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return err
+		}
+	}
+
+	if err := output.Close(); err != nil {
+		return err
+	}
+
+	job.report(100)
+	return nil
+}
@@ -0,0 +1,39 @@
+package converter
+
+import config "github.com/wildan3105/converto/configs"
+
+// NewDefaultRegistry builds the registry with every converter shipped with converto.
+// Formats with a configured vendor command are converted by running that command.
+func NewDefaultRegistry() *Registry {
+	registry := NewRegistry()
+
+	for _, format := range []string{".step", ".iges"} {
+		registry.Register(format, NewCopyConverter())
+	}
+
+	registry.Register(".stl", NewSceneConverter(encodeSTL, Schema{"encoding": encodingOption}))
+	registry.Register(".obj", NewSceneConverter(encodeOBJ, nil))
+	registry.Register(".glb", NewSceneConverter(encodeGLB, nil))
+	registry.Register(".gltf", NewSceneConverter(encodeGLTF, nil))
+	registry.Register(".3mf", NewSceneConverter(encode3MF, nil))
+	registry.Register(".ply", NewSceneConverter(encodePLY, Schema{"encoding": encodingOption}))
+
+	commands := map[string]string{
+		".step": config.AppConfig.ConverterStepCommand,
+		".iges": config.AppConfig.ConverterIgesCommand,
+	}
+
+	for format, command := range commands {
+		if command == "" {
+			continue
+		}
+
+		registry.Register(format, NewExecConverter(ExecConfig{
+			Command:    command,
+			Timeout:    config.AppConfig.ConverterTimeout,
+			ScratchDir: config.AppConfig.ConverterScratchDirectory,
+		}))
+	}
+
+	return registry
+}
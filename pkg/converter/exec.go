@@ -0,0 +1,291 @@
+package converter
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultExecTimeout bounds a single external conversion when no timeout is configured
+	DefaultExecTimeout = 10 * time.Minute
+	// stderrTailSize is the number of trailing stderr bytes kept for error reporting
+	stderrTailSize = 4 * 1024
+	// waitDelay is how long to wait for the output pipes after the process tree was killed
+	waitDelay = 5 * time.Second
+)
+
+// progressPattern matches progress lines such as "PROGRESS 42" or "42%" on the command's stdout
+var progressPattern = regexp.MustCompile(`(?i)(?:^\s*progress\D*(\d{1,3})(?:\.\d+)?|(\d{1,3})(?:\.\d+)?\s*%)`)
+
+// ExecConfig configures an external converter command.
+//
+// Command is a template split on whitespace (no shell is involved). The placeholders
+// {input}, {output} and {format} are replaced by the input path, the output path and
+// the target format without the leading dot. Conversion options are passed in the
+// environment as CONVERTO_OPTION_<NAME>, e.g. CONVERTO_OPTION_TOLERANCE=0.01.
+type ExecConfig struct {
+	Command    string
+	Timeout    time.Duration
+	ScratchDir string
+}
+
+// ExecError is returned when the external command fails or times out
+type ExecError struct {
+	ExitCode   int
+	TimedOut   bool
+	StderrTail string
+	Err        error
+}
+
+func (e *ExecError) Error() string {
+	var msg string
+	if e.TimedOut {
+		msg = "converter command timed out"
+	} else {
+		msg = fmt.Sprintf("converter command exited with code %d", e.ExitCode)
+	}
+
+	if e.StderrTail != "" {
+		msg += ": " + e.StderrTail
+	}
+
+	return msg
+}
+
+func (e *ExecError) Unwrap() error {
+	return e.Err
+}
+
+// ExecConverter runs a vendor binary in a scratch directory to perform the conversion
+type ExecConverter struct {
+	config ExecConfig
+}
+
+// NewExecConverter creates a new instance of ExecConverter
+func NewExecConverter(config ExecConfig) *ExecConverter {
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultExecTimeout
+	}
+
+	return &ExecConverter{config: config}
+}
+
+// SourceFormats returns the formats ExecConverter accepts; vendor commands only read .shapr
+func (c *ExecConverter) SourceFormats() []string {
+	return []string{".shapr"}
+}
+
+// OptionSchema returns the options forwarded to the command
+func (c *ExecConverter) OptionSchema() Schema {
+	return execSchema
+}
+
+// Convert stages the input in a fresh scratch directory, runs the command and streams its output file back
+func (c *ExecConverter) Convert(ctx context.Context, job Job) error {
+	workDir, err := os.MkdirTemp(c.config.ScratchDir, "converto-")
+	if err != nil {
+		return fmt.Errorf("failed to create scratch directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	sourceFormat := job.SourceFormat
+	if sourceFormat == "" {
+		sourceFormat = ".shapr"
+	}
+	inputPath := filepath.Join(workDir, "input"+sourceFormat)
+	outputPath := filepath.Join(workDir, "output"+job.TargetFormat)
+
+	if err := writeFile(inputPath, job.Input); err != nil {
+		return fmt.Errorf("failed to stage input: %w", err)
+	}
+
+	args := c.args(inputPath, outputPath, job.TargetFormat)
+	if len(args) == 0 {
+		return errors.New("converter command is empty")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.config.Timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = workDir
+	cmd.Env = []string{
+		"PATH=" + os.Getenv("PATH"),
+		"HOME=" + workDir,
+		"TMPDIR=" + workDir,
+	}
+	cmd.Env = append(cmd.Env, optionsEnv(job.Options)...)
+	cmd.WaitDelay = waitDelay
+	isolateProcessGroup(cmd)
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to attach stdout: %w", err)
+	}
+
+	stderr := newTailBuffer(stderrTailSize)
+	cmd.Stderr = stderr
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start converter command: %w", err)
+	}
+
+	// stdout has to be fully read before calling Wait
+	parseProgress(stdout, job.report)
+	waitErr := cmd.Wait()
+
+	if waitErr != nil || ctx.Err() != nil {
+		execErr := &ExecError{
+			ExitCode:   cmd.ProcessState.ExitCode(),
+			StderrTail: strings.TrimSpace(stderr.String()),
+			Err:        waitErr,
+		}
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			execErr.TimedOut = true
+			execErr.Err = ctx.Err()
+		} else if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return execErr
+	}
+
+	result, err := os.Open(outputPath)
+	if err != nil {
+		return fmt.Errorf("converter command produced no output: %w", err)
+	}
+	defer result.Close()
+
+	output, err := job.Output.Create(job.OutputName)
+	if err != nil {
+		return err
+	}
+	defer output.Close()
+
+	if _, err := io.Copy(output, result); err != nil {
+		return fmt.Errorf("failed to copy converter output: %w", err)
+	}
+
+	if err := output.Close(); err != nil {
+		return err
+	}
+
+	job.report(100)
+	return nil
+}
+
+// args expands the command template for one job
+func (c *ExecConverter) args(inputPath, outputPath, targetFormat string) []string {
+	replacer := strings.NewReplacer(
+		"{input}", inputPath,
+		"{output}", outputPath,
+		"{format}", strings.TrimPrefix(targetFormat, "."),
+	)
+
+	fields := strings.Fields(c.config.Command)
+	for i, field := range fields {
+		fields[i] = replacer.Replace(field)
+	}
+
+	return fields
+}
+
+// optionsEnv exposes each option to the command as CONVERTO_OPTION_<NAME>=<value>
+func optionsEnv(opts Options) []string {
+	env := make([]string, 0, len(opts))
+	for name, value := range opts {
+		env = append(env, fmt.Sprintf("CONVERTO_OPTION_%s=%v", strings.ToUpper(name), value))
+	}
+	sort.Strings(env)
+
+	return env
+}
+
+// parseProgress reads the command's stdout line by line and reports increasing progress values below 100
+func parseProgress(r io.Reader, report func(int)) {
+	scanner := bufio.NewScanner(r)
+	lastReported := -1
+
+	for scanner.Scan() {
+		match := progressPattern.FindStringSubmatch(scanner.Text())
+		if match == nil {
+			continue
+		}
+
+		value := match[1]
+		if value == "" {
+			value = match[2]
+		}
+
+		progress, err := strconv.Atoi(value)
+		if err != nil {
+			continue
+		}
+
+		// 100% is only reported once the output has been copied back
+		progress = min(progress, 99)
+		if progress > lastReported {
+			report(progress)
+			lastReported = progress
+		}
+	}
+
+	// drain anything left so the command never blocks on a full pipe
+	_, _ = io.Copy(io.Discard, r)
+}
+
+// writeFile copies the reader into a newly created file at path
+func writeFile(path string, r io.Reader) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(file, r); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// tailBuffer is an io.Writer that keeps only the last size bytes written to it
+type tailBuffer struct {
+	mu   sync.Mutex
+	size int
+	data []byte
+}
+
+func newTailBuffer(size int) *tailBuffer {
+	return &tailBuffer{size: size}
+}
+
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.data = append(t.data, p...)
+	if len(t.data) > t.size {
+		t.data = t.data[len(t.data)-t.size:]
+	}
+
+	return len(p), nil
+}
+
+func (t *tailBuffer) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return string(t.data)
+}
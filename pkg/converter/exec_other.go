@@ -0,0 +1,9 @@
+//go:build !unix
+
+package converter
+
+import "os/exec"
+
+// isolateProcessGroup is a no-op on platforms without process groups;
+// only the direct child is killed when the command is cancelled
+func isolateProcessGroup(cmd *exec.Cmd) {}
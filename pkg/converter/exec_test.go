@@ -0,0 +1,158 @@
+//go:build unix
+
+package converter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeScript writes a fake vendor binary to a temporary directory and returns its path
+func writeScript(t *testing.T, body string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "fake-converter.sh")
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"+body), 0o755))
+
+	return path
+}
+
+func TestExecConverterSuccess(t *testing.T) {
+	script := writeScript(t, `
+echo "loading model"
+echo "PROGRESS 30"
+echo "converting... 75%"
+tr 'a-z' 'A-Z' < "$1" > "$2"
+`)
+
+	c := NewExecConverter(ExecConfig{Command: script + " {input} {output}", ScratchDir: t.TempDir()})
+
+	output := newMemoryOutput()
+	var reported []int
+	err := c.Convert(context.Background(), Job{
+		Input:        strings.NewReader("abc 123"),
+		Output:       output,
+		OutputName:   "model.step",
+		TargetFormat: ".step",
+		Progress:     func(progress int) { reported = append(reported, progress) },
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"model.step"}, output.order)
+	assert.Equal(t, "ABC 123", output.files["model.step"].String())
+	assert.Equal(t, []int{30, 75, 100}, reported)
+}
+
+func TestExecConverterPassesOptionsInEnvironment(t *testing.T) {
+	script := writeScript(t, `env | grep '^CONVERTO_OPTION_' | sort > "$1"`)
+
+	c := NewExecConverter(ExecConfig{Command: script + " {output}", ScratchDir: t.TempDir()})
+
+	output := newMemoryOutput()
+	err := c.Convert(context.Background(), Job{
+		Input:        strings.NewReader("abc"),
+		Output:       output,
+		OutputName:   "model.step",
+		TargetFormat: ".step",
+		Options:      Options{"tolerance": 0.01, "units": "in"},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "CONVERTO_OPTION_TOLERANCE=0.01\nCONVERTO_OPTION_UNITS=in\n", output.files["model.step"].String())
+}
+
+func TestExecConverterFailureKeepsStderrTail(t *testing.T) {
+	script := writeScript(t, `
+i=0
+while [ $i -lt 2000 ]; do echo "noise line $i" >&2; i=$((i+1)); done
+echo "fatal: unsupported body type" >&2
+exit 3
+`)
+
+	c := NewExecConverter(ExecConfig{Command: script, ScratchDir: t.TempDir()})
+
+	err := c.Convert(context.Background(), Job{
+		Input:        strings.NewReader("abc"),
+		Output:       newMemoryOutput(),
+		OutputName:   "model.out",
+		TargetFormat: ".iges",
+	})
+
+	var execErr *ExecError
+	require.ErrorAs(t, err, &execErr)
+	assert.Equal(t, 3, execErr.ExitCode)
+	assert.False(t, execErr.TimedOut)
+	assert.LessOrEqual(t, len(execErr.StderrTail), stderrTailSize)
+	assert.True(t, strings.HasSuffix(execErr.StderrTail, "fatal: unsupported body type"))
+	assert.Contains(t, err.Error(), "fatal: unsupported body type")
+}
+
+func TestExecConverterTimeoutKillsProcessTree(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "child.pid")
+	script := writeScript(t, `
+sleep 30 &
+echo $! > "`+pidFile+`"
+wait
+`)
+
+	c := NewExecConverter(ExecConfig{Command: script, Timeout: 500 * time.Millisecond, ScratchDir: t.TempDir()})
+
+	started := time.Now()
+	err := c.Convert(context.Background(), Job{
+		Input:        strings.NewReader("abc"),
+		Output:       newMemoryOutput(),
+		OutputName:   "model.out",
+		TargetFormat: ".step",
+	})
+
+	var execErr *ExecError
+	require.ErrorAs(t, err, &execErr)
+	assert.True(t, execErr.TimedOut)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Less(t, time.Since(started), 5*time.Second)
+
+	pid, readErr := os.ReadFile(pidFile)
+	require.NoError(t, readErr)
+	assert.Eventually(t, func() bool {
+		return !processRunning(strings.TrimSpace(string(pid)))
+	}, 2*time.Second, 50*time.Millisecond, "child process should have been killed")
+}
+
+// processRunning reports whether a process exists and is not a zombie waiting to be reaped
+func processRunning(pid string) bool {
+	stat, err := os.ReadFile("/proc/" + pid + "/stat")
+	if err != nil {
+		return false
+	}
+
+	fields := strings.Fields(string(stat[bytes.LastIndexByte(stat, ')')+1:]))
+	return len(fields) > 0 && fields[0] != "Z"
+}
+
+func TestExecConverterCleansUpScratchDirectory(t *testing.T) {
+	scratch := t.TempDir()
+	script := writeScript(t, `cp "$1" "$2"`)
+
+	c := NewExecConverter(ExecConfig{Command: script + " {input} {output}", ScratchDir: scratch})
+
+	err := c.Convert(context.Background(), Job{
+		Input:        strings.NewReader("abc"),
+		Output:       newMemoryOutput(),
+		OutputName:   "model.out",
+		TargetFormat: ".step",
+	})
+	require.NoError(t, err)
+
+	entries, err := os.ReadDir(scratch)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
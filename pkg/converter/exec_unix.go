@@ -0,0 +1,17 @@
+//go:build unix
+
+package converter
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// isolateProcessGroup starts the command in its own process group so that
+// cancelling it kills every process the vendor binary spawned as well
+func isolateProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
@@ -0,0 +1,87 @@
+package converter
+
+import (
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/wildan3105/converto/pkg/format/gltf"
+	"github.com/wildan3105/converto/pkg/format/obj"
+	"github.com/wildan3105/converto/pkg/format/ply"
+	"github.com/wildan3105/converto/pkg/format/stl"
+	"github.com/wildan3105/converto/pkg/format/threemf"
+	"github.com/wildan3105/converto/pkg/scene"
+)
+
+// encodeSTL writes binary STL unless the "encoding" option asks for "ascii"
+func encodeSTL(out Output, name string, s *scene.Scene, opts Options) error {
+	return createFile(out, name, func(w io.Writer) error {
+		return stl.Encode(w, s, stl.EncodeOptions{
+			ASCII: opts.String("encoding", "binary") == "ascii",
+		})
+	})
+}
+
+// encodeOBJ writes the OBJ geometry and, when the scene has materials, a companion .mtl file
+func encodeOBJ(out Output, name string, s *scene.Scene, opts Options) error {
+	var materialLibrary string
+	if len(s.Materials) > 0 {
+		materialLibrary = strings.TrimSuffix(name, filepath.Ext(name)) + ".mtl"
+	}
+
+	err := createFile(out, name, func(w io.Writer) error {
+		return obj.Encode(w, s, obj.EncodeOptions{MaterialLibrary: materialLibrary})
+	})
+	if err != nil || materialLibrary == "" {
+		return err
+	}
+
+	return createFile(out, materialLibrary, func(w io.Writer) error {
+		return obj.EncodeMaterials(w, s)
+	})
+}
+
+// encodeGLB writes a binary glTF container
+func encodeGLB(out Output, name string, s *scene.Scene, opts Options) error {
+	return createFile(out, name, func(w io.Writer) error {
+		return gltf.EncodeGLB(w, s)
+	})
+}
+
+// encodeGLTF writes a glTF JSON document with an embedded buffer
+func encodeGLTF(out Output, name string, s *scene.Scene, opts Options) error {
+	return createFile(out, name, func(w io.Writer) error {
+		return gltf.EncodeGLTF(w, s)
+	})
+}
+
+// encode3MF writes a 3MF package
+func encode3MF(out Output, name string, s *scene.Scene, opts Options) error {
+	return createFile(out, name, func(w io.Writer) error {
+		return threemf.Encode(w, s)
+	})
+}
+
+// encodePLY writes binary PLY unless the "encoding" option asks for "ascii"
+func encodePLY(out Output, name string, s *scene.Scene, opts Options) error {
+	return createFile(out, name, func(w io.Writer) error {
+		return ply.Encode(w, s, ply.EncodeOptions{
+			ASCII: opts.String("encoding", "binary") == "ascii",
+		})
+	})
+}
+
+// createFile creates a file through out, lets write fill it and closes it
+func createFile(out Output, name string, write func(w io.Writer) error) error {
+	file, err := out.Create(name)
+	if err != nil {
+		return err
+	}
+
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
@@ -0,0 +1,132 @@
+package converter
+
+import (
+	"context"
+	"math"
+
+	"github.com/wildan3105/converto/pkg/meshops"
+	"github.com/wildan3105/converto/pkg/scene"
+)
+
+// meshStep is one mesh operation applied to the whole scene. It stops between meshes once ctx is done.
+type meshStep func(ctx context.Context, s *scene.Scene, progress meshops.Progress) error
+
+// meshSteps returns the mesh operations requested by the options, in the order they run:
+// weld, fill holes, fix normals, decimate. Every operation but welding needs shared
+// vertices, so requesting any of them welds exact duplicates first.
+func meshSteps(opts Options) []meshStep {
+	var steps []meshStep
+
+	fillHoles := opts.Bool("fill_holes", false)
+	fixNormals := opts.Bool("fix_normals", false)
+	decimateTarget := opts.Int("decimate_target", 0)
+
+	if opts.Bool("weld", false) || fillHoles || fixNormals || decimateTarget > 0 {
+		tolerance := opts.Float("weld_tolerance", 0)
+		steps = append(steps, func(ctx context.Context, s *scene.Scene, progress meshops.Progress) error {
+			return forEachMesh(ctx, s, progress, func(m *scene.Mesh, _ bool, _ meshops.Progress) {
+				meshops.Weld(m, tolerance)
+			})
+		})
+	}
+
+	if fillHoles {
+		steps = append(steps, func(ctx context.Context, s *scene.Scene, progress meshops.Progress) error {
+			return forEachMesh(ctx, s, progress, func(m *scene.Mesh, _ bool, progress meshops.Progress) {
+				meshops.FillHoles(m, progress)
+			})
+		})
+	}
+
+	if fixNormals {
+		steps = append(steps, func(ctx context.Context, s *scene.Scene, progress meshops.Progress) error {
+			return forEachMesh(ctx, s, progress, func(m *scene.Mesh, mirrored bool, progress meshops.Progress) {
+				meshops.FixNormals(m, mirrored, progress)
+			})
+		})
+	}
+
+	if decimateTarget > 0 {
+		steps = append(steps, func(ctx context.Context, s *scene.Scene, progress meshops.Progress) error {
+			// the target is shared between the meshes in proportion to their triangle counts
+			total := s.TriangleCount()
+			return forEachMesh(ctx, s, progress, func(m *scene.Mesh, _ bool, progress meshops.Progress) {
+				target := int(math.Round(float64(decimateTarget) * float64(m.TriangleCount()) / float64(total)))
+				meshops.Decimate(m, max(target, 1), progress)
+			})
+		})
+	}
+
+	return steps
+}
+
+// forEachMesh runs op on every mesh of the scene, giving each a share of progress
+// proportional to its triangle count. mirrored is set for meshes placed by a mirroring transform.
+// It returns the context's error, without running op on the remaining meshes, once ctx is done.
+func forEachMesh(ctx context.Context, s *scene.Scene, progress meshops.Progress, op func(m *scene.Mesh, mirrored bool, progress meshops.Progress)) error {
+	total := s.TriangleCount()
+	done := 0
+
+	for b := range s.Bodies {
+		body := &s.Bodies[b]
+		mirrored := body.Transform.Determinant() < 0
+
+		for i := range body.Meshes {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			mesh := &body.Meshes[i]
+			share := mesh.TriangleCount()
+			start := done
+
+			op(mesh, mirrored, func(fraction float64) {
+				if total > 0 && progress != nil {
+					progress((float64(start) + fraction*float64(share)) / float64(total))
+				}
+			})
+
+			done += share
+			if total > 0 && progress != nil {
+				progress(float64(done) / float64(total))
+			}
+		}
+	}
+
+	return nil
+}
+
+// applyMeshSteps runs the requested mesh operations, reporting their progress between from and to
+// percent. It returns the context's error once ctx is done, checking it between steps and meshes.
+func applyMeshSteps(ctx context.Context, s *scene.Scene, steps []meshStep, job Job, from, to int) error {
+	if len(steps) == 0 {
+		return nil
+	}
+
+	span := float64(to-from) / float64(len(steps))
+	for i, step := range steps {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		start := float64(from) + float64(i)*span
+		if err := step(ctx, s, progressRange(job, start, start+span)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// progressRange maps an operation's completed fraction onto the job's progress between
+// from and to percent, reporting only when the whole percentage changes
+func progressRange(job Job, from, to float64) meshops.Progress {
+	last := -1
+	return func(fraction float64) {
+		fraction = math.Min(math.Max(fraction, 0), 1)
+		progress := int(from + fraction*(to-from))
+		if progress > last {
+			last = progress
+			job.report(progress)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package converter
+
+// String returns the option as a string, or def when it is missing or not a string
+func (o Options) String(key, def string) string {
+	if v, ok := o[key].(string); ok {
+		return v
+	}
+	return def
+}
+
+// Bool returns the option as a bool, or def when it is missing or not a bool
+func (o Options) Bool(key string, def bool) bool {
+	if v, ok := o[key].(bool); ok {
+		return v
+	}
+	return def
+}
+
+// Float returns the option as a float64, or def when it is missing or not a number.
+// Numbers may come from JSON (float64) or BSON (int32, int64, float64).
+func (o Options) Float(key string, def float64) float64 {
+	switch v := o[key].(type) {
+	case float64:
+		return v
+	case float32:
+		return float64(v)
+	case int:
+		return float64(v)
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	default:
+		return def
+	}
+}
+
+// Int returns the option as an int, or def when it is missing or not a number
+func (o Options) Int(key string, def int) int {
+	return int(o.Float(key, float64(def)))
+}
@@ -0,0 +1,29 @@
+package converter
+
+import (
+	"bytes"
+	"io"
+)
+
+// memoryOutput collects the files created by a converter in memory
+type memoryOutput struct {
+	files map[string]*bytes.Buffer
+	order []string
+}
+
+func newMemoryOutput() *memoryOutput {
+	return &memoryOutput{files: make(map[string]*bytes.Buffer)}
+}
+
+func (m *memoryOutput) Create(name string) (io.WriteCloser, error) {
+	buf := &bytes.Buffer{}
+	m.files[name] = buf
+	m.order = append(m.order, name)
+	return nopCloser{buf}, nil
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
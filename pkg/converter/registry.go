@@ -0,0 +1,80 @@
+package converter
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+)
+
+// ErrUnsupportedFormat is returned when no converter is registered for a target format
+var ErrUnsupportedFormat = errors.New("unsupported target format")
+
+// Registry maps target formats (e.g. ".stl") to the converter producing them
+type Registry struct {
+	mu         sync.RWMutex
+	converters map[string]Converter
+}
+
+// NewRegistry creates an empty converter registry
+func NewRegistry() *Registry {
+	return &Registry{
+		converters: make(map[string]Converter),
+	}
+}
+
+// Register associates a converter with a target format, replacing any previous one
+func (r *Registry) Register(targetFormat string, c Converter) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.converters[targetFormat] = c
+}
+
+// Get returns the converter registered for the target format
+func (r *Registry) Get(targetFormat string) (Converter, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	c, ok := r.converters[targetFormat]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, targetFormat)
+	}
+
+	return c, nil
+}
+
+// Formats returns the registered target formats in lexical order
+func (r *Registry) Formats() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	formats := make([]string, 0, len(r.converters))
+	for format := range r.converters {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+
+	return formats
+}
+
+// Supports reports whether a converter can turn the source format into the target format
+func (r *Registry) Supports(sourceFormat, targetFormat string) bool {
+	c, err := r.Get(targetFormat)
+	if err != nil {
+		return false
+	}
+
+	return slices.Contains(c.SourceFormats(), sourceFormat)
+}
+
+// ValidateOptions checks the options against the schema of the target format's converter
+func (r *Registry) ValidateOptions(targetFormat string, opts Options) error {
+	c, err := r.Get(targetFormat)
+	if err != nil {
+		return err
+	}
+
+	return c.OptionSchema().Validate(opts)
+}
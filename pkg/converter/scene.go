@@ -0,0 +1,138 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/wildan3105/converto/pkg/format/gltf"
+	"github.com/wildan3105/converto/pkg/format/obj"
+	"github.com/wildan3105/converto/pkg/format/ply"
+	"github.com/wildan3105/converto/pkg/format/shapr"
+	"github.com/wildan3105/converto/pkg/format/stl"
+	"github.com/wildan3105/converto/pkg/scene"
+)
+
+// SceneDecoder parses one source format into the shared scene model
+type SceneDecoder func(r io.Reader) (*scene.Scene, error)
+
+// decoders maps every source format the scene converters can read to its decoder
+var decoders = map[string]SceneDecoder{
+	".shapr": shapr.Decode,
+	".stl":   stl.Decode,
+	".obj":   obj.Decode,
+	".ply":   ply.Decode,
+	".gltf":  gltf.DecodeGLTF,
+	".glb":   gltf.DecodeGLB,
+}
+
+// SceneEncoder writes a scene in one target format, creating the primary file
+// named name and any companion files through out
+type SceneEncoder func(out Output, name string, s *scene.Scene, opts Options) error
+
+// Decoder returns the scene decoder for a source format
+func Decoder(sourceFormat string) (SceneDecoder, bool) {
+	decode, ok := decoders[sourceFormat]
+	return decode, ok
+}
+
+// SceneConverter parses the input into the shared scene model and hands it to a format encoder
+type SceneConverter struct {
+	encode SceneEncoder
+	schema Schema
+}
+
+// NewSceneConverter creates a new instance of SceneConverter using the given encoder.
+// The schema lists the encoder's options; those shared by every scene converter are added to it.
+func NewSceneConverter(encode SceneEncoder, schema Schema) *SceneConverter {
+	return &SceneConverter{encode: encode, schema: sceneSchema(schema)}
+}
+
+// OptionSchema returns the options accepted by the converter
+func (c *SceneConverter) OptionSchema() Schema {
+	return c.schema
+}
+
+// SourceFormats returns every format a scene decoder exists for, in lexical order
+func (c *SceneConverter) SourceFormats() []string {
+	formats := make([]string, 0, len(decoders))
+	for format := range decoders {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+
+	return formats
+}
+
+// Convert decodes the input, unless the job carries it decoded, runs the requested mesh
+// operations, then encodes it into the target format. Decoding reports up to 20%, mesh
+// operations up to 80% and encoding the rest. Inputs without a source format are treated as .shapr.
+func (c *SceneConverter) Convert(ctx context.Context, job Job) error {
+	job.report(0)
+
+	s := job.Scene
+	if s == nil {
+		sourceFormat := job.SourceFormat
+		if sourceFormat == "" {
+			sourceFormat = ".shapr"
+		}
+
+		decode, ok := decoders[sourceFormat]
+		if !ok {
+			return fmt.Errorf("%w: cannot read %s", ErrUnsupportedFormat, sourceFormat)
+		}
+
+		var err error
+		if s, err = decode(job.Input); err != nil {
+			return fmt.Errorf("failed to parse input: %w", err)
+		}
+	}
+
+	job.report(20)
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// mesh operations work in the units of the source file, before any transform is applied
+	if err := applyMeshSteps(ctx, s, meshSteps(job.Options), job, 20, 80); err != nil {
+		return err
+	}
+
+	if err := applySceneOptions(s, job.Options); err != nil {
+		return fmt.Errorf("failed to apply options: %w", err)
+	}
+
+	job.report(80)
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := c.encode(job.Output, job.OutputName, s, job.Options); err != nil {
+		return fmt.Errorf("failed to encode %s: %w", job.TargetFormat, err)
+	}
+
+	job.report(100)
+	return nil
+}
+
+// applySceneOptions converts units, scales and re-orients the scene as requested by the options
+func applySceneOptions(s *scene.Scene, opts Options) error {
+	if units := opts.String("units", ""); units != "" {
+		if err := s.ConvertUnits(scene.Units(units)); err != nil {
+			return err
+		}
+	}
+
+	if scale := opts.Float("scale", 1); scale != 1 {
+		s.Transform(scene.Scaling(scale))
+	}
+
+	if opts.String("up_axis", "z") == "y" {
+		s.Transform(scene.ZUpToYUp())
+	}
+
+	return nil
+}
@@ -0,0 +1,153 @@
+package converter
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wildan3105/converto/pkg/format/ply"
+	"github.com/wildan3105/converto/pkg/format/shapr"
+	"github.com/wildan3105/converto/pkg/format/stl"
+	"github.com/wildan3105/converto/pkg/scene"
+	"github.com/wildan3105/converto/pkg/scene/scenetest"
+)
+
+func TestSceneConverterReadsSourceFormat(t *testing.T) {
+	var input bytes.Buffer
+	require.NoError(t, stl.Encode(&input, scenetest.Cube(2), stl.EncodeOptions{}))
+
+	out := newMemoryOutput()
+	err := NewSceneConverter(encodePLY, nil).Convert(context.Background(), Job{
+		Input:        &input,
+		SourceFormat: ".stl",
+		Output:       out,
+		OutputName:   "cube.ply",
+		TargetFormat: ".ply",
+		Options:      Options{"encoding": "ascii"},
+	})
+	require.NoError(t, err)
+
+	s, err := ply.Decode(out.files["cube.ply"])
+	require.NoError(t, err)
+	assert.Equal(t, 12, s.TriangleCount())
+}
+
+func TestSceneConverterUsesDecodedScene(t *testing.T) {
+	out := newMemoryOutput()
+	err := NewSceneConverter(encodePLY, nil).Convert(context.Background(), Job{
+		// the input is not read again
+		Input:        bytes.NewReader([]byte("not a model")),
+		Scene:        scenetest.Cube(2),
+		SourceFormat: ".stl",
+		Output:       out,
+		OutputName:   "cube.ply",
+		TargetFormat: ".ply",
+	})
+	require.NoError(t, err)
+
+	s, err := ply.Decode(out.files["cube.ply"])
+	require.NoError(t, err)
+	assert.Equal(t, 12, s.TriangleCount())
+}
+
+func TestRegistrySupports(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register(".stl", NewSceneConverter(encodeSTL, nil))
+	registry.Register(".step", NewCopyConverter())
+
+	assert.True(t, registry.Supports(".obj", ".stl"))
+	assert.True(t, registry.Supports(".shapr", ".step"))
+	assert.False(t, registry.Supports(".stl", ".step"))
+	assert.False(t, registry.Supports(".shapr", ".iges"))
+	assert.False(t, registry.Supports(".dwg", ".stl"))
+}
+
+func TestSceneConverterAppliesOptions(t *testing.T) {
+	var input bytes.Buffer
+	require.NoError(t, shapr.Encode(&input, scenetest.Cube(10)))
+
+	out := newMemoryOutput()
+	err := NewSceneConverter(encodeSTL, nil).Convert(context.Background(), Job{
+		Input:        &input,
+		SourceFormat: ".shapr",
+		Output:       out,
+		OutputName:   "cube.stl",
+		TargetFormat: ".stl",
+		Options:      Options{"units": "cm", "scale": 2.0, "up_axis": "y"},
+	})
+	require.NoError(t, err)
+
+	s, err := stl.Decode(out.files["cube.stl"])
+	require.NoError(t, err)
+
+	min, max := scene.Vec3{}, scene.Vec3{}
+	for _, p := range s.Bodies[0].Meshes[0].Positions {
+		min, max = min.Min(p), max.Max(p)
+	}
+	// 10 mm is 1 cm, doubled, and the Z extent becomes the Y extent pointing towards -Z
+	assert.InDeltaSlice(t, []float64{0, 0, -2}, min[:], 1e-9)
+	assert.InDeltaSlice(t, []float64{2, 2, 0}, max[:], 1e-9)
+}
+
+func TestSceneConverterRunsMeshOperations(t *testing.T) {
+	open := scenetest.Cube(10)
+	open.Bodies[0].Meshes[0].Indices = open.Bodies[0].Meshes[0].Indices[6:] // remove the bottom face
+
+	var input bytes.Buffer
+	require.NoError(t, stl.Encode(&input, open, stl.EncodeOptions{}))
+
+	var reported []int
+	out := newMemoryOutput()
+	err := NewSceneConverter(encodePLY, nil).Convert(context.Background(), Job{
+		Input:        &input,
+		SourceFormat: ".stl",
+		Output:       out,
+		OutputName:   "cube.ply",
+		TargetFormat: ".ply",
+		Options:      Options{"fill_holes": true, "fix_normals": true, "decimate_target": 12.0},
+		Progress:     func(progress int) { reported = append(reported, progress) },
+	})
+	require.NoError(t, err)
+
+	s, err := ply.Decode(out.files["cube.ply"])
+	require.NoError(t, err)
+	mesh := s.Bodies[0].Meshes[0]
+	// the hole is closed by a fan around a new vertex, which decimation removes again
+	assert.Len(t, mesh.Positions, 8)
+	assert.Equal(t, 12, mesh.TriangleCount())
+
+	assert.IsNonDecreasing(t, reported)
+	assert.Subset(t, reported, []int{0, 20, 80, 100})
+}
+
+func TestSceneConverterStopsMeshOperationsWhenCancelled(t *testing.T) {
+	var input bytes.Buffer
+	require.NoError(t, stl.Encode(&input, scenetest.Cube(10), stl.EncodeOptions{}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var reported []int
+	out := newMemoryOutput()
+	err := NewSceneConverter(encodePLY, nil).Convert(ctx, Job{
+		Input:        &input,
+		SourceFormat: ".stl",
+		Output:       out,
+		OutputName:   "cube.ply",
+		TargetFormat: ".ply",
+		Options:      Options{"weld": true, "fix_normals": true, "decimate_target": 12.0},
+		Progress: func(progress int) {
+			reported = append(reported, progress)
+			// cancelled once the first mesh operation reports
+			if progress > 20 {
+				cancel()
+			}
+		},
+	})
+	require.ErrorIs(t, err, context.Canceled)
+
+	assert.Empty(t, out.files)
+	assert.Less(t, reported[len(reported)-1], 80)
+}
@@ -0,0 +1,185 @@
+package converter
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// OptionType is the JSON type an option value must have
+type OptionType string
+
+const (
+	OptionString OptionType = "string"
+	OptionNumber OptionType = "number"
+	OptionBool   OptionType = "boolean"
+)
+
+// OptionSpec describes a single option a converter accepts.
+// Enum restricts string values; Min and Max are inclusive bounds for numbers.
+type OptionSpec struct {
+	Type        OptionType `json:"type"`
+	Enum        []string   `json:"enum,omitempty"`
+	Min         float64    `json:"min,omitempty"`
+	Max         float64    `json:"max,omitempty"`
+	Description string     `json:"description"`
+}
+
+// Schema maps option names to their specification
+type Schema map[string]OptionSpec
+
+// OptionsError is returned when options do not match a converter's schema
+type OptionsError struct {
+	Problems []string
+}
+
+func (e *OptionsError) Error() string {
+	return "invalid options: " + strings.Join(e.Problems, "; ")
+}
+
+// Validate checks every option against the schema. Unknown options are rejected.
+func (s Schema) Validate(opts Options) error {
+	keys := make([]string, 0, len(opts))
+	for key := range opts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var problems []string
+	for _, key := range keys {
+		spec, ok := s[key]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s: unknown option", key))
+			continue
+		}
+		if problem := spec.check(opts[key]); problem != "" {
+			problems = append(problems, fmt.Sprintf("%s: %s", key, problem))
+		}
+	}
+
+	if len(problems) > 0 {
+		return &OptionsError{Problems: problems}
+	}
+	return nil
+}
+
+// check returns a description of what is wrong with value, or "" when it is valid
+func (o OptionSpec) check(value any) string {
+	switch o.Type {
+	case OptionString:
+		v, ok := value.(string)
+		if !ok {
+			return "must be a string"
+		}
+		if len(o.Enum) > 0 && !slices.Contains(o.Enum, v) {
+			return "must be one of " + strings.Join(o.Enum, ", ")
+		}
+	case OptionNumber:
+		v, ok := value.(float64)
+		if !ok || math.IsNaN(v) || math.IsInf(v, 0) {
+			return "must be a number"
+		}
+		if v < o.Min || v > o.Max {
+			return fmt.Sprintf("must be between %g and %g", o.Min, o.Max)
+		}
+	case OptionBool:
+		if _, ok := value.(bool); !ok {
+			return "must be a boolean"
+		}
+	}
+	return ""
+}
+
+// Option specifications shared by several converters
+var (
+	unitsOption = OptionSpec{
+		Type:        OptionString,
+		Enum:        []string{"mm", "cm", "m", "in", "ft"},
+		Description: "Units of the output coordinates; the model keeps its physical size",
+	}
+	scaleOption = OptionSpec{
+		Type:        OptionNumber,
+		Min:         1e-6,
+		Max:         1e6,
+		Description: "Uniform scale factor applied to the model",
+	}
+	upAxisOption = OptionSpec{
+		Type:        OptionString,
+		Enum:        []string{"z", "y"},
+		Description: "Up axis of the output; y rotates the Z-up source to Y-up",
+	}
+	encodingOption = OptionSpec{
+		Type:        OptionString,
+		Enum:        []string{"binary", "ascii"},
+		Description: "Binary or ASCII encoding of the output file",
+	}
+	toleranceOption = OptionSpec{
+		Type:        OptionNumber,
+		Min:         1e-4,
+		Max:         10,
+		Description: "Maximum chordal deviation of the tessellation, in millimeters",
+	}
+	angularDeflectionOption = OptionSpec{
+		Type:        OptionNumber,
+		Min:         0.1,
+		Max:         90,
+		Description: "Maximum angle between adjacent facet normals of the tessellation, in degrees",
+	}
+)
+
+// Mesh operations run by scene converters between parsing and export
+var (
+	weldOption = OptionSpec{
+		Type:        OptionBool,
+		Description: "Merge duplicate vertices",
+	}
+	weldToleranceOption = OptionSpec{
+		Type:        OptionNumber,
+		Min:         0,
+		Max:         10,
+		Description: "Distance within which vertices are merged, in the units of the source file; 0 merges exact duplicates only",
+	}
+	fillHolesOption = OptionSpec{
+		Type:        OptionBool,
+		Description: "Close holes in the meshes; implies weld",
+	}
+	fixNormalsOption = OptionSpec{
+		Type:        OptionBool,
+		Description: "Make triangle winding consistent and closed meshes face outward; implies weld",
+	}
+	decimateTargetOption = OptionSpec{
+		Type:        OptionNumber,
+		Min:         4,
+		Max:         1e8,
+		Description: "Reduce the model to about this many triangles with quadric edge collapse; implies weld",
+	}
+)
+
+// sceneSchema returns the options understood by every scene converter, plus extra
+func sceneSchema(extra Schema) Schema {
+	schema := Schema{
+		"units":           unitsOption,
+		"scale":           scaleOption,
+		"up_axis":         upAxisOption,
+		"weld":            weldOption,
+		"weld_tolerance":  weldToleranceOption,
+		"fill_holes":      fillHolesOption,
+		"fix_normals":     fixNormalsOption,
+		"decimate_target": decimateTargetOption,
+	}
+	for name, spec := range extra {
+		schema[name] = spec
+	}
+	return schema
+}
+
+// execSchema lists the options forwarded to vendor commands
+var execSchema = Schema{
+	"units":              unitsOption,
+	"scale":              scaleOption,
+	"up_axis":            upAxisOption,
+	"tolerance":          toleranceOption,
+	"angular_deflection": angularDeflectionOption,
+}
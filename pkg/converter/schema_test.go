@@ -0,0 +1,43 @@
+package converter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaValidate(t *testing.T) {
+	schema := sceneSchema(Schema{"encoding": encodingOption})
+
+	assert.NoError(t, schema.Validate(nil))
+	assert.NoError(t, schema.Validate(Options{"units": "in", "scale": 2.5, "up_axis": "y", "encoding": "ascii"}))
+
+	err := schema.Validate(Options{
+		"units":     "furlong",
+		"scale":     0.0,
+		"encoding":  true,
+		"tolerance": 0.1,
+	})
+
+	var optionsErr *OptionsError
+	require.ErrorAs(t, err, &optionsErr)
+	assert.Equal(t, []string{
+		"encoding: must be a string",
+		"scale: must be between 1e-06 and 1e+06",
+		"tolerance: unknown option",
+		"units: must be one of mm, cm, m, in, ft",
+	}, optionsErr.Problems)
+}
+
+func TestRegistryValidateOptions(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register(".step", NewExecConverter(ExecConfig{Command: "true"}))
+	registry.Register(".iges", NewCopyConverter())
+
+	assert.NoError(t, registry.ValidateOptions(".step", Options{"tolerance": 0.01, "angular_deflection": 15.0}))
+	assert.Error(t, registry.ValidateOptions(".step", Options{"encoding": "ascii"}))
+	assert.NoError(t, registry.ValidateOptions(".iges", Options{"tolerance": 0.01, "angular_deflection": 15.0}))
+	assert.Error(t, registry.ValidateOptions(".iges", Options{"weld": true}))
+	assert.ErrorIs(t, registry.ValidateOptions(".dwg", nil), ErrUnsupportedFormat)
+}
@@ -0,0 +1,24 @@
+package domain
+
+import "time"
+
+// BoundingBox is an axis-aligned box given by its minimum and maximum corners
+type BoundingBox struct {
+	Min  [3]float64 `bson:"min" json:"min"`
+	Max  [3]float64 `bson:"max" json:"max"`
+	Size [3]float64 `bson:"size" json:"size"`
+}
+
+// Analysis holds the physical properties of an uploaded model.
+// Lengths are in millimeters, areas in mm² and volumes in mm³.
+type Analysis struct {
+	Units         string      `bson:"units" json:"units"`
+	BoundingBox   BoundingBox `bson:"boundingBox" json:"bounding_box"`
+	Volume        float64     `bson:"volume" json:"volume"`
+	SurfaceArea   float64     `bson:"surfaceArea" json:"surface_area"`
+	TriangleCount int         `bson:"triangleCount" json:"triangle_count"`
+	VertexCount   int         `bson:"vertexCount" json:"vertex_count"`
+	BodyCount     int         `bson:"bodyCount" json:"body_count"`
+	Watertight    bool        `bson:"watertight" json:"watertight"`
+	AnalyzedAt    time.Time   `bson:"analyzedAt" json:"analyzed_at"`
+}
@@ -0,0 +1,20 @@
+package domain
+
+import "time"
+
+// Batch groups the uploads created from one batch request: a ZIP archive or several files.
+// Entries are listed in request order; entries that could not be converted keep the reason in Error.
+type Batch struct {
+	ID            string       `bson:"_id,omitempty" json:"id"`
+	Entries       []BatchEntry `bson:"entries" json:"entries"`
+	TargetFormats []string     `bson:"targetFormats" json:"target_formats"`
+	Source        JobSource    `bson:"source" json:"source"`
+	CreatedAt     time.Time    `bson:"createdAt" json:"created_at"`
+}
+
+// BatchEntry is one file of a batch and the upload created from it
+type BatchEntry struct {
+	Name     string `bson:"name" json:"name"`
+	UploadID string `bson:"uploadId,omitempty" json:"upload_id,omitempty"`
+	Error    string `bson:"error,omitempty" json:"error,omitempty"`
+}
@@ -9,22 +9,49 @@ const (
 	ConversionInProgress ConversionStatus = "in_progress"
 	ConversionCompleted  ConversionStatus = "completed"
 	ConversionFailed     ConversionStatus = "failed"
+	ConversionCancelled  ConversionStatus = "cancelled"
 )
 
-// Conversion represents a conversion task with associated metadata and job status
+// Conversion represents a conversion task with associated metadata and job status.
+// UploadID and BatchID link the conversion to the upload and batch it was created from, if any.
+// Outbox holds the last job event written for the conversion, published by the outbox relay.
 type Conversion struct {
-	ID         string         `bson:"_id,omitempty" json:"id"`
-	File       FileMetadata   `bson:"file" json:"file"`
-	Conversion ConversionData `bson:"conversion" json:"conversion"`
-	Job        ConversionJob  `bson:"job" json:"job"`
+	ID         string            `bson:"_id,omitempty" json:"id"`
+	UploadID   string            `bson:"uploadId,omitempty" json:"upload_id,omitempty"`
+	BatchID    string            `bson:"batchId,omitempty" json:"batch_id,omitempty"`
+	File       FileMetadata      `bson:"file" json:"file"`
+	Conversion ConversionData    `bson:"conversion" json:"conversion"`
+	Job        ConversionJob     `bson:"job" json:"job"`
+	Validation *ValidationReport `bson:"validation,omitempty" json:"validation,omitempty"`
+	Analysis   *Analysis         `bson:"analysis,omitempty" json:"analysis,omitempty"`
+	Outbox     *OutboxMessage    `bson:"outbox,omitempty" json:"outbox,omitempty"`
 }
 
-// ConversionData represents the metadata and status of a conversion task
+// ConversionData represents the metadata and status of a conversion task.
+// Options holds the converter options supplied with the request, validated against the target format's schema.
+// CancelRequested asks the worker running the conversion to stop; the worker then records the cancelled status.
+// Attempts records every run of the conversion's job by the worker, oldest first.
+// Error describes why the conversion failed, or why its last attempt did while it waits for a retry;
+// ErrorMessage holds its message.
 type ConversionData struct {
-	TargetFormat string           `bson:"targetFormat" json:"target_format"`
-	Progress     int              `bson:"progress" json:"progress"`
-	Status       ConversionStatus `bson:"status" json:"status"`
-	ErrorMessage *string          `bson:"errorMessage" json:"error_message,omitempty"`
-	StartedAt    time.Time        `bson:"startedAt" json:"started_at,omitempty"`
-	CompletedAt  time.Time        `bson:"completedAt" json:"completed_at,omitempty"`
+	TargetFormat    string           `bson:"targetFormat" json:"target_format"`
+	Options         map[string]any   `bson:"options,omitempty" json:"options,omitempty"`
+	Progress        int              `bson:"progress" json:"progress"`
+	Status          ConversionStatus `bson:"status" json:"status"`
+	CancelRequested bool             `bson:"cancelRequested,omitempty" json:"cancel_requested,omitempty"`
+	ErrorMessage    *string          `bson:"errorMessage" json:"error_message,omitempty"`
+	Error           *ConversionError `bson:"error,omitempty" json:"error,omitempty"`
+	StartedAt       time.Time        `bson:"startedAt" json:"started_at,omitempty"`
+	CompletedAt     time.Time        `bson:"completedAt" json:"completed_at,omitempty"`
+	Attempts        []Attempt        `bson:"attempts,omitempty" json:"attempts,omitempty"`
+}
+
+// Attempt records one run of a conversion's job. A failed attempt keeps its error, and
+// RetryAt is set when another attempt was scheduled after it.
+type Attempt struct {
+	Number     int              `bson:"number" json:"number"`
+	StartedAt  time.Time        `bson:"startedAt" json:"started_at"`
+	FinishedAt time.Time        `bson:"finishedAt" json:"finished_at"`
+	Error      *ConversionError `bson:"error,omitempty" json:"error,omitempty"`
+	RetryAt    *time.Time       `bson:"retryAt,omitempty" json:"retry_at,omitempty"`
 }
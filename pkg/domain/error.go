@@ -0,0 +1,39 @@
+package domain
+
+import "time"
+
+// ErrorStage is the step of a conversion at which it failed
+type ErrorStage string
+
+const (
+	ErrorStagePublish    ErrorStage = "publish"
+	ErrorStagePrepare    ErrorStage = "prepare"
+	ErrorStageValidation ErrorStage = "validation"
+	ErrorStageConversion ErrorStage = "conversion"
+	ErrorStageStorage    ErrorStage = "storage"
+)
+
+// Error codes recorded on failed conversions
+const (
+	ErrorCodePublishFailed      = "publish_failed"
+	ErrorCodeUnsupportedFormat  = "unsupported_format"
+	ErrorCodeStorageUnavailable = "storage_unavailable"
+	ErrorCodeValidationFailed   = "validation_failed"
+	ErrorCodeInvalidOptions     = "invalid_options"
+	ErrorCodeConverterTimeout   = "converter_timeout"
+	ErrorCodeConversionFailed   = "conversion_failed"
+	ErrorCodeEmptyOutput        = "empty_output"
+	ErrorCodeDatabaseError      = "database_error"
+	ErrorCodeLeaseExpired       = "lease_expired"
+	ErrorCodeInternal           = "internal_error"
+)
+
+// ConversionError records why a conversion, or one attempt of it, failed. Retryable tells whether
+// another attempt may succeed.
+type ConversionError struct {
+	Code       string     `bson:"code" json:"code"`
+	Message    string     `bson:"message" json:"message"`
+	Stage      ErrorStage `bson:"stage" json:"stage"`
+	Retryable  bool       `bson:"retryable" json:"retryable"`
+	OccurredAt time.Time  `bson:"occurredAt" json:"occurred_at"`
+}
@@ -5,14 +5,29 @@ type FileCategory string
 const (
 	FileCategoryOriginal  FileCategory = "original"
 	FileCategoryConverted FileCategory = "converted"
+	FileCategoryPreview   FileCategory = "preview"
 )
 
-// FileMetadata represents metadata information for files in the conversion process
+// FileMetadata represents metadata information for files in the conversion process.
+// SourceFormat is the format detected from the uploaded content (e.g. ".stl").
+// ConvertedName and ConvertedPath point to the primary artifact; Artifacts lists every
+// file produced by the conversion, the primary one first. PreviewPath points to the PNG preview of the model.
 type FileMetadata struct {
-	OriginalName  string `bson:"originalName" json:"original_name"`
-	OriginalPath  string `bson:"originalPath" json:"original_path"`
-	ConvertedName string `bson:"convertedName" json:"converted_name"`
-	ConvertedPath string `bson:"convertedPath" json:"converted_path"`
-	SizeInBytes   int64  `bson:"sizeInBytes" json:"size_in_bytes"`
-	ID            string `json:"id,omitempty"`
+	OriginalName  string     `bson:"originalName" json:"original_name"`
+	OriginalPath  string     `bson:"originalPath" json:"original_path"`
+	SourceFormat  string     `bson:"sourceFormat,omitempty" json:"source_format,omitempty"`
+	ConvertedName string     `bson:"convertedName" json:"converted_name"`
+	ConvertedPath string     `bson:"convertedPath" json:"converted_path"`
+	Artifacts     []Artifact `bson:"artifacts,omitempty" json:"artifacts,omitempty"`
+	PreviewPath   string     `bson:"previewPath,omitempty" json:"preview_path,omitempty"`
+	SizeInBytes   int64      `bson:"sizeInBytes" json:"size_in_bytes"`
+	ID            string     `json:"id,omitempty"`
+}
+
+// Artifact represents a single file produced by a conversion
+type Artifact struct {
+	Name        string `bson:"name" json:"name"`
+	Path        string `bson:"path" json:"path"`
+	ContentType string `bson:"contentType" json:"content_type"`
+	SizeInBytes int64  `bson:"sizeInBytes" json:"size_in_bytes"`
 }
@@ -10,11 +10,23 @@ const (
 	JobSourceCLI JobSource = "CLI"
 )
 
-// ConversionJob represents the job status and metadata for a conversion
+// ConversionJob represents the job status and metadata for a conversion.
+// Lease is held by the worker running the job; Attempt is the last attempt of the job claimed by a worker.
 type ConversionJob struct {
 	ID           string    `bson:"id" json:"id"`
+	Attempt      int       `bson:"attempt,omitempty" json:"attempt,omitempty"`
 	Source       JobSource `bson:"source" json:"source"`
 	CreatedAt    time.Time `bson:"createdAt" json:"created_at"`
 	UpdatedAt    time.Time `bson:"updatedAt" json:"updated_at"`
 	ErrorMessage *string   `bson:"errorMessage" json:"error_message,omitempty"`
+	Lease        *Lease    `bson:"lease,omitempty" json:"lease,omitempty"`
+}
+
+// Lease is a worker's claim on a running job. The worker renews it while the job makes progress;
+// once it expires, the job is considered abandoned. Attempt is the attempt the worker is running.
+type Lease struct {
+	Owner      string    `bson:"owner" json:"owner"`
+	Attempt    int       `bson:"attempt" json:"attempt"`
+	AcquiredAt time.Time `bson:"acquiredAt" json:"acquired_at"`
+	ExpiresAt  time.Time `bson:"expiresAt" json:"expires_at"`
 }
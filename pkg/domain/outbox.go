@@ -0,0 +1,28 @@
+package domain
+
+import "time"
+
+// OutboxStatus represents the publishing state of an outbox message
+type OutboxStatus string
+
+const (
+	OutboxPending OutboxStatus = "pending"
+	OutboxSent    OutboxStatus = "sent"
+	OutboxFailed  OutboxStatus = "failed"
+)
+
+// OutboxMessage is a job event waiting to be published. It is stored in the document of its
+// conversion, so that it is written in the same operation as the conversion and cannot be lost
+// between the write and the publish. JobID and Attempt identify the event; the rest of it is read
+// from the conversion. PublishAttempts counts the failed publishes, retried from NextAttemptAt.
+type OutboxMessage struct {
+	ID              string       `bson:"id" json:"id"`
+	JobID           string       `bson:"jobId" json:"job_id"`
+	Attempt         int          `bson:"attempt" json:"attempt"`
+	Status          OutboxStatus `bson:"status" json:"status"`
+	PublishAttempts int          `bson:"publishAttempts" json:"publish_attempts"`
+	LastError       string       `bson:"lastError,omitempty" json:"last_error,omitempty"`
+	CreatedAt       time.Time    `bson:"createdAt" json:"created_at"`
+	NextAttemptAt   time.Time    `bson:"nextAttemptAt" json:"next_attempt_at"`
+	SentAt          *time.Time   `bson:"sentAt,omitempty" json:"sent_at,omitempty"`
+}
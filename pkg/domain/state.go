@@ -0,0 +1,87 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrConversionNotFound is returned when changing the status of a conversion that does not exist
+var ErrConversionNotFound = errors.New("conversion not found")
+
+// ErrIllegalTransition is matched by every TransitionError
+var ErrIllegalTransition = errors.New("illegal conversion status transition")
+
+// conversionTransitions lists the statuses a conversion may move to from each status. A conversion
+// starts pending and is in progress while a worker runs it; it goes back to pending to wait for a
+// retry, and a failed conversion may be retried manually. Completed and cancelled are final.
+// Staying in progress is allowed so that a running conversion can record its progress.
+var conversionTransitions = map[ConversionStatus][]ConversionStatus{
+	ConversionPending:    {ConversionInProgress, ConversionFailed, ConversionCancelled},
+	ConversionInProgress: {ConversionInProgress, ConversionPending, ConversionCompleted, ConversionFailed, ConversionCancelled},
+	ConversionFailed:     {ConversionPending},
+	ConversionCompleted:  {},
+	ConversionCancelled:  {},
+}
+
+// TransitionError describes a status change the state machine does not allow
+type TransitionError struct {
+	ConversionID string
+	From         ConversionStatus
+	To           ConversionStatus
+}
+
+func (e *TransitionError) Error() string {
+	if e.ConversionID == "" {
+		return fmt.Sprintf("%v from %s to %s", ErrIllegalTransition, e.From, e.To)
+	}
+	return fmt.Sprintf("%v from %s to %s for conversion %s", ErrIllegalTransition, e.From, e.To, e.ConversionID)
+}
+
+// Is makes every TransitionError match ErrIllegalTransition
+func (e *TransitionError) Is(target error) bool {
+	return target == ErrIllegalTransition
+}
+
+// CanTransitionTo reports whether a conversion may move from s to next
+func (s ConversionStatus) CanTransitionTo(next ConversionStatus) bool {
+	for _, status := range conversionTransitions[s] {
+		if status == next {
+			return true
+		}
+	}
+	return false
+}
+
+// IsFinished reports whether a conversion with status s no longer runs nor waits to run
+func (s ConversionStatus) IsFinished() bool {
+	switch s {
+	case ConversionCompleted, ConversionFailed, ConversionCancelled:
+		return true
+	}
+	return false
+}
+
+// ValidateTransition returns a TransitionError when a conversion may not move from any of from to
+// to. Without from, any status that may move to to is accepted.
+func ValidateTransition(to ConversionStatus, from ...ConversionStatus) error {
+	if _, ok := conversionTransitions[to]; !ok {
+		return &TransitionError{To: to}
+	}
+	for _, status := range from {
+		if !status.CanTransitionTo(to) {
+			return &TransitionError{From: status, To: to}
+		}
+	}
+	return nil
+}
+
+// TransitionSources returns the statuses a conversion may move to to from
+func TransitionSources(to ConversionStatus) []ConversionStatus {
+	var sources []ConversionStatus
+	for _, from := range []ConversionStatus{ConversionPending, ConversionInProgress, ConversionCompleted, ConversionFailed, ConversionCancelled} {
+		if from.CanTransitionTo(to) {
+			sources = append(sources, from)
+		}
+	}
+	return sources
+}
@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanTransitionTo(t *testing.T) {
+	assert.True(t, ConversionPending.CanTransitionTo(ConversionInProgress))
+	assert.True(t, ConversionInProgress.CanTransitionTo(ConversionInProgress))
+	assert.True(t, ConversionInProgress.CanTransitionTo(ConversionPending))
+	assert.True(t, ConversionFailed.CanTransitionTo(ConversionPending))
+
+	assert.False(t, ConversionCompleted.CanTransitionTo(ConversionInProgress))
+	assert.False(t, ConversionCancelled.CanTransitionTo(ConversionPending))
+	assert.False(t, ConversionFailed.CanTransitionTo(ConversionInProgress))
+	assert.False(t, ConversionPending.CanTransitionTo(ConversionCompleted))
+}
+
+func TestValidateTransition(t *testing.T) {
+	assert.NoError(t, ValidateTransition(ConversionCancelled, ConversionPending, ConversionInProgress))
+	assert.NoError(t, ValidateTransition(ConversionPending))
+
+	err := ValidateTransition(ConversionInProgress, ConversionPending, ConversionCompleted)
+	assert.True(t, errors.Is(err, ErrIllegalTransition))
+
+	var transitionErr *TransitionError
+	assert.True(t, errors.As(err, &transitionErr))
+	assert.Equal(t, ConversionCompleted, transitionErr.From)
+	assert.Equal(t, ConversionInProgress, transitionErr.To)
+
+	assert.ErrorIs(t, ValidateTransition("unknown"), ErrIllegalTransition)
+}
+
+func TestTransitionSources(t *testing.T) {
+	assert.Equal(t, []ConversionStatus{ConversionPending, ConversionInProgress}, TransitionSources(ConversionInProgress))
+	assert.Equal(t, []ConversionStatus{ConversionInProgress, ConversionFailed}, TransitionSources(ConversionPending))
+	assert.Equal(t, []ConversionStatus{ConversionInProgress}, TransitionSources(ConversionCompleted))
+}
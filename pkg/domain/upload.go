@@ -0,0 +1,15 @@
+package domain
+
+import "time"
+
+// Upload groups the conversions created from a single uploaded file.
+// The original is stored once and shared by every conversion of the upload.
+// BatchID links the upload to the batch it was part of, if any.
+type Upload struct {
+	ID            string       `bson:"_id,omitempty" json:"id"`
+	BatchID       string       `bson:"batchId,omitempty" json:"batch_id,omitempty"`
+	File          FileMetadata `bson:"file" json:"file"`
+	TargetFormats []string     `bson:"targetFormats" json:"target_formats"`
+	Source        JobSource    `bson:"source" json:"source"`
+	CreatedAt     time.Time    `bson:"createdAt" json:"created_at"`
+}
@@ -0,0 +1,27 @@
+package domain
+
+import "time"
+
+// ValidationSeverity tells whether a validation problem prevents conversion
+type ValidationSeverity string
+
+const (
+	ValidationError   ValidationSeverity = "error"
+	ValidationWarning ValidationSeverity = "warning"
+)
+
+// ValidationProblem is a single issue found in an uploaded file.
+// Offset is the byte offset in the file, when the problem has one.
+type ValidationProblem struct {
+	Severity ValidationSeverity `bson:"severity" json:"severity"`
+	Code     string             `bson:"code" json:"code"`
+	Message  string             `bson:"message" json:"message"`
+	Offset   *int64             `bson:"offset,omitempty" json:"offset,omitempty"`
+}
+
+// ValidationReport is the result of validating an uploaded file in depth
+type ValidationReport struct {
+	Valid       bool                `bson:"valid" json:"valid"`
+	Problems    []ValidationProblem `bson:"problems" json:"problems"`
+	ValidatedAt time.Time           `bson:"validatedAt" json:"validated_at"`
+}
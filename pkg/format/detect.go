@@ -0,0 +1,104 @@
+package format
+
+import (
+	"bytes"
+	"encoding/binary"
+	"unicode/utf8"
+)
+
+// SniffSize is the number of leading bytes Detect needs to identify a format
+const SniffSize = 4096
+
+// binarySTLHeaderSize is the 80 byte header plus the 32-bit triangle count of binary STL
+const binarySTLHeaderSize = 84
+
+// Detect identifies the format of a model from its leading bytes and total size.
+// It returns the format's extension (e.g. ".stl"), or "" when the content is not recognised.
+func Detect(header []byte, size int64) string {
+	switch {
+	case bytes.HasPrefix(header, []byte("SHPR")):
+		return ".shapr"
+	case bytes.HasPrefix(header, []byte("glTF")):
+		return ".glb"
+	case bytes.HasPrefix(header, []byte("ply\n")), bytes.HasPrefix(header, []byte("ply\r\n")):
+		return ".ply"
+	case isBinarySTL(header, size):
+		// checked before ASCII STL: some exporters start binary headers with "solid"
+		return ".stl"
+	}
+
+	if !isText(header) {
+		return ""
+	}
+
+	text := bytes.TrimLeft(header, " \t\r\n\ufeff")
+	switch {
+	case bytes.HasPrefix(text, []byte("solid")) && bytes.Contains(text, []byte("facet")):
+		return ".stl"
+	case bytes.HasPrefix(text, []byte("{")) && bytes.Contains(text, []byte(`"asset"`)):
+		return ".gltf"
+	case isOBJ(text):
+		return ".obj"
+	}
+
+	return ""
+}
+
+// isBinarySTL checks that the size matches the triangle count declared in the header
+func isBinarySTL(header []byte, size int64) bool {
+	if len(header) < binarySTLHeaderSize {
+		return false
+	}
+	count := int64(binary.LittleEndian.Uint32(header[80:84]))
+	return count > 0 && size == binarySTLHeaderSize+count*50
+}
+
+// objStatements are the OBJ statements accepted when sniffing a file
+var objStatements = map[string]bool{
+	"v": true, "vt": true, "vn": true, "vp": true, "f": true, "l": true, "p": true,
+	"o": true, "g": true, "s": true, "mtllib": true, "usemtl": true,
+}
+
+// isOBJ looks for a vertex line among lines made only of comments and known OBJ statements
+func isOBJ(text []byte) bool {
+	hasVertex := false
+
+	lines := bytes.Split(text, []byte("\n"))
+	// the last line may have been cut by the sniff window
+	if len(lines) > 1 {
+		lines = lines[:len(lines)-1]
+	}
+
+	for _, line := range lines {
+		fields := bytes.Fields(line)
+		if len(fields) == 0 || fields[0][0] == '#' {
+			continue
+		}
+
+		statement := string(fields[0])
+		if !objStatements[statement] {
+			return false
+		}
+		hasVertex = hasVertex || statement == "v"
+	}
+
+	return hasVertex
+}
+
+// isText reports whether the bytes look like UTF-8 text without control characters
+func isText(data []byte) bool {
+	// the sniff window may split a multi-byte rune at the end
+	for i := 0; i < utf8.UTFMax-1 && len(data) > 0 && !utf8.Valid(data); i++ {
+		data = data[:len(data)-1]
+	}
+	if !utf8.Valid(data) {
+		return false
+	}
+
+	for _, r := range string(data) {
+		if r < 0x20 && r != '\n' && r != '\r' && r != '\t' {
+			return false
+		}
+	}
+	return len(data) > 0
+}
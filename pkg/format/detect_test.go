@@ -0,0 +1,37 @@
+package format
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetect(t *testing.T) {
+	binarySTL := make([]byte, binarySTLHeaderSize+50)
+	copy(binarySTL, "solid exported by a tool that writes binary")
+	binary.LittleEndian.PutUint32(binarySTL[80:], 1)
+
+	tests := map[string]struct {
+		header []byte
+		size   int64
+		want   string
+	}{
+		"shapr":       {[]byte("SHPR\x01\x00"), 16, ".shapr"},
+		"glb":         {[]byte("glTF\x02\x00\x00\x00"), 12, ".glb"},
+		"ply":         {[]byte("ply\nformat ascii 1.0\n"), 21, ".ply"},
+		"binary stl":  {binarySTL, int64(len(binarySTL)), ".stl"},
+		"ascii stl":   {[]byte("solid cube\n  facet normal 0 0 1\n"), 32, ".stl"},
+		"gltf":        {[]byte("\ufeff{\n  \"asset\": {\"version\": \"2.0\"}\n}"), 36, ".gltf"},
+		"obj":         {[]byte("# exported\nmtllib cube.mtl\nv 0 0 0\nv 1 0 0\n"), 42, ".obj"},
+		"plain json":  {[]byte(`{"name": "not a model"}`), 23, ""},
+		"random text": {[]byte("hello world\n"), 12, ""},
+		"binary junk": {[]byte{0x00, 0xff, 0x10, 0x80}, 4, ""},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.want, Detect(tc.header, tc.size))
+		})
+	}
+}
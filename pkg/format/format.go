@@ -0,0 +1,34 @@
+// Package format holds what converto knows about file formats independently of
+// any single reader or writer, such as their media types.
+package format
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// DefaultContentType is used for formats without a more specific media type
+const DefaultContentType = "application/octet-stream"
+
+// contentTypes maps file extensions to the media type served on download
+var contentTypes = map[string]string{
+	".shapr": DefaultContentType,
+	".step":  "model/step",
+	".iges":  "model/iges",
+	".stl":   "model/stl",
+	".obj":   "model/obj",
+	".mtl":   "model/mtl",
+	".glb":   "model/gltf-binary",
+	".gltf":  "model/gltf+json",
+	".3mf":   "model/3mf",
+	".ply":   "model/x-ply",
+	".png":   "image/png",
+}
+
+// ContentType returns the media type for a file name based on its extension
+func ContentType(name string) string {
+	if contentType, ok := contentTypes[strings.ToLower(filepath.Ext(name))]; ok {
+		return contentType
+	}
+	return DefaultContentType
+}
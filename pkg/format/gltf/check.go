@@ -0,0 +1,59 @@
+package gltf
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// CheckGLB verifies the container structure of a .glb file without parsing its JSON:
+// the header, the declared total length against the file size and the chunk layout.
+// size is the total file size, or -1 when unknown.
+func CheckGLB(r io.Reader, size int64) []error {
+	var problems []error
+
+	header := make([]byte, 12)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return append(problems, fmt.Errorf("%w: incomplete GLB header", ErrMalformed))
+	}
+	if binary.LittleEndian.Uint32(header) != glbMagic {
+		return append(problems, fmt.Errorf("%w: not a GLB container", ErrMalformed))
+	}
+	if version := binary.LittleEndian.Uint32(header[4:]); version != glbVersion {
+		return append(problems, fmt.Errorf("%w: unsupported GLB version %d", ErrMalformed, version))
+	}
+
+	length := int64(binary.LittleEndian.Uint32(header[8:]))
+	if size >= 0 && length != size {
+		problems = append(problems, fmt.Errorf("%w: header declares %d bytes, file has %d", ErrMalformed, length, size))
+	}
+
+	offset := int64(12)
+	for index := 0; ; index++ {
+		n, err := io.ReadFull(r, header[:8])
+		if n == 0 && err == io.EOF {
+			if index == 0 {
+				problems = append(problems, fmt.Errorf("%w: missing JSON chunk", ErrMalformed))
+			}
+			return problems
+		}
+		if err != nil {
+			return append(problems, fmt.Errorf("%w: incomplete chunk header at offset %d", ErrMalformed, offset))
+		}
+
+		chunkLength := int64(binary.LittleEndian.Uint32(header))
+		kind := binary.LittleEndian.Uint32(header[4:])
+		if index == 0 && kind != glbChunkJSON {
+			problems = append(problems, fmt.Errorf("%w: first chunk at offset %d is not JSON", ErrMalformed, offset))
+		}
+		if chunkLength%4 != 0 {
+			problems = append(problems, fmt.Errorf("%w: chunk at offset %d is not 4-byte aligned", ErrMalformed, offset))
+		}
+
+		copied, err := io.CopyN(io.Discard, r, chunkLength)
+		if err != nil {
+			return append(problems, fmt.Errorf("%w: chunk at offset %d declares %d bytes, only %d present", ErrMalformed, offset, chunkLength, copied))
+		}
+		offset += 8 + chunkLength
+	}
+}
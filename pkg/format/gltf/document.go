@@ -0,0 +1,90 @@
+// Package gltf reads and writes glTF 2.0 scenes, either as a .gltf JSON file with an
+// embedded base64 buffer or as a single binary .glb container.
+package gltf
+
+// glTF component types and buffer view targets used by the writer
+const (
+	componentFloat        = 5126
+	componentUnsignedInt  = 5125
+	targetArrayBuffer     = 34962
+	targetElementArray    = 34963
+	primitiveModeTriangle = 4
+)
+
+// Document is the JSON part of a glTF asset, limited to what the writer produces
+type Document struct {
+	Asset       Asset        `json:"asset"`
+	Scene       int          `json:"scene"`
+	Scenes      []Scene      `json:"scenes"`
+	Nodes       []Node       `json:"nodes"`
+	Meshes      []Mesh       `json:"meshes,omitempty"`
+	Materials   []Material   `json:"materials,omitempty"`
+	Accessors   []Accessor   `json:"accessors,omitempty"`
+	BufferViews []BufferView `json:"bufferViews,omitempty"`
+	Buffers     []Buffer     `json:"buffers,omitempty"`
+}
+
+type Asset struct {
+	Version   string            `json:"version"`
+	Generator string            `json:"generator,omitempty"`
+	Extras    map[string]string `json:"extras,omitempty"`
+}
+
+type Scene struct {
+	Name  string `json:"name,omitempty"`
+	Nodes []int  `json:"nodes"`
+}
+
+type Node struct {
+	Name     string       `json:"name,omitempty"`
+	Children []int        `json:"children,omitempty"`
+	Mesh     *int         `json:"mesh,omitempty"`
+	Matrix   *[16]float64 `json:"matrix,omitempty"`
+	Scale    *[3]float64  `json:"scale,omitempty"`
+}
+
+type Mesh struct {
+	Name       string      `json:"name,omitempty"`
+	Primitives []Primitive `json:"primitives"`
+}
+
+type Primitive struct {
+	Attributes map[string]int `json:"attributes"`
+	Indices    *int           `json:"indices,omitempty"`
+	Material   *int           `json:"material,omitempty"`
+	Mode       int            `json:"mode"`
+}
+
+type Material struct {
+	Name                 string               `json:"name,omitempty"`
+	PBRMetallicRoughness PBRMetallicRoughness `json:"pbrMetallicRoughness"`
+	AlphaMode            string               `json:"alphaMode,omitempty"`
+	DoubleSided          bool                 `json:"doubleSided,omitempty"`
+}
+
+type PBRMetallicRoughness struct {
+	BaseColorFactor [4]float64 `json:"baseColorFactor"`
+	MetallicFactor  float64    `json:"metallicFactor"`
+	RoughnessFactor float64    `json:"roughnessFactor"`
+}
+
+type Accessor struct {
+	BufferView    int       `json:"bufferView"`
+	ComponentType int       `json:"componentType"`
+	Count         int       `json:"count"`
+	Type          string    `json:"type"`
+	Min           []float64 `json:"min,omitempty"`
+	Max           []float64 `json:"max,omitempty"`
+}
+
+type BufferView struct {
+	Buffer     int `json:"buffer"`
+	ByteOffset int `json:"byteOffset"`
+	ByteLength int `json:"byteLength"`
+	Target     int `json:"target,omitempty"`
+}
+
+type Buffer struct {
+	ByteLength int    `json:"byteLength"`
+	URI        string `json:"uri,omitempty"`
+}
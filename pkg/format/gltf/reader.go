@@ -0,0 +1,516 @@
+package gltf
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/wildan3105/converto/pkg/scene"
+)
+
+// ErrMalformed is returned when a glTF asset cannot be read
+var ErrMalformed = errors.New("gltf: malformed asset")
+
+// glTF component types accepted by the reader, in addition to those the writer uses
+const (
+	componentByte          = 5120
+	componentUnsignedByte  = 5121
+	componentShort         = 5122
+	componentUnsignedShort = 5123
+)
+
+// maxUnbackedCount bounds the elements of an accessor without a buffer view, which are all zeros
+const maxUnbackedCount = 1 << 20
+
+// inputDocument extends Document with the fields only needed when reading
+type inputDocument struct {
+	Document
+	Nodes     []inputNode     `json:"nodes"`
+	Accessors []inputAccessor `json:"accessors"`
+	Views     []inputView     `json:"bufferViews"`
+}
+
+type inputNode struct {
+	Node
+	Translation *[3]float64 `json:"translation"`
+	Rotation    *[4]float64 `json:"rotation"`
+}
+
+type inputAccessor struct {
+	Accessor
+	BufferView *int `json:"bufferView"`
+	ByteOffset int  `json:"byteOffset"`
+	Normalized bool `json:"normalized"`
+}
+
+type inputView struct {
+	BufferView
+	ByteStride int `json:"byteStride"`
+}
+
+// DecodeGLTF reads a .gltf JSON document. Only embedded (data URI) buffers are supported.
+func DecodeGLTF(r io.Reader) (*scene.Scene, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return decode(data, nil)
+}
+
+// DecodeGLB reads a binary .glb container
+func DecodeGLB(r io.Reader) (*scene.Scene, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < 20 || binary.LittleEndian.Uint32(data) != glbMagic {
+		return nil, fmt.Errorf("%w: not a GLB container", ErrMalformed)
+	}
+	if version := binary.LittleEndian.Uint32(data[4:]); version != glbVersion {
+		return nil, fmt.Errorf("%w: unsupported GLB version %d", ErrMalformed, version)
+	}
+	if length := binary.LittleEndian.Uint32(data[8:]); int(length) > len(data) {
+		return nil, fmt.Errorf("%w: container shorter than declared length", ErrMalformed)
+	}
+
+	var jsonChunk, binChunk []byte
+	for offset := 12; offset+8 <= len(data); {
+		length := int(binary.LittleEndian.Uint32(data[offset:]))
+		kind := binary.LittleEndian.Uint32(data[offset+4:])
+		start := offset + 8
+		if length < 0 || start+length > len(data) {
+			return nil, fmt.Errorf("%w: chunk exceeds container", ErrMalformed)
+		}
+
+		switch {
+		case kind == glbChunkJSON && jsonChunk == nil:
+			jsonChunk = data[start : start+length]
+		case kind == glbChunkBIN && binChunk == nil:
+			binChunk = data[start : start+length]
+		}
+		offset = start + length
+	}
+
+	if jsonChunk == nil {
+		return nil, fmt.Errorf("%w: missing JSON chunk", ErrMalformed)
+	}
+
+	return decode(jsonChunk, binChunk)
+}
+
+// decode builds a scene from the glTF JSON and the optional GLB binary chunk.
+// Every node holding a mesh becomes a body with its world transform; the scene is in meters.
+func decode(jsonData, glbBuffer []byte) (*scene.Scene, error) {
+	var doc inputDocument
+	if err := json.Unmarshal(jsonData, &doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	if !strings.HasPrefix(doc.Asset.Version, "2.") {
+		return nil, fmt.Errorf("%w: unsupported glTF version %q", ErrMalformed, doc.Asset.Version)
+	}
+
+	buffers := make([][]byte, len(doc.Buffers))
+	for i, buffer := range doc.Buffers {
+		switch {
+		case buffer.URI == "" && i == 0 && glbBuffer != nil:
+			buffers[i] = glbBuffer
+		case strings.HasPrefix(buffer.URI, "data:"):
+			comma := strings.IndexByte(buffer.URI, ',')
+			if comma < 0 || !strings.HasSuffix(buffer.URI[:comma], ";base64") {
+				return nil, fmt.Errorf("%w: buffer %d has an unsupported data URI", ErrMalformed, i)
+			}
+			data, err := base64.StdEncoding.DecodeString(buffer.URI[comma+1:])
+			if err != nil {
+				return nil, fmt.Errorf("%w: buffer %d: %v", ErrMalformed, i, err)
+			}
+			buffers[i] = data
+		default:
+			return nil, fmt.Errorf("%w: buffer %d references an external file", ErrMalformed, i)
+		}
+	}
+
+	d := &reader{doc: &doc, buffers: buffers, scene: scene.New()}
+	d.scene.Units = scene.UnitsMeter
+	for key, value := range doc.Asset.Extras {
+		d.scene.Metadata[key] = value
+	}
+
+	for _, material := range doc.Materials {
+		pbr := material.PBRMetallicRoughness
+		d.scene.Materials = append(d.scene.Materials, scene.Material{
+			Name:      material.Name,
+			BaseColor: pbr.BaseColorFactor,
+			Metallic:  pbr.MetallicFactor,
+			Roughness: pbr.RoughnessFactor,
+		})
+	}
+	// the glTF defaults apply when factors are omitted
+	var raw struct {
+		Materials []struct {
+			PBR map[string]json.RawMessage `json:"pbrMetallicRoughness"`
+		} `json:"materials"`
+	}
+	_ = json.Unmarshal(jsonData, &raw)
+	for i := range d.scene.Materials {
+		if i >= len(raw.Materials) {
+			break
+		}
+		pbr := raw.Materials[i].PBR
+		if _, ok := pbr["baseColorFactor"]; !ok {
+			d.scene.Materials[i].BaseColor = scene.Color{1, 1, 1, 1}
+		}
+		if _, ok := pbr["metallicFactor"]; !ok {
+			d.scene.Materials[i].Metallic = 1
+		}
+		if _, ok := pbr["roughnessFactor"]; !ok {
+			d.scene.Materials[i].Roughness = 1
+		}
+	}
+
+	// without scenes, the default scene index 0 selects every root node
+	if doc.Scene < 0 || doc.Scene > 0 && doc.Scene >= len(doc.Scenes) {
+		return nil, fmt.Errorf("%w: scene %d out of range", ErrMalformed, doc.Scene)
+	}
+	var roots []int
+	if len(doc.Scenes) > 0 {
+		roots = doc.Scenes[doc.Scene].Nodes
+	} else {
+		roots = d.rootNodes()
+	}
+
+	for _, root := range roots {
+		if err := d.visit(root, scene.Identity(), 0); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(d.scene.Bodies) == 0 {
+		return nil, fmt.Errorf("%w: no meshes in scene", ErrMalformed)
+	}
+
+	return d.scene, nil
+}
+
+type reader struct {
+	doc     *inputDocument
+	buffers [][]byte
+	scene   *scene.Scene
+	visited []bool
+}
+
+// rootNodes returns the nodes that are nobody's child
+func (d *reader) rootNodes() []int {
+	isChild := make([]bool, len(d.doc.Nodes))
+	for _, node := range d.doc.Nodes {
+		for _, child := range node.Children {
+			if child >= 0 && child < len(isChild) {
+				isChild[child] = true
+			}
+		}
+	}
+
+	var roots []int
+	for i, child := range isChild {
+		if !child {
+			roots = append(roots, i)
+		}
+	}
+	return roots
+}
+
+func (d *reader) visit(index int, parent scene.Mat4, depth int) error {
+	if index < 0 || index >= len(d.doc.Nodes) {
+		return fmt.Errorf("%w: node %d out of range", ErrMalformed, index)
+	}
+	if depth > 64 {
+		return fmt.Errorf("%w: node hierarchy too deep or cyclic", ErrMalformed)
+	}
+	// nodes form a strict tree: a node reached twice is shared or part of a cycle
+	if d.visited == nil {
+		d.visited = make([]bool, len(d.doc.Nodes))
+	}
+	if d.visited[index] {
+		return fmt.Errorf("%w: node %d has more than one parent", ErrMalformed, index)
+	}
+	d.visited[index] = true
+
+	node := &d.doc.Nodes[index]
+	world := parent.Mul(localTransform(node))
+
+	if node.Mesh != nil {
+		body, err := d.body(node, world)
+		if err != nil {
+			return err
+		}
+		d.scene.Bodies = append(d.scene.Bodies, body)
+	}
+
+	for _, child := range node.Children {
+		if err := d.visit(child, world, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *reader) body(node *inputNode, world scene.Mat4) (scene.Body, error) {
+	meshIndex := *node.Mesh
+	if meshIndex < 0 || meshIndex >= len(d.doc.Meshes) {
+		return scene.Body{}, fmt.Errorf("%w: mesh %d out of range", ErrMalformed, meshIndex)
+	}
+	gltfMesh := d.doc.Meshes[meshIndex]
+
+	name := node.Name
+	if name == "" {
+		name = gltfMesh.Name
+	}
+	if name == "" {
+		name = fmt.Sprintf("body_%d", len(d.scene.Bodies)+1)
+	}
+
+	body := scene.Body{Name: name, Transform: world}
+	for p, primitive := range gltfMesh.Primitives {
+		if primitive.Mode != primitiveModeTriangle && primitive.Mode != 0 {
+			// points and lines have no surface to convert
+			continue
+		}
+
+		mesh, err := d.mesh(primitive)
+		if err != nil {
+			return scene.Body{}, fmt.Errorf("mesh %d primitive %d: %w", meshIndex, p, err)
+		}
+		mesh.Name = fmt.Sprintf("%s_%d", name, p)
+		body.Meshes = append(body.Meshes, mesh)
+	}
+
+	return body, nil
+}
+
+func (d *reader) mesh(primitive Primitive) (scene.Mesh, error) {
+	mesh := scene.Mesh{Material: scene.NoMaterial}
+
+	position, ok := primitive.Attributes["POSITION"]
+	if !ok {
+		return mesh, fmt.Errorf("%w: primitive without POSITION", ErrMalformed)
+	}
+
+	values, _, err := d.accessor(position, "VEC3")
+	if err != nil {
+		return mesh, err
+	}
+	for i := 0; i+2 < len(values); i += 3 {
+		mesh.Positions = append(mesh.Positions, scene.Vec3{values[i], values[i+1], values[i+2]})
+	}
+
+	if normal, ok := primitive.Attributes["NORMAL"]; ok {
+		values, _, err := d.accessor(normal, "VEC3")
+		if err != nil {
+			return mesh, err
+		}
+		for i := 0; i+2 < len(values); i += 3 {
+			mesh.Normals = append(mesh.Normals, scene.Vec3{values[i], values[i+1], values[i+2]})
+		}
+	}
+
+	if uv, ok := primitive.Attributes["TEXCOORD_0"]; ok {
+		values, _, err := d.accessor(uv, "VEC2")
+		if err != nil {
+			return mesh, err
+		}
+		for i := 0; i+1 < len(values); i += 2 {
+			mesh.UVs = append(mesh.UVs, scene.Vec2{values[i], values[i+1]})
+		}
+	}
+
+	if color, ok := primitive.Attributes["COLOR_0"]; ok {
+		values, kind, err := d.accessor(color, "")
+		if err != nil {
+			return mesh, err
+		}
+		stride := 4
+		if kind == "VEC3" {
+			stride = 3
+		}
+		for i := 0; i+stride-1 < len(values); i += stride {
+			c := scene.Color{values[i], values[i+1], values[i+2], 1}
+			if stride == 4 {
+				c[3] = values[i+3]
+			}
+			mesh.Colors = append(mesh.Colors, c)
+		}
+	}
+
+	if primitive.Indices != nil {
+		values, _, err := d.accessor(*primitive.Indices, "SCALAR")
+		if err != nil {
+			return mesh, err
+		}
+		mesh.Indices = make([]uint32, len(values))
+		for i, v := range values {
+			mesh.Indices[i] = uint32(v)
+		}
+	} else {
+		mesh.Indices = make([]uint32, len(mesh.Positions))
+		for i := range mesh.Indices {
+			mesh.Indices[i] = uint32(i)
+		}
+	}
+	mesh.Indices = mesh.Indices[:len(mesh.Indices)/3*3]
+
+	if primitive.Material != nil {
+		if *primitive.Material < 0 || *primitive.Material >= len(d.scene.Materials) {
+			return mesh, fmt.Errorf("%w: material %d out of range", ErrMalformed, *primitive.Material)
+		}
+		mesh.Material = *primitive.Material
+	}
+
+	if err := mesh.Validate(); err != nil {
+		return mesh, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+
+	return mesh, nil
+}
+
+// accessor returns the accessor's values as float64s (normalized where requested) and its type
+func (d *reader) accessor(index int, wantType string) ([]float64, string, error) {
+	if index < 0 || index >= len(d.doc.Accessors) {
+		return nil, "", fmt.Errorf("%w: accessor %d out of range", ErrMalformed, index)
+	}
+	a := d.doc.Accessors[index]
+	if wantType != "" && a.Type != wantType {
+		return nil, "", fmt.Errorf("%w: accessor %d has type %s, expected %s", ErrMalformed, index, a.Type, wantType)
+	}
+
+	components := map[string]int{"SCALAR": 1, "VEC2": 2, "VEC3": 3, "VEC4": 4}[a.Type]
+	size := map[int]int{
+		componentByte: 1, componentUnsignedByte: 1,
+		componentShort: 2, componentUnsignedShort: 2,
+		componentUnsignedInt: 4, componentFloat: 4,
+	}[a.ComponentType]
+	if components == 0 || size == 0 {
+		return nil, "", fmt.Errorf("%w: accessor %d has an unsupported layout", ErrMalformed, index)
+	}
+
+	if a.Count < 0 {
+		return nil, "", fmt.Errorf("%w: accessor %d has a negative count", ErrMalformed, index)
+	}
+	if a.BufferView == nil {
+		// sparse-only or zero-initialised accessors
+		if a.Count > maxUnbackedCount {
+			return nil, "", fmt.Errorf("%w: accessor %d has too many elements", ErrMalformed, index)
+		}
+		return make([]float64, a.Count*components), a.Type, nil
+	}
+
+	if *a.BufferView < 0 || *a.BufferView >= len(d.doc.Views) {
+		return nil, "", fmt.Errorf("%w: buffer view %d out of range", ErrMalformed, *a.BufferView)
+	}
+	view := d.doc.Views[*a.BufferView]
+	if view.Buffer < 0 || view.Buffer >= len(d.buffers) {
+		return nil, "", fmt.Errorf("%w: buffer %d out of range", ErrMalformed, view.Buffer)
+	}
+	buffer := d.buffers[view.Buffer]
+	if view.ByteOffset < 0 || view.ByteLength < 0 || view.ByteOffset > len(buffer) || view.ByteLength > len(buffer)-view.ByteOffset {
+		return nil, "", fmt.Errorf("%w: buffer view %d exceeds its buffer", ErrMalformed, *a.BufferView)
+	}
+	data := buffer[view.ByteOffset : view.ByteOffset+view.ByteLength]
+
+	elementSize := components * size
+	stride := view.ByteStride
+	if stride == 0 {
+		stride = elementSize
+	}
+	if stride < elementSize || stride > len(data) {
+		return nil, "", fmt.Errorf("%w: buffer view %d has an invalid stride", ErrMalformed, *a.BufferView)
+	}
+	// every element takes at least a byte, which keeps the bound below from overflowing
+	if a.ByteOffset < 0 || a.ByteOffset > len(data) || a.Count > len(data) || (a.Count > 0 && a.ByteOffset+(a.Count-1)*stride+elementSize > len(data)) {
+		return nil, "", fmt.Errorf("%w: accessor %d exceeds its buffer view", ErrMalformed, index)
+	}
+
+	values := make([]float64, 0, a.Count*components)
+	reader := bytes.NewReader(nil)
+	for i := 0; i < a.Count; i++ {
+		start := a.ByteOffset + i*stride
+		reader.Reset(data[start : start+elementSize])
+		for c := 0; c < components; c++ {
+			values = append(values, readComponent(reader, a.ComponentType, a.Normalized))
+		}
+	}
+
+	return values, a.Type, nil
+}
+
+func readComponent(r *bytes.Reader, componentType int, normalized bool) float64 {
+	var buf [4]byte
+	switch componentType {
+	case componentByte:
+		_, _ = r.Read(buf[:1])
+		v := float64(int8(buf[0]))
+		if normalized {
+			return math.Max(v/127, -1)
+		}
+		return v
+	case componentUnsignedByte:
+		_, _ = r.Read(buf[:1])
+		if normalized {
+			return float64(buf[0]) / 255
+		}
+		return float64(buf[0])
+	case componentShort:
+		_, _ = r.Read(buf[:2])
+		v := float64(int16(binary.LittleEndian.Uint16(buf[:])))
+		if normalized {
+			return math.Max(v/32767, -1)
+		}
+		return v
+	case componentUnsignedShort:
+		_, _ = r.Read(buf[:2])
+		if normalized {
+			return float64(binary.LittleEndian.Uint16(buf[:])) / 65535
+		}
+		return float64(binary.LittleEndian.Uint16(buf[:]))
+	case componentUnsignedInt:
+		_, _ = r.Read(buf[:4])
+		return float64(binary.LittleEndian.Uint32(buf[:]))
+	default:
+		_, _ = r.Read(buf[:4])
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(buf[:])))
+	}
+}
+
+// localTransform returns the node's matrix, or composes it from translation, rotation and scale
+func localTransform(node *inputNode) scene.Mat4 {
+	if node.Matrix != nil {
+		return scene.Mat4(*node.Matrix)
+	}
+
+	t := scene.Identity()
+	if node.Scale != nil {
+		s := node.Scale
+		t = scene.Mat4{s[0], 0, 0, 0, 0, s[1], 0, 0, 0, 0, s[2], 0, 0, 0, 0, 1}
+	}
+	if node.Rotation != nil {
+		x, y, z, w := node.Rotation[0], node.Rotation[1], node.Rotation[2], node.Rotation[3]
+		rotation := scene.Mat4{
+			1 - 2*(y*y+z*z), 2 * (x*y + z*w), 2 * (x*z - y*w), 0,
+			2 * (x*y - z*w), 1 - 2*(x*x+z*z), 2 * (y*z + x*w), 0,
+			2 * (x*z + y*w), 2 * (y*z - x*w), 1 - 2*(x*x+y*y), 0,
+			0, 0, 0, 1,
+		}
+		t = rotation.Mul(t)
+	}
+	if node.Translation != nil {
+		translation := scene.Identity()
+		translation[12], translation[13], translation[14] = node.Translation[0], node.Translation[1], node.Translation[2]
+		t = translation.Mul(t)
+	}
+	return t
+}
@@ -0,0 +1,164 @@
+package gltf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wildan3105/converto/pkg/scene"
+	"github.com/wildan3105/converto/pkg/scene/scenetest"
+)
+
+func TestDecodeRoundTrip(t *testing.T) {
+	encoders := map[string]func(*bytes.Buffer) error{
+		"gltf": func(buf *bytes.Buffer) error { return EncodeGLTF(buf, scenetest.Cube(20)) },
+		"glb":  func(buf *bytes.Buffer) error { return EncodeGLB(buf, scenetest.Cube(20)) },
+	}
+	decoders := map[string]func(*bytes.Buffer) (*scene.Scene, error){
+		"gltf": func(buf *bytes.Buffer) (*scene.Scene, error) { return DecodeGLTF(buf) },
+		"glb":  func(buf *bytes.Buffer) (*scene.Scene, error) { return DecodeGLB(buf) },
+	}
+
+	for name, encode := range encoders {
+		var buf bytes.Buffer
+		require.NoError(t, encode(&buf), name)
+
+		s, err := decoders[name](&buf)
+		require.NoError(t, err, name)
+		require.NoError(t, s.Validate(), name)
+
+		assert.Equal(t, scene.UnitsMeter, s.Units, name)
+		require.Len(t, s.Bodies, 1, name)
+		assert.Equal(t, "Body 1", s.Bodies[0].Name, name)
+		assert.Equal(t, 12, s.TriangleCount(), name)
+
+		require.Len(t, s.Materials, 1, name)
+		assert.Equal(t, "grey", s.Materials[0].Name, name)
+		assert.InDelta(t, 0.75, s.Materials[0].Roughness, 1e-6, name)
+
+		// the writer's millimeter-to-meter root scale ends up in the body transform
+		var max scene.Vec3
+		s.Bodies[0].ForEachTriangle(func(a, b, c scene.Vec3) {
+			max = max.Max(a).Max(b).Max(c)
+		})
+		assert.InDelta(t, 0.02, max[0], 1e-9, name)
+	}
+}
+
+func TestDecodeTRS(t *testing.T) {
+	doc := `{
+		"asset": {"version": "2.0"},
+		"scene": 0,
+		"scenes": [{"nodes": [0]}],
+		"nodes": [{"mesh": 0, "translation": [1, 2, 3], "scale": [2, 2, 2]}],
+		"meshes": [{"primitives": [{"attributes": {"POSITION": 0}}]}],
+		"accessors": [{"bufferView": 0, "componentType": 5126, "count": 3, "type": "VEC3"}],
+		"bufferViews": [{"buffer": 0, "byteLength": 36}],
+		"buffers": [{"byteLength": 36, "uri": "data:application/octet-stream;base64,AAAAAAAAAAAAAAAAAACAPwAAAAAAAAAAAAAAAAAAgD8AAAAA"}]
+	}`
+
+	s, err := DecodeGLTF(strings.NewReader(doc))
+	require.NoError(t, err)
+	require.Len(t, s.Bodies, 1)
+	assert.Equal(t, 1, s.TriangleCount())
+
+	var corners []scene.Vec3
+	s.Bodies[0].ForEachTriangle(func(a, b, c scene.Vec3) {
+		corners = append(corners, a, b, c)
+	})
+	assert.Equal(t, []scene.Vec3{{1, 2, 3}, {3, 2, 3}, {1, 4, 3}}, corners)
+}
+
+func TestDecodeRejectsExternalBuffers(t *testing.T) {
+	doc := `{"asset": {"version": "2.0"}, "buffers": [{"byteLength": 4, "uri": "model.bin"}]}`
+
+	_, err := DecodeGLTF(strings.NewReader(doc))
+	assert.ErrorIs(t, err, ErrMalformed)
+}
+
+func TestDecodeRejectsInvalidAccessorCounts(t *testing.T) {
+	doc := func(accessor string) string {
+		return `{
+			"asset": {"version": "2.0"},
+			"nodes": [{"mesh": 0}],
+			"meshes": [{"primitives": [{"attributes": {"POSITION": 0}}]}],
+			"accessors": [` + accessor + `],
+			"bufferViews": [{"buffer": 0, "byteLength": 36}],
+			"buffers": [{"byteLength": 36, "uri": "data:application/octet-stream;base64,AAAAAAAAAAAAAAAAAACAPwAAAAAAAAAAAAAAAAAAgD8AAAAA"}]
+		}`
+	}
+
+	accessors := map[string]string{
+		"negative":           `{"bufferView": 0, "componentType": 5126, "count": -1, "type": "VEC3"}`,
+		"negative unbacked":  `{"componentType": 5126, "count": -1, "type": "VEC3"}`,
+		"oversized":          `{"bufferView": 0, "componentType": 5126, "count": 4611686018427387904, "type": "VEC3"}`,
+		"oversized unbacked": `{"componentType": 5126, "count": 4611686018427387904, "type": "VEC3"}`,
+	}
+
+	for name, accessor := range accessors {
+		_, err := DecodeGLTF(strings.NewReader(doc(accessor)))
+		assert.ErrorIs(t, err, ErrMalformed, name)
+	}
+}
+
+func TestDecodeRejectsIndicesOutOfRange(t *testing.T) {
+	doc := func(scene, node, mesh, accessor, view int) string {
+		return fmt.Sprintf(`{
+			"asset": {"version": "2.0"},
+			"scene": %d,
+			"scenes": [{"nodes": [%d]}],
+			"nodes": [{"mesh": %d}],
+			"meshes": [{"primitives": [{"attributes": {"POSITION": %d}}]}],
+			"accessors": [{"bufferView": %d, "componentType": 5126, "count": 3, "type": "VEC3"}],
+			"bufferViews": [{"buffer": 0, "byteLength": 36}],
+			"buffers": [{"byteLength": 36, "uri": "data:application/octet-stream;base64,AAAAAAAAAAAAAAAAAACAPwAAAAAAAAAAAAAAAAAAgD8AAAAA"}]
+		}`, scene, node, mesh, accessor, view)
+	}
+
+	_, err := DecodeGLTF(strings.NewReader(doc(0, 0, 0, 0, 0)))
+	require.NoError(t, err)
+
+	documents := map[string]string{
+		"negative scene":    doc(-1, 0, 0, 0, 0),
+		"missing scene":     doc(1, 0, 0, 0, 0),
+		"negative node":     doc(0, -1, 0, 0, 0),
+		"missing node":      doc(0, 1, 0, 0, 0),
+		"negative mesh":     doc(0, 0, -1, 0, 0),
+		"missing mesh":      doc(0, 0, 1, 0, 0),
+		"negative accessor": doc(0, 0, 0, -1, 0),
+		"missing accessor":  doc(0, 0, 0, 1, 0),
+		"negative view":     doc(0, 0, 0, 0, -1),
+		"missing view":      doc(0, 0, 0, 0, 1),
+	}
+
+	for name, document := range documents {
+		_, err := DecodeGLTF(strings.NewReader(document))
+		assert.ErrorIs(t, err, ErrMalformed, name)
+	}
+}
+
+func TestDecodeRejectsSharedNodes(t *testing.T) {
+	// every node lists the next one twice, so walking the paths would take 2^40 visits
+	var nodes []string
+	for i := 0; i < 40; i++ {
+		nodes = append(nodes, fmt.Sprintf(`{"children": [%d, %d]}`, i+1, i+1))
+	}
+	nodes = append(nodes, `{"mesh": 0}`)
+
+	doc := `{
+		"asset": {"version": "2.0"},
+		"scene": 0,
+		"scenes": [{"nodes": [0]}],
+		"nodes": [` + strings.Join(nodes, ",") + `],
+		"meshes": [{"primitives": [{"attributes": {"POSITION": 0}}]}],
+		"accessors": [{"bufferView": 0, "componentType": 5126, "count": 3, "type": "VEC3"}],
+		"bufferViews": [{"buffer": 0, "byteLength": 36}],
+		"buffers": [{"byteLength": 36, "uri": "data:application/octet-stream;base64,AAAAAAAAAAAAAAAAAACAPwAAAAAAAAAAAAAAAAAAgD8AAAAA"}]
+	}`
+
+	_, err := DecodeGLTF(strings.NewReader(doc))
+	assert.ErrorIs(t, err, ErrMalformed)
+}
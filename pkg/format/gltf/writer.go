@@ -0,0 +1,245 @@
+package gltf
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+
+	"github.com/wildan3105/converto/pkg/scene"
+)
+
+const (
+	glbMagic     = 0x46546C67 // "glTF"
+	glbVersion   = 2
+	glbChunkJSON = 0x4E4F534A // "JSON"
+	glbChunkBIN  = 0x004E4942 // "BIN\0"
+)
+
+// EncodeGLTF writes the scene as a .gltf JSON document with its buffer embedded as a data URI
+func EncodeGLTF(w io.Writer, s *scene.Scene) error {
+	doc, bin := Build(s)
+	if len(bin) > 0 {
+		doc.Buffers[0].URI = "data:application/octet-stream;base64," + base64.StdEncoding.EncodeToString(bin)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(doc)
+}
+
+// EncodeGLB writes the scene as a binary .glb container
+func EncodeGLB(w io.Writer, s *scene.Scene) error {
+	doc, bin := Build(s)
+
+	jsonChunk, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	jsonChunk = pad(jsonChunk, ' ')
+	bin = pad(bin, 0)
+
+	length := 12 + 8 + len(jsonChunk)
+	if len(bin) > 0 {
+		length += 8 + len(bin)
+	}
+
+	header := []uint32{glbMagic, glbVersion, uint32(length), uint32(len(jsonChunk)), glbChunkJSON}
+	if err := binary.Write(w, binary.LittleEndian, header); err != nil {
+		return err
+	}
+	if _, err := w.Write(jsonChunk); err != nil {
+		return err
+	}
+
+	if len(bin) == 0 {
+		return nil
+	}
+
+	if err := binary.Write(w, binary.LittleEndian, []uint32{uint32(len(bin)), glbChunkBIN}); err != nil {
+		return err
+	}
+	_, err = w.Write(bin)
+	return err
+}
+
+// Build converts the scene into a glTF document and its binary buffer.
+//
+// The node hierarchy is a root node, scaled from the scene units to meters,
+// with one child node per body carrying the body transform. Each body becomes
+// a glTF mesh with one primitive per scene mesh.
+func Build(s *scene.Scene) (*Document, []byte) {
+	b := &builder{
+		doc: &Document{
+			Asset: Asset{Version: "2.0", Generator: "converto"},
+		},
+	}
+
+	if len(s.Metadata) > 0 {
+		b.doc.Asset.Extras = s.Metadata
+	}
+
+	for _, material := range s.Materials {
+		m := Material{
+			Name: material.Name,
+			PBRMetallicRoughness: PBRMetallicRoughness{
+				BaseColorFactor: material.BaseColor,
+				MetallicFactor:  material.Metallic,
+				RoughnessFactor: material.Roughness,
+			},
+			DoubleSided: true,
+		}
+		if material.BaseColor[3] < 1 {
+			m.AlphaMode = "BLEND"
+		}
+		b.doc.Materials = append(b.doc.Materials, m)
+	}
+
+	root := Node{Name: "root"}
+	if factor := s.Units.Millimeters() / 1000; factor > 0 && factor != 1 {
+		root.Scale = &[3]float64{factor, factor, factor}
+	}
+	b.doc.Nodes = append(b.doc.Nodes, root)
+
+	for i := range s.Bodies {
+		body := &s.Bodies[i]
+		node := Node{Name: body.Name}
+		if !body.Transform.IsIdentity() && body.Transform != (scene.Mat4{}) {
+			matrix := [16]float64(body.Transform)
+			node.Matrix = &matrix
+		}
+
+		if len(body.Meshes) > 0 {
+			mesh := Mesh{Name: body.Name}
+			for m := range body.Meshes {
+				mesh.Primitives = append(mesh.Primitives, b.primitive(&body.Meshes[m]))
+			}
+			meshIndex := len(b.doc.Meshes)
+			b.doc.Meshes = append(b.doc.Meshes, mesh)
+			node.Mesh = &meshIndex
+		}
+
+		b.doc.Nodes[0].Children = append(b.doc.Nodes[0].Children, len(b.doc.Nodes))
+		b.doc.Nodes = append(b.doc.Nodes, node)
+	}
+
+	b.doc.Scenes = []Scene{{Nodes: []int{0}}}
+
+	bin := b.buf.Bytes()
+	if len(bin) > 0 {
+		b.doc.Buffers = []Buffer{{ByteLength: len(bin)}}
+	}
+
+	return b.doc, bin
+}
+
+// builder accumulates accessors and buffer views over a single binary buffer
+type builder struct {
+	doc *Document
+	buf bytes.Buffer
+}
+
+// primitive adds the vertex attributes and indices of a mesh to the buffer
+func (b *builder) primitive(mesh *scene.Mesh) Primitive {
+	p := Primitive{
+		Attributes: make(map[string]int),
+		Mode:       primitiveModeTriangle,
+	}
+
+	if len(mesh.Positions) > 0 {
+		lo, hi := mesh.Positions[0], mesh.Positions[0]
+		for _, v := range mesh.Positions {
+			lo, hi = lo.Min(v), hi.Max(v)
+		}
+		accessor := b.vec3Accessor(mesh.Positions)
+		b.doc.Accessors[accessor].Min = float32s(lo[:])
+		b.doc.Accessors[accessor].Max = float32s(hi[:])
+		p.Attributes["POSITION"] = accessor
+	}
+
+	if len(mesh.Normals) > 0 {
+		normals := make([]scene.Vec3, len(mesh.Normals))
+		for i, n := range mesh.Normals {
+			normals[i] = n.Normalize()
+		}
+		p.Attributes["NORMAL"] = b.vec3Accessor(normals)
+	}
+
+	if len(mesh.UVs) > 0 {
+		values := make([]float32, 0, 2*len(mesh.UVs))
+		for _, uv := range mesh.UVs {
+			values = append(values, float32(uv[0]), float32(uv[1]))
+		}
+		p.Attributes["TEXCOORD_0"] = b.accessor(values, componentFloat, len(mesh.UVs), "VEC2", targetArrayBuffer)
+	}
+
+	if len(mesh.Colors) > 0 {
+		values := make([]float32, 0, 4*len(mesh.Colors))
+		for _, c := range mesh.Colors {
+			values = append(values, float32(c[0]), float32(c[1]), float32(c[2]), float32(c[3]))
+		}
+		p.Attributes["COLOR_0"] = b.accessor(values, componentFloat, len(mesh.Colors), "VEC4", targetArrayBuffer)
+	}
+
+	if len(mesh.Indices) > 0 {
+		indices := b.accessor(mesh.Indices, componentUnsignedInt, len(mesh.Indices), "SCALAR", targetElementArray)
+		p.Indices = &indices
+	}
+
+	if mesh.Material != scene.NoMaterial {
+		material := mesh.Material
+		p.Material = &material
+	}
+
+	return p
+}
+
+func (b *builder) vec3Accessor(vectors []scene.Vec3) int {
+	values := make([]float32, 0, 3*len(vectors))
+	for _, v := range vectors {
+		values = append(values, float32(v[0]), float32(v[1]), float32(v[2]))
+	}
+	return b.accessor(values, componentFloat, len(vectors), "VEC3", targetArrayBuffer)
+}
+
+// accessor appends data to the buffer in its own 4-byte aligned view and returns the accessor index
+func (b *builder) accessor(data any, componentType, count int, kind string, target int) int {
+	for b.buf.Len()%4 != 0 {
+		b.buf.WriteByte(0)
+	}
+
+	offset := b.buf.Len()
+	_ = binary.Write(&b.buf, binary.LittleEndian, data)
+
+	b.doc.BufferViews = append(b.doc.BufferViews, BufferView{
+		ByteOffset: offset,
+		ByteLength: b.buf.Len() - offset,
+		Target:     target,
+	})
+	b.doc.Accessors = append(b.doc.Accessors, Accessor{
+		BufferView:    len(b.doc.BufferViews) - 1,
+		ComponentType: componentType,
+		Count:         count,
+		Type:          kind,
+	})
+
+	return len(b.doc.Accessors) - 1
+}
+
+// float32s rounds the values to float32 precision so accessor bounds match the stored data
+func float32s(values []float64) []float64 {
+	out := make([]float64, len(values))
+	for i, v := range values {
+		out[i] = float64(float32(v))
+	}
+	return out
+}
+
+// pad extends data to a multiple of 4 bytes, as required for GLB chunks
+func pad(data []byte, with byte) []byte {
+	for len(data)%4 != 0 {
+		data = append(data, with)
+	}
+	return data
+}
@@ -0,0 +1,85 @@
+package gltf
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wildan3105/converto/pkg/scene"
+	"github.com/wildan3105/converto/pkg/scene/scenetest"
+)
+
+func TestBuild(t *testing.T) {
+	s := scenetest.Cube(10)
+	s.Bodies[0].Transform[12] = 5
+
+	doc, bin := Build(s)
+
+	require.Len(t, doc.Nodes, 2)
+	assert.Equal(t, []int{1}, doc.Nodes[0].Children)
+	assert.Equal(t, &[3]float64{0.001, 0.001, 0.001}, doc.Nodes[0].Scale)
+	require.NotNil(t, doc.Nodes[1].Matrix)
+	assert.Equal(t, 5.0, doc.Nodes[1].Matrix[12])
+
+	require.Len(t, doc.Meshes, 1)
+	primitive := doc.Meshes[0].Primitives[0]
+	position := doc.Accessors[primitive.Attributes["POSITION"]]
+	assert.Equal(t, 8, position.Count)
+	assert.Equal(t, []float64{0, 0, 0}, position.Min)
+	assert.Equal(t, []float64{10, 10, 10}, position.Max)
+	assert.Equal(t, 36, doc.Accessors[*primitive.Indices].Count)
+
+	require.Len(t, doc.Materials, 1)
+	assert.Equal(t, 0.75, doc.Materials[0].PBRMetallicRoughness.RoughnessFactor)
+
+	assert.Equal(t, 8*12+36*4, len(bin))
+	for _, view := range doc.BufferViews {
+		assert.Zero(t, view.ByteOffset%4)
+	}
+}
+
+func TestEncodeGLTFEmbedsBuffer(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, EncodeGLTF(&buf, scenetest.Cube(1)))
+
+	var doc Document
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &doc))
+	require.Len(t, doc.Buffers, 1)
+
+	uri := doc.Buffers[0].URI
+	require.True(t, strings.HasPrefix(uri, "data:application/octet-stream;base64,"))
+	data, err := base64.StdEncoding.DecodeString(strings.SplitN(uri, ",", 2)[1])
+	require.NoError(t, err)
+	assert.Equal(t, doc.Buffers[0].ByteLength, len(data))
+}
+
+func TestEncodeGLB(t *testing.T) {
+	s := scenetest.Cube(1)
+	s.Units = scene.UnitsMeter
+
+	var buf bytes.Buffer
+	require.NoError(t, EncodeGLB(&buf, s))
+	data := buf.Bytes()
+
+	assert.Equal(t, "glTF", string(data[:4]))
+	assert.Equal(t, uint32(2), binary.LittleEndian.Uint32(data[4:]))
+	assert.Equal(t, uint32(len(data)), binary.LittleEndian.Uint32(data[8:]))
+
+	jsonLength := binary.LittleEndian.Uint32(data[12:])
+	assert.Zero(t, jsonLength%4)
+	assert.Equal(t, "JSON", string(data[16:20]))
+
+	var doc Document
+	require.NoError(t, json.Unmarshal(data[20:20+jsonLength], &doc))
+	assert.Nil(t, doc.Nodes[0].Scale)
+	assert.Empty(t, doc.Buffers[0].URI)
+
+	binHeader := data[20+jsonLength:]
+	assert.Equal(t, "BIN\x00", string(binHeader[4:8]))
+	assert.GreaterOrEqual(t, int(binary.LittleEndian.Uint32(binHeader)), doc.Buffers[0].ByteLength)
+}
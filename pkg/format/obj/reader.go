@@ -0,0 +1,296 @@
+package obj
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/wildan3105/converto/pkg/scene"
+)
+
+// ErrMalformed is returned when an OBJ file cannot be parsed
+var ErrMalformed = errors.New("obj: malformed file")
+
+// corner is one face vertex as its (position, uv, normal) index triple; -1 marks a missing attribute
+type corner [3]int
+
+// Decode reads an OBJ file. Groups and objects become bodies, material switches
+// split a body into meshes, and polygons are triangulated as fans. Material
+// libraries are not followed: materials are created by name with a neutral colour.
+// OBJ carries no units, so the scene is assumed to be in millimeters.
+func Decode(r io.Reader) (*scene.Scene, error) {
+	d := &decoder{
+		scene:     scene.New(),
+		materials: make(map[string]int),
+		material:  scene.NoMaterial,
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := scanner.Text()
+		if i := strings.IndexByte(text, '#'); i >= 0 {
+			text = text[:i]
+		}
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+
+		if err := d.statement(fields); err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrMalformed, line, err)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	d.flushMesh()
+	d.flushBody()
+
+	if len(d.scene.Bodies) == 0 {
+		return nil, fmt.Errorf("%w: no faces found", ErrMalformed)
+	}
+
+	return d.scene, nil
+}
+
+type decoder struct {
+	scene     *scene.Scene
+	materials map[string]int
+
+	positions []scene.Vec3
+	colors    []scene.Color
+	colored   []bool
+	uvs       []scene.Vec2
+	normals   []scene.Vec3
+
+	bodyName string
+	body     *scene.Body
+	material int
+	mesh     *scene.Mesh
+	index    map[corner]uint32
+
+	// which optional attributes the corners of the current mesh referenced
+	meshHasUVs     bool
+	meshHasNormals bool
+	meshHasColors  bool
+}
+
+func (d *decoder) statement(fields []string) error {
+	args := fields[1:]
+
+	switch fields[0] {
+	case "v":
+		values, err := parseFloats(args, 3, 6)
+		if err != nil {
+			return err
+		}
+		d.positions = append(d.positions, scene.Vec3{values[0], values[1], values[2]})
+		if len(values) == 6 {
+			d.colors = append(d.colors, scene.Color{values[3], values[4], values[5], 1})
+		} else {
+			d.colors = append(d.colors, scene.Color{1, 1, 1, 1})
+		}
+		d.colored = append(d.colored, len(values) == 6)
+	case "vt":
+		values, err := parseFloats(args, 1, 3)
+		if err != nil {
+			return err
+		}
+		values = append(values, 0)
+		d.uvs = append(d.uvs, scene.Vec2{values[0], values[1]})
+	case "vn":
+		values, err := parseFloats(args, 3, 3)
+		if err != nil {
+			return err
+		}
+		d.normals = append(d.normals, scene.Vec3{values[0], values[1], values[2]})
+	case "f":
+		return d.face(args)
+	case "o", "g":
+		d.flushMesh()
+		d.flushBody()
+		d.bodyName = strings.Join(args, " ")
+	case "usemtl":
+		d.flushMesh()
+		d.material = d.materialIndex(strings.Join(args, " "))
+	case "mtllib", "s", "l", "p", "vp":
+		// not represented in the scene model
+	default:
+		return fmt.Errorf("unknown statement %q", fields[0])
+	}
+
+	return nil
+}
+
+// face triangulates a polygon as a fan around its first corner
+func (d *decoder) face(args []string) error {
+	if len(args) < 3 {
+		return errors.New("face with fewer than 3 vertices")
+	}
+
+	corners := make([]corner, len(args))
+	for i, arg := range args {
+		c, err := d.parseCorner(arg)
+		if err != nil {
+			return err
+		}
+		corners[i] = c
+	}
+
+	mesh := d.currentMesh()
+	for i := 1; i+1 < len(corners); i++ {
+		for _, c := range []corner{corners[0], corners[i], corners[i+1]} {
+			mesh.Indices = append(mesh.Indices, d.vertex(c))
+		}
+	}
+
+	return nil
+}
+
+// parseCorner resolves a v, v/vt, v//vn or v/vt/vn reference, including negative (relative) indices
+func (d *decoder) parseCorner(arg string) (corner, error) {
+	c := corner{-1, -1, -1}
+	parts := strings.Split(arg, "/")
+	if len(parts) > 3 {
+		return c, fmt.Errorf("invalid face vertex %q", arg)
+	}
+
+	counts := []int{len(d.positions), len(d.uvs), len(d.normals)}
+	for i, part := range parts {
+		if part == "" {
+			if i == 0 {
+				return c, fmt.Errorf("invalid face vertex %q", arg)
+			}
+			continue
+		}
+
+		index, err := strconv.Atoi(part)
+		if err != nil {
+			return c, fmt.Errorf("invalid face vertex %q", arg)
+		}
+		if index < 0 {
+			index += counts[i] + 1
+		}
+		if index < 1 || index > counts[i] {
+			return c, fmt.Errorf("face vertex %q out of range", arg)
+		}
+		c[i] = index - 1
+	}
+
+	return c, nil
+}
+
+// vertex returns the mesh index for a corner, adding a new vertex the first time it is seen
+func (d *decoder) vertex(c corner) uint32 {
+	if i, ok := d.index[c]; ok {
+		return i
+	}
+
+	mesh := d.mesh
+	i := uint32(len(mesh.Positions))
+	d.index[c] = i
+
+	mesh.Positions = append(mesh.Positions, d.positions[c[0]])
+	mesh.Colors = append(mesh.Colors, d.colors[c[0]])
+	if d.colored[c[0]] {
+		d.meshHasColors = true
+	}
+	if c[1] >= 0 {
+		mesh.UVs = append(mesh.UVs, d.uvs[c[1]])
+		d.meshHasUVs = true
+	} else {
+		mesh.UVs = append(mesh.UVs, scene.Vec2{})
+	}
+	if c[2] >= 0 {
+		mesh.Normals = append(mesh.Normals, d.normals[c[2]])
+		d.meshHasNormals = true
+	} else {
+		mesh.Normals = append(mesh.Normals, scene.Vec3{})
+	}
+
+	return i
+}
+
+func (d *decoder) currentMesh() *scene.Mesh {
+	if d.body == nil {
+		name := d.bodyName
+		if name == "" {
+			name = fmt.Sprintf("body_%d", len(d.scene.Bodies)+1)
+		}
+		d.body = &scene.Body{Name: name, Transform: scene.Identity()}
+	}
+	if d.mesh == nil {
+		d.mesh = &scene.Mesh{Name: d.body.Name, Material: d.material}
+		d.index = make(map[corner]uint32)
+	}
+	return d.mesh
+}
+
+func (d *decoder) materialIndex(name string) int {
+	if i, ok := d.materials[name]; ok {
+		return i
+	}
+	i := len(d.scene.Materials)
+	d.materials[name] = i
+	d.scene.Materials = append(d.scene.Materials, scene.Material{
+		Name:      name,
+		BaseColor: scene.Color{0.8, 0.8, 0.8, 1},
+		Roughness: 1,
+	})
+	return i
+}
+
+// flushMesh adds the current mesh to the current body, dropping attributes no face corner provided
+func (d *decoder) flushMesh() {
+	if d.mesh == nil {
+		return
+	}
+
+	mesh := d.mesh
+	if !d.meshHasUVs {
+		mesh.UVs = nil
+	}
+	if !d.meshHasNormals {
+		mesh.Normals = nil
+	}
+	if !d.meshHasColors {
+		mesh.Colors = nil
+	}
+
+	d.body.Meshes = append(d.body.Meshes, *mesh)
+	d.mesh = nil
+	d.meshHasUVs, d.meshHasNormals, d.meshHasColors = false, false, false
+}
+
+func (d *decoder) flushBody() {
+	if d.body == nil {
+		return
+	}
+	d.scene.Bodies = append(d.scene.Bodies, *d.body)
+	d.body = nil
+}
+
+func parseFloats(args []string, minCount, maxCount int) ([]float64, error) {
+	if len(args) < minCount || len(args) > maxCount {
+		return nil, fmt.Errorf("expected %d to %d values, got %d", minCount, maxCount, len(args))
+	}
+
+	values := make([]float64, len(args))
+	for i, arg := range args {
+		value, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
@@ -0,0 +1,74 @@
+package obj
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wildan3105/converto/pkg/scene"
+	"github.com/wildan3105/converto/pkg/scene/scenetest"
+)
+
+func TestDecodeRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Encode(&buf, scenetest.Cube(2), EncodeOptions{MaterialLibrary: "cube.mtl"}))
+
+	s, err := Decode(&buf)
+	require.NoError(t, err)
+	require.NoError(t, s.Validate())
+
+	require.Len(t, s.Bodies, 1)
+	assert.Equal(t, 12, s.TriangleCount())
+	assert.Equal(t, 8, s.VertexCount())
+	require.Len(t, s.Materials, 1)
+	assert.Equal(t, "grey", s.Materials[0].Name)
+	assert.Equal(t, 0, s.Bodies[0].Meshes[0].Material)
+}
+
+func TestDecodePolygonsAndNegativeIndices(t *testing.T) {
+	input := `
+v 0 0 0
+v 1 0 0
+v 1 1 0
+v 0 1 0
+o quad
+f -4 -3 -2 -1
+`
+	s, err := Decode(strings.NewReader(input))
+	require.NoError(t, err)
+
+	require.Len(t, s.Bodies, 1)
+	assert.Equal(t, "quad", s.Bodies[0].Name)
+	assert.Equal(t, []uint32{0, 1, 2, 0, 2, 3}, s.Bodies[0].Meshes[0].Indices)
+}
+
+func TestDecodeMalformed(t *testing.T) {
+	_, err := Decode(strings.NewReader("v 0 0 0\nf 1 2 3\n"))
+	assert.ErrorIs(t, err, ErrMalformed)
+}
+
+func TestDecodeWithoutMaterials(t *testing.T) {
+	input := `
+v 0 0 0 1 0 0
+v 1 0 0 1 0 0
+v 0 1 0 1 0 0
+v 0 0 1
+v 1 0 1
+v 0 1 1
+o colored
+f 1 2 3
+o plain
+f 4 5 6
+`
+	s, err := Decode(strings.NewReader(input))
+	require.NoError(t, err)
+	require.NoError(t, s.Validate())
+
+	require.Len(t, s.Bodies, 2)
+	assert.Equal(t, scene.NoMaterial, s.Bodies[0].Meshes[0].Material)
+	assert.Len(t, s.Bodies[0].Meshes[0].Colors, 3)
+	// the colours of the first mesh do not leak into the next one
+	assert.Nil(t, s.Bodies[1].Meshes[0].Colors)
+}
@@ -0,0 +1,169 @@
+// Package obj reads Wavefront OBJ geometry and writes it together with its companion MTL material library.
+package obj
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/wildan3105/converto/pkg/scene"
+)
+
+// EncodeOptions controls the OBJ output
+type EncodeOptions struct {
+	// MaterialLibrary is the file name referenced by mtllib; materials are omitted when empty
+	MaterialLibrary string
+}
+
+// Encode writes the scene as OBJ. Every body becomes a named group whose
+// vertices are shared between its faces; body transforms are applied.
+func Encode(w io.Writer, s *scene.Scene, opts EncodeOptions) error {
+	bw := bufio.NewWriter(w)
+	materialNames := MaterialNames(s)
+
+	fmt.Fprintf(bw, "# converto OBJ export\n")
+	if opts.MaterialLibrary != "" {
+		fmt.Fprintf(bw, "mtllib %s\n", opts.MaterialLibrary)
+	}
+
+	// OBJ indices are 1-based and global to the file
+	vertexBase, uvBase, normalBase := 1, 1, 1
+
+	for b := range s.Bodies {
+		body := &s.Bodies[b]
+		fmt.Fprintf(bw, "g %s\n", groupName(body.Name, b))
+
+		for m := range body.Meshes {
+			mesh := &body.Meshes[m]
+			writeVertices(bw, body.Transform, mesh)
+
+			if opts.MaterialLibrary != "" && mesh.Material != scene.NoMaterial {
+				fmt.Fprintf(bw, "usemtl %s\n", materialNames[mesh.Material])
+			}
+
+			hasUVs, hasNormals := len(mesh.UVs) > 0, len(mesh.Normals) > 0
+			for i := 0; i < len(mesh.Indices); i += 3 {
+				bw.WriteString("f")
+				for _, index := range mesh.Indices[i : i+3] {
+					bw.WriteString(" ")
+					bw.WriteString(faceVertex(int(index), vertexBase, uvBase, normalBase, hasUVs, hasNormals))
+				}
+				bw.WriteString("\n")
+			}
+
+			vertexBase += len(mesh.Positions)
+			if hasUVs {
+				uvBase += len(mesh.UVs)
+			}
+			if hasNormals {
+				normalBase += len(mesh.Normals)
+			}
+		}
+	}
+
+	return bw.Flush()
+}
+
+// EncodeMaterials writes the scene materials as an MTL library, including the PBR extension keys
+func EncodeMaterials(w io.Writer, s *scene.Scene) error {
+	bw := bufio.NewWriter(w)
+	names := MaterialNames(s)
+
+	fmt.Fprintf(bw, "# converto MTL export\n")
+	for i, material := range s.Materials {
+		c := material.BaseColor
+		fmt.Fprintf(bw, "\nnewmtl %s\n", names[i])
+		fmt.Fprintf(bw, "Kd %s %s %s\n", formatFloat(c[0]), formatFloat(c[1]), formatFloat(c[2]))
+		fmt.Fprintf(bw, "Ka 0 0 0\n")
+		fmt.Fprintf(bw, "Ks %s %s %s\n", formatFloat(0.04), formatFloat(0.04), formatFloat(0.04))
+		fmt.Fprintf(bw, "Ns %s\n", formatFloat((1-material.Roughness)*(1-material.Roughness)*1000))
+		fmt.Fprintf(bw, "d %s\n", formatFloat(c[3]))
+		fmt.Fprintf(bw, "illum 2\n")
+		fmt.Fprintf(bw, "Pr %s\n", formatFloat(material.Roughness))
+		fmt.Fprintf(bw, "Pm %s\n", formatFloat(material.Metallic))
+	}
+
+	return bw.Flush()
+}
+
+// MaterialNames returns unique, whitespace-free names for the scene materials, in order
+func MaterialNames(s *scene.Scene) []string {
+	names := make([]string, len(s.Materials))
+	seen := make(map[string]bool)
+
+	for i, material := range s.Materials {
+		base := strings.Join(strings.Fields(material.Name), "_")
+		if base == "" {
+			base = fmt.Sprintf("material_%d", i+1)
+		}
+
+		name := base
+		for n := 2; seen[name]; n++ {
+			name = fmt.Sprintf("%s_%d", base, n)
+		}
+		seen[name] = true
+		names[i] = name
+	}
+
+	return names
+}
+
+// writeVertices writes the positions (with optional vertex colors), UVs and normals of a mesh
+func writeVertices(w *bufio.Writer, transform scene.Mat4, mesh *scene.Mesh) {
+	identity := transform.IsIdentity() || transform == scene.Mat4{}
+
+	for i, p := range mesh.Positions {
+		if !identity {
+			p = transform.TransformPoint(p)
+		}
+		fmt.Fprintf(w, "v %s %s %s", formatFloat(p[0]), formatFloat(p[1]), formatFloat(p[2]))
+		if len(mesh.Colors) > 0 {
+			c := mesh.Colors[i]
+			fmt.Fprintf(w, " %s %s %s", formatFloat(c[0]), formatFloat(c[1]), formatFloat(c[2]))
+		}
+		w.WriteString("\n")
+	}
+
+	for _, uv := range mesh.UVs {
+		fmt.Fprintf(w, "vt %s %s\n", formatFloat(uv[0]), formatFloat(uv[1]))
+	}
+
+	for _, n := range mesh.Normals {
+		if !identity {
+			n = transform.TransformNormal(n)
+		}
+		fmt.Fprintf(w, "vn %s %s %s\n", formatFloat(n[0]), formatFloat(n[1]), formatFloat(n[2]))
+	}
+}
+
+// faceVertex formats one face corner as v, v/vt, v//vn or v/vt/vn
+func faceVertex(index, vertexBase, uvBase, normalBase int, hasUVs, hasNormals bool) string {
+	v := strconv.Itoa(vertexBase + index)
+
+	switch {
+	case hasUVs && hasNormals:
+		return v + "/" + strconv.Itoa(uvBase+index) + "/" + strconv.Itoa(normalBase+index)
+	case hasUVs:
+		return v + "/" + strconv.Itoa(uvBase+index)
+	case hasNormals:
+		return v + "//" + strconv.Itoa(normalBase+index)
+	default:
+		return v
+	}
+}
+
+// groupName returns a whitespace-free group name for the body, falling back to its position
+func groupName(name string, index int) string {
+	name = strings.Join(strings.Fields(name), "_")
+	if name == "" {
+		name = fmt.Sprintf("body_%d", index+1)
+	}
+	return name
+}
+
+// formatFloat formats with the shortest representation that round-trips through float32
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 32)
+}
@@ -0,0 +1,51 @@
+package obj
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wildan3105/converto/pkg/scene"
+	"github.com/wildan3105/converto/pkg/scene/scenetest"
+)
+
+func TestEncodeSharesVerticesAcrossBodies(t *testing.T) {
+	s := scenetest.Cube(1)
+	second := scenetest.CubeMesh(1)
+	second.Normals = make([]scene.Vec3, len(second.Positions))
+	second.UVs = make([]scene.Vec2, len(second.Positions))
+	s.Bodies = append(s.Bodies, scene.Body{Name: "Second body", Transform: scene.Identity(), Meshes: []scene.Mesh{second}})
+
+	var buf bytes.Buffer
+	require.NoError(t, Encode(&buf, s, EncodeOptions{MaterialLibrary: "model.mtl"}))
+	out := buf.String()
+
+	assert.Contains(t, out, "mtllib model.mtl\n")
+	assert.Contains(t, out, "g Body_1\n")
+	assert.Contains(t, out, "g Second_body\n")
+	assert.Contains(t, out, "usemtl grey\n")
+	assert.Equal(t, 16, strings.Count(out, "\nv "))
+	assert.Equal(t, 8, strings.Count(out, "\nvt "))
+	assert.Equal(t, 8, strings.Count(out, "\nvn "))
+	assert.Equal(t, 24, strings.Count(out, "\nf "))
+
+	// the first body uses plain indices, the second continues after its 8 vertices
+	assert.Contains(t, out, "f 1 3 2\n")
+	assert.Contains(t, out, "f 9/1/1 11/3/3 10/2/2\n")
+}
+
+func TestEncodeMaterials(t *testing.T) {
+	s := scenetest.Cube(1)
+	s.Materials = append(s.Materials, scene.Material{Name: "grey", BaseColor: scene.Color{1, 0, 0, 0.5}, Metallic: 1})
+
+	var buf bytes.Buffer
+	require.NoError(t, EncodeMaterials(&buf, s))
+	out := buf.String()
+
+	assert.Contains(t, out, "newmtl grey\nKd 0.5 0.5 0.5\n")
+	assert.Contains(t, out, "newmtl grey_2\nKd 1 0 0\n")
+	assert.Contains(t, out, "d 0.5\n")
+	assert.Contains(t, out, "Pm 1\n")
+}
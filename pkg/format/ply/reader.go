@@ -0,0 +1,405 @@
+package ply
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/wildan3105/converto/pkg/scene"
+)
+
+// ErrMalformed is returned when a PLY file cannot be parsed
+var ErrMalformed = errors.New("ply: malformed file")
+
+// maxElementCount bounds the element counts a header may declare
+const maxElementCount = 1 << 28
+
+// property is a scalar or list property of an element
+type property struct {
+	name      string
+	kind      string
+	list      bool
+	countKind string
+}
+
+// element is a header element declaration
+type element struct {
+	name       string
+	count      int
+	properties []property
+}
+
+// Decode reads an ASCII or binary (little or big endian) PLY file into a single body.
+// Vertex positions, normals, texture coordinates and colours are kept; polygons are
+// triangulated as fans. PLY carries no units, so the scene is assumed to be in millimeters.
+func Decode(r io.Reader) (*scene.Scene, error) {
+	br := bufio.NewReader(r)
+
+	format, elements, err := readHeader(br)
+	if err != nil {
+		return nil, err
+	}
+
+	var values valueReader
+	switch format {
+	case "ascii":
+		values = &asciiReader{r: br}
+	case "binary_little_endian":
+		values = &binaryReader{r: br, order: binary.LittleEndian}
+	case "binary_big_endian":
+		values = &binaryReader{r: br, order: binary.BigEndian}
+	default:
+		return nil, fmt.Errorf("%w: unknown format %q", ErrMalformed, format)
+	}
+
+	mesh := scene.Mesh{Name: "body_1", Material: scene.NoMaterial}
+	hasFaces := false
+
+	for _, el := range elements {
+		switch el.name {
+		case "vertex":
+			if err := readVertices(values, el, &mesh); err != nil {
+				return nil, err
+			}
+		case "face":
+			hasFaces = true
+			if err := readFaces(values, el, &mesh); err != nil {
+				return nil, err
+			}
+		default:
+			if err := skipElement(values, el); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if !hasFaces {
+		return nil, fmt.Errorf("%w: no face element (point clouds are not supported)", ErrMalformed)
+	}
+
+	if err := mesh.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+
+	s := scene.New()
+	s.Bodies = []scene.Body{{Name: mesh.Name, Transform: scene.Identity(), Meshes: []scene.Mesh{mesh}}}
+	return s, nil
+}
+
+func readHeader(r *bufio.Reader) (string, []element, error) {
+	var format string
+	var elements []element
+
+	for lineNumber := 1; ; lineNumber++ {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return "", nil, fmt.Errorf("%w: unterminated header", ErrMalformed)
+		}
+		fields := strings.Fields(line)
+
+		if lineNumber == 1 {
+			if len(fields) != 1 || fields[0] != "ply" {
+				return "", nil, fmt.Errorf("%w: missing ply magic", ErrMalformed)
+			}
+			continue
+		}
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "format":
+			if len(fields) != 3 {
+				return "", nil, fmt.Errorf("%w: header line %d: invalid format", ErrMalformed, lineNumber)
+			}
+			format = fields[1]
+		case "element":
+			if len(fields) != 3 {
+				return "", nil, fmt.Errorf("%w: header line %d: invalid element", ErrMalformed, lineNumber)
+			}
+			count, err := strconv.Atoi(fields[2])
+			if err != nil || count < 0 || count > maxElementCount {
+				return "", nil, fmt.Errorf("%w: header line %d: invalid element count", ErrMalformed, lineNumber)
+			}
+			elements = append(elements, element{name: fields[1], count: count})
+		case "property":
+			if len(elements) == 0 {
+				return "", nil, fmt.Errorf("%w: header line %d: property outside element", ErrMalformed, lineNumber)
+			}
+			p, err := parseProperty(fields[1:])
+			if err != nil {
+				return "", nil, fmt.Errorf("%w: header line %d: %v", ErrMalformed, lineNumber, err)
+			}
+			el := &elements[len(elements)-1]
+			el.properties = append(el.properties, p)
+		case "comment", "obj_info":
+		case "end_header":
+			if format == "" {
+				return "", nil, fmt.Errorf("%w: missing format line", ErrMalformed)
+			}
+			return format, elements, nil
+		default:
+			return "", nil, fmt.Errorf("%w: header line %d: unknown keyword %q", ErrMalformed, lineNumber, fields[0])
+		}
+	}
+}
+
+func parseProperty(fields []string) (property, error) {
+	if len(fields) == 4 && fields[0] == "list" {
+		if typeSize(fields[1]) == 0 || typeSize(fields[2]) == 0 {
+			return property{}, errors.New("unknown list property type")
+		}
+		return property{name: fields[3], kind: fields[2], list: true, countKind: fields[1]}, nil
+	}
+	if len(fields) == 2 && typeSize(fields[0]) != 0 {
+		return property{name: fields[1], kind: fields[0]}, nil
+	}
+	return property{}, errors.New("invalid property")
+}
+
+func readVertices(values valueReader, el element, mesh *scene.Mesh) error {
+	has := func(names ...string) bool {
+		for _, name := range names {
+			found := false
+			for _, p := range el.properties {
+				found = found || p.name == name
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	}
+
+	if !has("x", "y", "z") {
+		return fmt.Errorf("%w: vertex element without x, y, z", ErrMalformed)
+	}
+	hasNormals := has("nx", "ny", "nz")
+	hasColors := has("red", "green", "blue")
+	hasUVs := has("s", "t") || has("u", "v") || has("texture_u", "texture_v")
+
+	mesh.Positions = make([]scene.Vec3, 0, min(el.count, 1<<20))
+	for i := 0; i < el.count; i++ {
+		var position, normal scene.Vec3
+		var uv scene.Vec2
+		color := scene.Color{1, 1, 1, 1}
+
+		for _, p := range el.properties {
+			if p.list {
+				if _, err := readList(values, p); err != nil {
+					return err
+				}
+				continue
+			}
+
+			v, err := values.read(p.kind)
+			if err != nil {
+				return err
+			}
+
+			switch p.name {
+			case "x":
+				position[0] = v
+			case "y":
+				position[1] = v
+			case "z":
+				position[2] = v
+			case "nx":
+				normal[0] = v
+			case "ny":
+				normal[1] = v
+			case "nz":
+				normal[2] = v
+			case "s", "u", "texture_u":
+				uv[0] = v
+			case "t", "v", "texture_v":
+				uv[1] = v
+			case "red":
+				color[0] = normalizeColor(v, p.kind)
+			case "green":
+				color[1] = normalizeColor(v, p.kind)
+			case "blue":
+				color[2] = normalizeColor(v, p.kind)
+			case "alpha":
+				color[3] = normalizeColor(v, p.kind)
+			}
+		}
+
+		mesh.Positions = append(mesh.Positions, position)
+		if hasNormals {
+			mesh.Normals = append(mesh.Normals, normal)
+		}
+		if hasUVs {
+			mesh.UVs = append(mesh.UVs, uv)
+		}
+		if hasColors {
+			mesh.Colors = append(mesh.Colors, color)
+		}
+	}
+
+	return nil
+}
+
+func readFaces(values valueReader, el element, mesh *scene.Mesh) error {
+	for i := 0; i < el.count; i++ {
+		for _, p := range el.properties {
+			if !p.list {
+				if _, err := values.read(p.kind); err != nil {
+					return err
+				}
+				continue
+			}
+
+			indices, err := readList(values, p)
+			if err != nil {
+				return err
+			}
+			if p.name != "vertex_indices" && p.name != "vertex_index" {
+				continue
+			}
+			if len(indices) < 3 {
+				return fmt.Errorf("%w: face %d has fewer than 3 vertices", ErrMalformed, i)
+			}
+
+			for k := 1; k+1 < len(indices); k++ {
+				for _, index := range []float64{indices[0], indices[k], indices[k+1]} {
+					if index < 0 || index >= float64(len(mesh.Positions)) {
+						return fmt.Errorf("%w: face %d references missing vertex", ErrMalformed, i)
+					}
+					mesh.Indices = append(mesh.Indices, uint32(index))
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func skipElement(values valueReader, el element) error {
+	for i := 0; i < el.count; i++ {
+		for _, p := range el.properties {
+			var err error
+			if p.list {
+				_, err = readList(values, p)
+			} else {
+				_, err = values.read(p.kind)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func readList(values valueReader, p property) ([]float64, error) {
+	count, err := values.read(p.countKind)
+	if err != nil {
+		return nil, err
+	}
+	if count < 0 || count > 1<<16 {
+		return nil, fmt.Errorf("%w: invalid list length", ErrMalformed)
+	}
+
+	items := make([]float64, int(count))
+	for i := range items {
+		if items[i], err = values.read(p.kind); err != nil {
+			return nil, err
+		}
+	}
+	return items, nil
+}
+
+// normalizeColor maps integer colour channels to 0..1; float channels are already normalized
+func normalizeColor(v float64, kind string) float64 {
+	switch kind {
+	case "uchar", "uint8", "char", "int8":
+		return v / 255
+	case "ushort", "uint16", "short", "int16":
+		return v / 65535
+	default:
+		return v
+	}
+}
+
+// typeSize returns the byte size of a PLY scalar type, or 0 for unknown types
+func typeSize(kind string) int {
+	switch kind {
+	case "char", "uchar", "int8", "uint8":
+		return 1
+	case "short", "ushort", "int16", "uint16":
+		return 2
+	case "int", "uint", "float", "int32", "uint32", "float32":
+		return 4
+	case "double", "float64":
+		return 8
+	default:
+		return 0
+	}
+}
+
+// valueReader reads one scalar of a given PLY type
+type valueReader interface {
+	read(kind string) (float64, error)
+}
+
+type asciiReader struct {
+	r      *bufio.Reader
+	fields []string
+}
+
+func (a *asciiReader) read(kind string) (float64, error) {
+	for len(a.fields) == 0 {
+		line, err := a.r.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			return 0, fmt.Errorf("%w: unexpected end of data", ErrMalformed)
+		}
+		a.fields = strings.Fields(line)
+	}
+
+	field := a.fields[0]
+	a.fields = a.fields[1:]
+
+	v, err := strconv.ParseFloat(field, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid value %q", ErrMalformed, field)
+	}
+	return v, nil
+}
+
+type binaryReader struct {
+	r     *bufio.Reader
+	order binary.ByteOrder
+	buf   [8]byte
+}
+
+func (b *binaryReader) read(kind string) (float64, error) {
+	size := typeSize(kind)
+	buf := b.buf[:size]
+	if _, err := io.ReadFull(b.r, buf); err != nil {
+		return 0, fmt.Errorf("%w: unexpected end of data", ErrMalformed)
+	}
+
+	switch kind {
+	case "char", "int8":
+		return float64(int8(buf[0])), nil
+	case "uchar", "uint8":
+		return float64(buf[0]), nil
+	case "short", "int16":
+		return float64(int16(b.order.Uint16(buf))), nil
+	case "ushort", "uint16":
+		return float64(b.order.Uint16(buf)), nil
+	case "int", "int32":
+		return float64(int32(b.order.Uint32(buf))), nil
+	case "uint", "uint32":
+		return float64(b.order.Uint32(buf)), nil
+	case "float", "float32":
+		return float64(math.Float32frombits(b.order.Uint32(buf))), nil
+	default:
+		return math.Float64frombits(b.order.Uint64(buf)), nil
+	}
+}
@@ -0,0 +1,57 @@
+package ply
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wildan3105/converto/pkg/scene"
+	"github.com/wildan3105/converto/pkg/scene/scenetest"
+)
+
+func TestDecodeRoundTrip(t *testing.T) {
+	for _, ascii := range []bool{false, true} {
+		var buf bytes.Buffer
+		require.NoError(t, Encode(&buf, scenetest.Cube(2), EncodeOptions{ASCII: ascii}))
+
+		s, err := Decode(&buf)
+		require.NoError(t, err)
+		require.NoError(t, s.Validate())
+
+		assert.Equal(t, 12, s.TriangleCount(), "ascii=%v", ascii)
+		assert.Equal(t, 8, s.VertexCount(), "ascii=%v", ascii)
+
+		// material colours are written per vertex and read back as vertex colours
+		colors := s.Bodies[0].Meshes[0].Colors
+		require.Len(t, colors, 8)
+		assert.InDelta(t, 0.5, colors[0][0], 0.01)
+	}
+}
+
+func TestDecodeBigEndianPolygon(t *testing.T) {
+	var buf bytes.Buffer
+	buf.WriteString("ply\nformat binary_big_endian 1.0\nelement vertex 4\nproperty float x\nproperty float y\nproperty float z\n")
+	buf.WriteString("element face 1\nproperty list uchar int vertex_indices\nend_header\n")
+	for _, v := range [][3]float32{{0, 0, 0}, {1, 0, 0}, {1, 1, 0}, {0, 1, 0}} {
+		for _, c := range v {
+			binary.Write(&buf, binary.BigEndian, c)
+		}
+	}
+	buf.WriteByte(4)
+	for _, i := range []int32{0, 1, 2, 3} {
+		binary.Write(&buf, binary.BigEndian, i)
+	}
+
+	s, err := Decode(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, 2, s.TriangleCount())
+	assert.Equal(t, scene.Vec3{1, 1, 0}, s.Bodies[0].Meshes[0].Positions[2])
+}
+
+func TestDecodeMalformed(t *testing.T) {
+	_, err := Decode(strings.NewReader("ply\nformat ascii 1.0\nelement vertex 1\nproperty float x\nend_header\n"))
+	assert.ErrorIs(t, err, ErrMalformed)
+}
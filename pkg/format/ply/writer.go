@@ -0,0 +1,163 @@
+// Package ply reads and writes Stanford PLY polygon files with optional normals and vertex colours.
+package ply
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/wildan3105/converto/pkg/scene"
+)
+
+// EncodeOptions controls the PLY flavour written by Encode
+type EncodeOptions struct {
+	ASCII bool
+}
+
+// vertex is a scene-space vertex with its normal and 8-bit colour
+type vertex struct {
+	position scene.Vec3
+	normal   scene.Vec3
+	color    [4]uint8
+}
+
+// Encode writes every mesh of the scene into a single PLY vertex/face list, with body
+// transforms applied. Vertex colours come from the mesh colours, falling back to the
+// mesh material colour, and are written when any mesh has colours or materials.
+// Normals are only written when every mesh has them.
+func Encode(w io.Writer, s *scene.Scene, opts EncodeOptions) error {
+	hasNormals, hasColors := true, false
+	for _, body := range s.Bodies {
+		for _, mesh := range body.Meshes {
+			hasNormals = hasNormals && len(mesh.Normals) > 0
+			hasColors = hasColors || len(mesh.Colors) > 0 || mesh.Material != scene.NoMaterial
+		}
+	}
+	hasNormals = hasNormals && s.VertexCount() > 0
+
+	var vertices []vertex
+	var faces [][3]uint32
+
+	for _, body := range s.Bodies {
+		identity := body.Transform.IsIdentity() || body.Transform == scene.Mat4{}
+
+		for _, mesh := range body.Meshes {
+			offset := uint32(len(vertices))
+			fallback := scene.Color{1, 1, 1, 1}
+			if mesh.Material != scene.NoMaterial {
+				fallback = s.Materials[mesh.Material].BaseColor
+			}
+
+			for i, p := range mesh.Positions {
+				v := vertex{position: p, color: color8(fallback)}
+				if !identity {
+					v.position = body.Transform.TransformPoint(p)
+				}
+				if hasNormals {
+					v.normal = mesh.Normals[i]
+					if !identity {
+						v.normal = body.Transform.TransformNormal(v.normal)
+					}
+				}
+				if len(mesh.Colors) > 0 {
+					v.color = color8(mesh.Colors[i])
+				}
+				vertices = append(vertices, v)
+			}
+
+			for t := 0; t < len(mesh.Indices); t += 3 {
+				faces = append(faces, [3]uint32{offset + mesh.Indices[t], offset + mesh.Indices[t+1], offset + mesh.Indices[t+2]})
+			}
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+
+	encoding := "binary_little_endian"
+	if opts.ASCII {
+		encoding = "ascii"
+	}
+
+	fmt.Fprintf(bw, "ply\nformat %s 1.0\ncomment converto PLY export (units: %s)\n", encoding, s.Units)
+	fmt.Fprintf(bw, "element vertex %d\nproperty float x\nproperty float y\nproperty float z\n", len(vertices))
+	if hasNormals {
+		fmt.Fprintf(bw, "property float nx\nproperty float ny\nproperty float nz\n")
+	}
+	if hasColors {
+		fmt.Fprintf(bw, "property uchar red\nproperty uchar green\nproperty uchar blue\nproperty uchar alpha\n")
+	}
+	fmt.Fprintf(bw, "element face %d\nproperty list uchar uint vertex_indices\nend_header\n", len(faces))
+
+	if opts.ASCII {
+		writeASCII(bw, vertices, faces, hasNormals, hasColors)
+	} else if err := writeBinary(bw, vertices, faces, hasNormals, hasColors); err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
+
+func writeASCII(w *bufio.Writer, vertices []vertex, faces [][3]uint32, hasNormals, hasColors bool) {
+	for _, v := range vertices {
+		fmt.Fprintf(w, "%g %g %g", float32(v.position[0]), float32(v.position[1]), float32(v.position[2]))
+		if hasNormals {
+			fmt.Fprintf(w, " %g %g %g", float32(v.normal[0]), float32(v.normal[1]), float32(v.normal[2]))
+		}
+		if hasColors {
+			fmt.Fprintf(w, " %d %d %d %d", v.color[0], v.color[1], v.color[2], v.color[3])
+		}
+		w.WriteString("\n")
+	}
+
+	for _, f := range faces {
+		fmt.Fprintf(w, "3 %d %d %d\n", f[0], f[1], f[2])
+	}
+}
+
+func writeBinary(w *bufio.Writer, vertices []vertex, faces [][3]uint32, hasNormals, hasColors bool) error {
+	record := make([]byte, 0, 28)
+	putFloat := func(v float64) {
+		record = binary.LittleEndian.AppendUint32(record, math.Float32bits(float32(v)))
+	}
+
+	for _, v := range vertices {
+		record = record[:0]
+		putFloat(v.position[0])
+		putFloat(v.position[1])
+		putFloat(v.position[2])
+		if hasNormals {
+			putFloat(v.normal[0])
+			putFloat(v.normal[1])
+			putFloat(v.normal[2])
+		}
+		if hasColors {
+			record = append(record, v.color[:]...)
+		}
+		if _, err := w.Write(record); err != nil {
+			return err
+		}
+	}
+
+	for _, f := range faces {
+		record = append(record[:0], 3)
+		record = binary.LittleEndian.AppendUint32(record, f[0])
+		record = binary.LittleEndian.AppendUint32(record, f[1])
+		record = binary.LittleEndian.AppendUint32(record, f[2])
+		if _, err := w.Write(record); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// color8 converts a linear color to 8-bit channels
+func color8(c scene.Color) [4]uint8 {
+	var out [4]uint8
+	for i, v := range c {
+		out[i] = uint8(min(max(v, 0), 1)*255 + 0.5)
+	}
+	return out
+}
@@ -0,0 +1,49 @@
+package ply
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wildan3105/converto/pkg/scene"
+	"github.com/wildan3105/converto/pkg/scene/scenetest"
+)
+
+func TestEncodeASCIIWithVertexColors(t *testing.T) {
+	s := scenetest.Cube(1)
+	mesh := &s.Bodies[0].Meshes[0]
+	mesh.Colors = make([]scene.Color, len(mesh.Positions))
+	mesh.Colors[0] = scene.Color{1, 0, 0, 1}
+
+	var buf bytes.Buffer
+	require.NoError(t, Encode(&buf, s, EncodeOptions{ASCII: true}))
+	out := buf.String()
+
+	header, body, found := strings.Cut(out, "end_header\n")
+	require.True(t, found)
+	assert.True(t, strings.HasPrefix(header, "ply\nformat ascii 1.0\n"))
+	assert.Contains(t, header, "element vertex 8\n")
+	assert.Contains(t, header, "property uchar red\n")
+	assert.NotContains(t, header, "property float nx")
+	assert.Contains(t, header, "element face 12\n")
+
+	lines := strings.Split(strings.TrimSpace(body), "\n")
+	require.Len(t, lines, 20)
+	assert.Equal(t, "0 0 0 255 0 0 255", lines[0])
+	assert.Equal(t, "3 0 2 1", lines[8])
+}
+
+func TestEncodeBinarySize(t *testing.T) {
+	s := scenetest.Cube(1)
+
+	var buf bytes.Buffer
+	require.NoError(t, Encode(&buf, s, EncodeOptions{}))
+
+	header, body, found := strings.Cut(buf.String(), "end_header\n")
+	require.True(t, found)
+	assert.Contains(t, header, "format binary_little_endian 1.0\n")
+	// material colours are written per vertex: 12 bytes position + 4 bytes colour
+	assert.Len(t, body, 8*16+12*13)
+}
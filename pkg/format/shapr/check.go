@@ -0,0 +1,111 @@
+package shapr
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// MaxCheckProblems bounds the number of problems Check reports
+const MaxCheckProblems = 50
+
+// Check verifies the container structure without decoding it: the header, the
+// declared chunk sizes against the file size, every chunk checksum, the chunk
+// count and the END chunk. Unlike Reader it keeps going after a checksum
+// mismatch, so that every damaged chunk is reported. Payloads are streamed, not kept.
+// size is the total file size, or -1 when unknown.
+func Check(r io.Reader, size int64) []*FormatError {
+	var problems []*FormatError
+	report := func(err *FormatError) bool {
+		problems = append(problems, err)
+		return len(problems) < MaxCheckProblems
+	}
+
+	buf := make([]byte, HeaderSize)
+	n, err := io.ReadFull(r, buf)
+	if err != nil {
+		if !bytes.HasPrefix([]byte(Magic), buf[:min(n, len(Magic))]) {
+			return append(problems, &FormatError{Err: ErrInvalidMagic})
+		}
+		return append(problems, &FormatError{Offset: int64(n), Err: ErrTruncated, Reason: "incomplete header"})
+	}
+
+	if string(buf[:4]) != Magic {
+		return append(problems, &FormatError{Err: ErrInvalidMagic})
+	}
+	if version := binary.LittleEndian.Uint16(buf[4:6]); version != Version {
+		return append(problems, &FormatError{Offset: 4, Err: ErrUnsupportedVersion, Reason: fmt.Sprintf("version %d", version)})
+	}
+	if sum := binary.LittleEndian.Uint32(buf[12:16]); sum != crc32.ChecksumIEEE(buf[:12]) {
+		report(&FormatError{Offset: 12, Err: ErrChecksumMismatch, Reason: "header"})
+	}
+	declaredChunks := binary.LittleEndian.Uint32(buf[8:12])
+
+	offset := int64(HeaderSize)
+	chunks := uint32(0)
+	for {
+		n, err := io.ReadFull(r, buf[:ChunkHeaderSize])
+		if err != nil {
+			reason := "incomplete chunk header"
+			if n == 0 && errors.Is(err, io.EOF) {
+				reason = "missing END chunk"
+			}
+			report(&FormatError{Offset: offset, Err: ErrTruncated, Reason: reason})
+			return problems
+		}
+
+		tag := string(buf[:4])
+		length := int64(binary.LittleEndian.Uint32(buf[4:8]))
+		sum := binary.LittleEndian.Uint32(buf[8:12])
+		chunks++
+
+		if length > MaxChunkSize {
+			report(&FormatError{Offset: offset + 4, Chunk: tag, Err: ErrMalformed, Reason: "declared size exceeds limit"})
+			return problems
+		}
+		if size >= 0 && offset+ChunkHeaderSize+length > size {
+			report(&FormatError{
+				Offset: offset + 4,
+				Chunk:  tag,
+				Err:    ErrTruncated,
+				Reason: fmt.Sprintf("declared size %d exceeds the %d bytes left in the file", length, size-offset-ChunkHeaderSize),
+			})
+			return problems
+		}
+
+		hash := crc32.NewIEEE()
+		copied, err := io.CopyN(hash, r, length)
+		if err != nil {
+			report(&FormatError{Offset: offset + ChunkHeaderSize + copied, Chunk: tag, Err: ErrTruncated, Reason: "payload shorter than declared size"})
+			return problems
+		}
+		if hash.Sum32() != sum {
+			if !report(&FormatError{Offset: offset + 8, Chunk: tag, Err: ErrChecksumMismatch}) {
+				return problems
+			}
+		}
+
+		if tag == tagEnd {
+			if length != 0 {
+				report(&FormatError{Offset: offset, Chunk: tag, Err: ErrMalformed, Reason: "END chunk has a payload"})
+			}
+			break
+		}
+		offset += ChunkHeaderSize + length
+	}
+	offset += ChunkHeaderSize
+
+	if chunks != declaredChunks {
+		report(&FormatError{Offset: 8, Err: ErrMalformed, Reason: fmt.Sprintf("header declares %d chunks, found %d", declaredChunks, chunks)})
+	}
+
+	trailing, _ := io.Copy(io.Discard, r)
+	if trailing > 0 {
+		report(&FormatError{Offset: offset, Err: ErrMalformed, Reason: fmt.Sprintf("%d bytes after END chunk", trailing)})
+	}
+
+	return problems
+}
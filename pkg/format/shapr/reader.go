@@ -0,0 +1,363 @@
+package shapr
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+
+	"github.com/wildan3105/converto/pkg/scene"
+)
+
+// Header is the fixed-size header at the start of a .shapr container
+type Header struct {
+	Version    uint16
+	Flags      uint16
+	ChunkCount uint32
+}
+
+// Chunk is a raw, checksum-verified chunk of a .shapr container
+type Chunk struct {
+	Tag     string
+	Offset  int64
+	Payload []byte
+}
+
+// Reader reads the header and chunks of a .shapr container
+type Reader struct {
+	r      io.Reader
+	offset int64
+	header Header
+}
+
+// NewReader reads and verifies the container header from r
+func NewReader(r io.Reader) (*Reader, error) {
+	buf := make([]byte, HeaderSize)
+	n, err := io.ReadFull(r, buf)
+	if err != nil {
+		if !bytes.HasPrefix([]byte(Magic), buf[:min(n, len(Magic))]) {
+			return nil, &FormatError{Err: ErrInvalidMagic}
+		}
+		return nil, &FormatError{Offset: int64(n), Err: ErrTruncated, Reason: "incomplete header"}
+	}
+
+	if string(buf[:4]) != Magic {
+		return nil, &FormatError{Err: ErrInvalidMagic}
+	}
+
+	if sum := binary.LittleEndian.Uint32(buf[12:16]); sum != crc32.ChecksumIEEE(buf[:12]) {
+		return nil, &FormatError{Offset: 12, Err: ErrChecksumMismatch, Reason: "header"}
+	}
+
+	header := Header{
+		Version:    binary.LittleEndian.Uint16(buf[4:6]),
+		Flags:      binary.LittleEndian.Uint16(buf[6:8]),
+		ChunkCount: binary.LittleEndian.Uint32(buf[8:12]),
+	}
+
+	if header.Version != Version {
+		return nil, &FormatError{Offset: 4, Err: ErrUnsupportedVersion, Reason: fmt.Sprintf("version %d", header.Version)}
+	}
+
+	return &Reader{r: r, offset: HeaderSize, header: header}, nil
+}
+
+// Header returns the container header
+func (r *Reader) Header() Header {
+	return r.header
+}
+
+// Next reads the next chunk and verifies its checksum. It returns io.EOF after the END chunk.
+func (r *Reader) Next() (*Chunk, error) {
+	buf := make([]byte, ChunkHeaderSize)
+	n, err := io.ReadFull(r.r, buf)
+	if err != nil {
+		if n == 0 && errors.Is(err, io.EOF) {
+			return nil, &FormatError{Offset: r.offset, Err: ErrTruncated, Reason: "missing END chunk"}
+		}
+		return nil, &FormatError{Offset: r.offset, Err: ErrTruncated, Reason: "incomplete chunk header"}
+	}
+
+	chunk := &Chunk{Tag: string(buf[:4]), Offset: r.offset}
+	length := binary.LittleEndian.Uint32(buf[4:8])
+	sum := binary.LittleEndian.Uint32(buf[8:12])
+
+	if length > MaxChunkSize {
+		return nil, &FormatError{Offset: r.offset + 4, Chunk: chunk.Tag, Err: ErrMalformed, Reason: "declared size exceeds limit"}
+	}
+
+	// reading through a LimitReader lets the buffer grow with the data actually
+	// present instead of trusting the declared length up front
+	payload, err := io.ReadAll(io.LimitReader(r.r, int64(length)))
+	if err != nil {
+		return nil, &FormatError{Offset: r.offset + ChunkHeaderSize, Chunk: chunk.Tag, Err: err}
+	}
+	if len(payload) != int(length) {
+		return nil, &FormatError{
+			Offset: r.offset + ChunkHeaderSize + int64(len(payload)),
+			Chunk:  chunk.Tag,
+			Err:    ErrTruncated,
+			Reason: "payload shorter than declared size",
+		}
+	}
+
+	if crc32.ChecksumIEEE(payload) != sum {
+		return nil, &FormatError{Offset: r.offset + 8, Chunk: chunk.Tag, Err: ErrChecksumMismatch}
+	}
+
+	r.offset += ChunkHeaderSize + int64(length)
+	chunk.Payload = payload
+
+	if chunk.Tag == tagEnd {
+		return chunk, io.EOF
+	}
+	return chunk, nil
+}
+
+// Offset returns the number of bytes consumed so far
+func (r *Reader) Offset() int64 {
+	return r.offset
+}
+
+// Decode parses a complete .shapr container into a scene
+func Decode(r io.Reader) (*scene.Scene, error) {
+	reader, err := NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	s := scene.New()
+	s.Units = ""
+	pendingMeshes := 0
+	chunks := uint32(0)
+
+	for {
+		chunk, err := reader.Next()
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		chunks++
+
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err := decodeChunk(s, chunk, &pendingMeshes); err != nil {
+			return nil, err
+		}
+	}
+
+	if chunks != reader.header.ChunkCount {
+		return nil, &FormatError{Offset: 8, Err: ErrMalformed, Reason: fmt.Sprintf("header declares %d chunks, found %d", reader.header.ChunkCount, chunks)}
+	}
+
+	if pendingMeshes > 0 {
+		return nil, &FormatError{Offset: reader.Offset(), Err: ErrMalformed, Reason: "last body is missing meshes"}
+	}
+
+	if s.Units == "" {
+		s.Units = scene.UnitsMillimeter
+	}
+
+	if err := s.Validate(); err != nil {
+		return nil, &FormatError{Offset: reader.Offset(), Err: ErrMalformed, Reason: err.Error()}
+	}
+
+	return s, nil
+}
+
+// decodeChunk applies one chunk to the scene being built
+func decodeChunk(s *scene.Scene, chunk *Chunk, pendingMeshes *int) error {
+	p := &payload{buf: chunk.Payload}
+	fail := func(reason string) error {
+		return &FormatError{Offset: chunk.Offset + ChunkHeaderSize + int64(p.off), Chunk: chunk.Tag, Err: ErrMalformed, Reason: reason}
+	}
+
+	if chunk.Tag != tagMesh && *pendingMeshes > 0 {
+		return fail("body is missing meshes")
+	}
+
+	switch chunk.Tag {
+	case tagUnits:
+		s.Units = scene.Units(p.str())
+		if p.err == nil && !s.Units.Valid() {
+			return fail("unknown units " + string(s.Units))
+		}
+
+	case tagMetadata:
+		count := p.u32()
+		for i := uint32(0); i < count && p.err == nil; i++ {
+			key := p.str()
+			s.Metadata[key] = p.str()
+		}
+
+	case tagMaterial:
+		s.Materials = append(s.Materials, scene.Material{
+			Name:      p.str(),
+			BaseColor: scene.Color{float64(p.f32()), float64(p.f32()), float64(p.f32()), float64(p.f32())},
+			Metallic:  float64(p.f32()),
+			Roughness: float64(p.f32()),
+		})
+
+	case tagBody:
+		body := scene.Body{Name: p.str()}
+		for i := range body.Transform {
+			body.Transform[i] = p.f64()
+		}
+		*pendingMeshes = int(p.u32())
+		s.Bodies = append(s.Bodies, body)
+
+	case tagMesh:
+		if *pendingMeshes == 0 {
+			return fail("mesh without a body")
+		}
+		mesh, reason := decodeMesh(p)
+		if reason != "" {
+			return fail(reason)
+		}
+		body := &s.Bodies[len(s.Bodies)-1]
+		body.Meshes = append(body.Meshes, mesh)
+		*pendingMeshes--
+
+	default:
+		// unknown chunks are skipped for forward compatibility
+		return nil
+	}
+
+	if p.err != nil {
+		return fail("payload shorter than its content")
+	}
+	if p.remaining() != 0 {
+		return fail("trailing bytes in payload")
+	}
+
+	return nil
+}
+
+// decodeMesh reads a MESH payload, returning a reason when its declared counts do not fit the payload
+func decodeMesh(p *payload) (scene.Mesh, string) {
+	mesh := scene.Mesh{
+		Name:     p.str(),
+		Material: int(int32(p.u32())),
+	}
+	flags := p.u32()
+	vertexCount := int(p.u32())
+	indexCount := int(p.u32())
+
+	if p.err != nil {
+		return mesh, "payload shorter than its content"
+	}
+
+	stride := 12
+	if flags&meshHasNormals != 0 {
+		stride += 12
+	}
+	if flags&meshHasUVs != 0 {
+		stride += 8
+	}
+	if flags&meshHasColors != 0 {
+		stride += 16
+	}
+
+	// compare in int64 so that crafted counts cannot overflow
+	if int64(vertexCount)*int64(stride)+int64(indexCount)*4 != int64(p.remaining()) {
+		return mesh, "declared vertex and index counts do not match payload size"
+	}
+	if indexCount%3 != 0 {
+		return mesh, "index count is not a multiple of 3"
+	}
+
+	mesh.Positions = make([]scene.Vec3, vertexCount)
+	for i := range mesh.Positions {
+		mesh.Positions[i] = p.vec3()
+	}
+	if flags&meshHasNormals != 0 {
+		mesh.Normals = make([]scene.Vec3, vertexCount)
+		for i := range mesh.Normals {
+			mesh.Normals[i] = p.vec3()
+		}
+	}
+	if flags&meshHasUVs != 0 {
+		mesh.UVs = make([]scene.Vec2, vertexCount)
+		for i := range mesh.UVs {
+			mesh.UVs[i] = scene.Vec2{float64(p.f32()), float64(p.f32())}
+		}
+	}
+	if flags&meshHasColors != 0 {
+		mesh.Colors = make([]scene.Color, vertexCount)
+		for i := range mesh.Colors {
+			mesh.Colors[i] = scene.Color{float64(p.f32()), float64(p.f32()), float64(p.f32()), float64(p.f32())}
+		}
+	}
+
+	mesh.Indices = make([]uint32, indexCount)
+	for i := range mesh.Indices {
+		mesh.Indices[i] = p.u32()
+	}
+
+	for _, index := range mesh.Indices {
+		if int(index) >= vertexCount {
+			return mesh, "index out of range"
+		}
+	}
+
+	return mesh, ""
+}
+
+// payload is a bounds-checked little-endian cursor over a chunk payload.
+// Reads past the end set err and return zero values.
+type payload struct {
+	buf []byte
+	off int
+	err error
+}
+
+func (p *payload) remaining() int {
+	return len(p.buf) - p.off
+}
+
+func (p *payload) take(n int) []byte {
+	if p.err != nil || n > p.remaining() {
+		p.err = ErrTruncated
+		return nil
+	}
+	b := p.buf[p.off : p.off+n]
+	p.off += n
+	return b
+}
+
+func (p *payload) u16() uint16 {
+	if b := p.take(2); b != nil {
+		return binary.LittleEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (p *payload) u32() uint32 {
+	if b := p.take(4); b != nil {
+		return binary.LittleEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (p *payload) f32() float32 {
+	return math.Float32frombits(p.u32())
+}
+
+func (p *payload) f64() float64 {
+	if b := p.take(8); b != nil {
+		return math.Float64frombits(binary.LittleEndian.Uint64(b))
+	}
+	return 0
+}
+
+func (p *payload) vec3() scene.Vec3 {
+	return scene.Vec3{float64(p.f32()), float64(p.f32()), float64(p.f32())}
+}
+
+func (p *payload) str() string {
+	return string(p.take(int(p.u16())))
+}
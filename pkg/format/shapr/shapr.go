@@ -0,0 +1,87 @@
+// Package shapr reads and writes the .shapr container format.
+//
+// A .shapr file is a little-endian binary container: a 16 byte header followed
+// by a sequence of checksummed chunks.
+//
+//	header: magic "SHPR" | version u16 | flags u16 | chunk count u32 | header CRC-32 u32
+//	chunk:  tag [4]byte  | payload length u32 | payload CRC-32 u32 | payload
+//
+// Chunks are UNIT (scene units), META (key/value metadata), MATL (a material),
+// BODY (a body and its transform) and MESH (a mesh of the preceding body).
+// The container ends with an empty END chunk. Unknown chunk tags are skipped.
+package shapr
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	// Magic identifies a .shapr container
+	Magic = "SHPR"
+	// Version is the container version written and understood by this package
+	Version = 1
+	// HeaderSize is the size in bytes of the container header
+	HeaderSize = 16
+	// ChunkHeaderSize is the size in bytes of a chunk header
+	ChunkHeaderSize = 12
+	// MaxChunkSize bounds the payload length a chunk may declare
+	MaxChunkSize = 1 << 30
+)
+
+// chunk tags
+const (
+	tagUnits    = "UNIT"
+	tagMetadata = "META"
+	tagMaterial = "MATL"
+	tagBody     = "BODY"
+	tagMesh     = "MESH"
+	tagEnd      = "END\x00"
+)
+
+// mesh attribute flags
+const (
+	meshHasNormals uint32 = 1 << iota
+	meshHasUVs
+	meshHasColors
+)
+
+var (
+	ErrInvalidMagic       = errors.New("not a .shapr file")
+	ErrUnsupportedVersion = errors.New("unsupported .shapr version")
+	ErrChecksumMismatch   = errors.New("checksum mismatch")
+	ErrTruncated          = errors.New("unexpected end of file")
+	ErrMalformed          = errors.New("malformed content")
+)
+
+// FormatError describes where and why a .shapr file could not be read
+type FormatError struct {
+	Offset int64
+	Chunk  string
+	Reason string
+	Err    error
+}
+
+func (e *FormatError) Error() string {
+	location := fmt.Sprintf("offset %d", e.Offset)
+	if e.Chunk != "" {
+		location = fmt.Sprintf("chunk %s at %s", printableTag(e.Chunk), location)
+	}
+
+	if e.Reason == "" {
+		return fmt.Sprintf("shapr: %s: %v", location, e.Err)
+	}
+	return fmt.Sprintf("shapr: %s: %v: %s", location, e.Err, e.Reason)
+}
+
+func (e *FormatError) Unwrap() error {
+	return e.Err
+}
+
+// printableTag renders a chunk tag for error messages
+func printableTag(tag string) string {
+	if tag == tagEnd {
+		return "END"
+	}
+	return fmt.Sprintf("%q", tag)
+}
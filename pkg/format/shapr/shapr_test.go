@@ -0,0 +1,109 @@
+package shapr
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wildan3105/converto/pkg/scene"
+	"github.com/wildan3105/converto/pkg/scene/scenetest"
+)
+
+func encode(t *testing.T, s *scene.Scene) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	require.NoError(t, Encode(&buf, s))
+	return buf.Bytes()
+}
+
+func TestRoundTrip(t *testing.T) {
+	original := scenetest.Cube(10)
+	original.Units = scene.UnitsInch
+	original.Bodies[0].Transform[12] = 5
+	mesh := &original.Bodies[0].Meshes[0]
+	mesh.UVs = make([]scene.Vec2, len(mesh.Positions))
+	mesh.Colors = make([]scene.Color, len(mesh.Positions))
+	mesh.Colors[3] = scene.Color{1, 0, 0, 1}
+
+	decoded, err := Decode(bytes.NewReader(encode(t, original)))
+	require.NoError(t, err)
+
+	assert.Equal(t, original, decoded)
+}
+
+func TestDecodeErrors(t *testing.T) {
+	valid := encode(t, scenetest.Cube(1))
+
+	corruptPayload := bytes.Clone(valid)
+	corruptPayload[len(corruptPayload)-ChunkHeaderSize-1] ^= 0xff
+
+	wrongVersion := bytes.Clone(valid)
+	binary.LittleEndian.PutUint16(wrongVersion[4:6], 7)
+	binary.LittleEndian.PutUint32(wrongVersion[12:16], crc32.ChecksumIEEE(wrongVersion[:12]))
+
+	wrongCount := bytes.Clone(valid)
+	binary.LittleEndian.PutUint32(wrongCount[8:12], 99)
+	binary.LittleEndian.PutUint32(wrongCount[12:16], crc32.ChecksumIEEE(wrongCount[:12]))
+
+	oversized := bytes.Clone(valid[:HeaderSize+ChunkHeaderSize])
+	binary.LittleEndian.PutUint32(oversized[HeaderSize+4:], MaxChunkSize+1)
+
+	tests := []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{"plain text", []byte("abc 123"), ErrInvalidMagic},
+		{"empty", nil, ErrTruncated},
+		{"short header", valid[:10], ErrTruncated},
+		{"unsupported version", wrongVersion, ErrUnsupportedVersion},
+		{"payload checksum", corruptPayload, ErrChecksumMismatch},
+		{"truncated payload", valid[:len(valid)-40], ErrTruncated},
+		{"missing end chunk", valid[:len(valid)-ChunkHeaderSize], ErrTruncated},
+		{"chunk count", wrongCount, ErrMalformed},
+		{"oversized chunk", oversized, ErrMalformed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decode(bytes.NewReader(tt.data))
+
+			var formatErr *FormatError
+			require.ErrorAs(t, err, &formatErr)
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	valid := encode(t, scenetest.Cube(1))
+	assert.Empty(t, Check(bytes.NewReader(valid), int64(len(valid))))
+
+	// damage the payloads of the first and the last non-END chunks: both are reported
+	damaged := bytes.Clone(valid)
+	damaged[HeaderSize+ChunkHeaderSize] ^= 0xff
+	damaged[len(damaged)-ChunkHeaderSize-1] ^= 0xff
+	damaged = append(damaged, 0, 0)
+	binary.LittleEndian.PutUint32(damaged[8:12], 99)
+
+	problems := Check(bytes.NewReader(damaged), int64(len(damaged)))
+	require.Len(t, problems, 5)
+	assert.ErrorIs(t, problems[0], ErrChecksumMismatch)
+	assert.Equal(t, "header", problems[0].Reason)
+	assert.ErrorIs(t, problems[1], ErrChecksumMismatch)
+	assert.Equal(t, "UNIT", problems[1].Chunk)
+	assert.ErrorIs(t, problems[2], ErrChecksumMismatch)
+	assert.Contains(t, problems[3].Reason, "header declares 99 chunks")
+	assert.Contains(t, problems[4].Reason, "2 bytes after END chunk")
+
+	// a declared size beyond the end of the file is caught before reading the payload
+	truncated := valid[:len(valid)-ChunkHeaderSize-4]
+	problems = Check(bytes.NewReader(truncated), int64(len(truncated)))
+	require.Len(t, problems, 1)
+	assert.ErrorIs(t, problems[0], ErrTruncated)
+	assert.Contains(t, problems[0].Reason, "exceeds the")
+}
@@ -0,0 +1,183 @@
+package shapr
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"sort"
+
+	"github.com/wildan3105/converto/pkg/scene"
+)
+
+// Encode writes the scene as a .shapr container
+func Encode(w io.Writer, s *scene.Scene) error {
+	if err := s.Validate(); err != nil {
+		return fmt.Errorf("shapr: invalid scene: %w", err)
+	}
+
+	var chunks []chunkData
+
+	units := &payloadWriter{}
+	units.str(string(s.Units))
+	chunks = append(chunks, chunkData{tagUnits, units.Bytes()})
+
+	if len(s.Metadata) > 0 {
+		keys := make([]string, 0, len(s.Metadata))
+		for key := range s.Metadata {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		meta := &payloadWriter{}
+		meta.u32(uint32(len(keys)))
+		for _, key := range keys {
+			meta.str(key)
+			meta.str(s.Metadata[key])
+		}
+		chunks = append(chunks, chunkData{tagMetadata, meta.Bytes()})
+	}
+
+	for _, material := range s.Materials {
+		p := &payloadWriter{}
+		p.str(material.Name)
+		for _, c := range material.BaseColor {
+			p.f32(c)
+		}
+		p.f32(material.Metallic)
+		p.f32(material.Roughness)
+		chunks = append(chunks, chunkData{tagMaterial, p.Bytes()})
+	}
+
+	for _, body := range s.Bodies {
+		p := &payloadWriter{}
+		p.str(body.Name)
+		for _, v := range body.Transform {
+			p.f64(v)
+		}
+		p.u32(uint32(len(body.Meshes)))
+		chunks = append(chunks, chunkData{tagBody, p.Bytes()})
+
+		for _, mesh := range body.Meshes {
+			chunks = append(chunks, chunkData{tagMesh, encodeMesh(&mesh)})
+		}
+	}
+
+	chunks = append(chunks, chunkData{tagEnd, nil})
+
+	header := make([]byte, HeaderSize)
+	copy(header, Magic)
+	binary.LittleEndian.PutUint16(header[4:6], Version)
+	binary.LittleEndian.PutUint32(header[8:12], uint32(len(chunks)))
+	binary.LittleEndian.PutUint32(header[12:16], crc32.ChecksumIEEE(header[:12]))
+
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	for _, chunk := range chunks {
+		if err := chunk.writeTo(w); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// encodeMesh serializes a mesh into a MESH payload
+func encodeMesh(mesh *scene.Mesh) []byte {
+	var flags uint32
+	if len(mesh.Normals) > 0 {
+		flags |= meshHasNormals
+	}
+	if len(mesh.UVs) > 0 {
+		flags |= meshHasUVs
+	}
+	if len(mesh.Colors) > 0 {
+		flags |= meshHasColors
+	}
+
+	p := &payloadWriter{}
+	p.str(mesh.Name)
+	p.u32(uint32(int32(mesh.Material)))
+	p.u32(flags)
+	p.u32(uint32(len(mesh.Positions)))
+	p.u32(uint32(len(mesh.Indices)))
+
+	for _, v := range mesh.Positions {
+		p.vec3(v)
+	}
+	for _, n := range mesh.Normals {
+		p.vec3(n)
+	}
+	for _, uv := range mesh.UVs {
+		p.f32(uv[0])
+		p.f32(uv[1])
+	}
+	for _, c := range mesh.Colors {
+		for _, component := range c {
+			p.f32(component)
+		}
+	}
+	for _, index := range mesh.Indices {
+		p.u32(index)
+	}
+
+	return p.Bytes()
+}
+
+type chunkData struct {
+	tag     string
+	payload []byte
+}
+
+func (c chunkData) writeTo(w io.Writer) error {
+	header := make([]byte, ChunkHeaderSize)
+	copy(header, c.tag)
+	binary.LittleEndian.PutUint32(header[4:8], uint32(len(c.payload)))
+	binary.LittleEndian.PutUint32(header[8:12], crc32.ChecksumIEEE(c.payload))
+
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(c.payload)
+	return err
+}
+
+// payloadWriter builds little-endian chunk payloads
+type payloadWriter struct {
+	bytes.Buffer
+}
+
+func (p *payloadWriter) u16(v uint16) {
+	_ = binary.Write(&p.Buffer, binary.LittleEndian, v)
+}
+
+func (p *payloadWriter) u32(v uint32) {
+	_ = binary.Write(&p.Buffer, binary.LittleEndian, v)
+}
+
+func (p *payloadWriter) f32(v float64) {
+	p.u32(math.Float32bits(float32(v)))
+}
+
+func (p *payloadWriter) f64(v float64) {
+	_ = binary.Write(&p.Buffer, binary.LittleEndian, math.Float64bits(v))
+}
+
+func (p *payloadWriter) vec3(v scene.Vec3) {
+	p.f32(v[0])
+	p.f32(v[1])
+	p.f32(v[2])
+}
+
+// str writes a length-prefixed string, truncated to the 65535 bytes the prefix can describe
+func (p *payloadWriter) str(s string) {
+	if len(s) > math.MaxUint16 {
+		s = s[:math.MaxUint16]
+	}
+	p.u16(uint16(len(s)))
+	p.WriteString(s)
+}
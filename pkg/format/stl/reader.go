@@ -0,0 +1,172 @@
+package stl
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/wildan3105/converto/pkg/scene"
+)
+
+// ErrMalformed is returned when an STL file cannot be parsed
+var ErrMalformed = errors.New("stl: malformed file")
+
+// Decode reads a binary or ASCII STL file. Identical vertex positions are merged so
+// that the resulting meshes are indexed; ASCII solids become separate bodies.
+// STL carries no units, so the scene is assumed to be in millimeters.
+func Decode(r io.Reader) (*scene.Scene, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if isBinary(data) {
+		return decodeBinary(data)
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("solid")) {
+		return decodeASCII(data)
+	}
+	return nil, fmt.Errorf("%w: neither binary nor ASCII STL", ErrMalformed)
+}
+
+// isBinary checks that the size matches the triangle count declared in the header
+func isBinary(data []byte) bool {
+	if len(data) < binaryHeaderSize+4 {
+		return false
+	}
+	count := int64(binary.LittleEndian.Uint32(data[binaryHeaderSize:]))
+	return int64(len(data)) == binaryHeaderSize+4+count*50
+}
+
+func decodeBinary(data []byte) (*scene.Scene, error) {
+	count := int(binary.LittleEndian.Uint32(data[binaryHeaderSize:]))
+	builder := newMeshBuilder("body_1")
+
+	records := data[binaryHeaderSize+4:]
+	for i := 0; i < count; i++ {
+		record := records[i*50:]
+		builder.add(readVec3(record[12:]), readVec3(record[24:]), readVec3(record[36:]))
+	}
+
+	s := scene.New()
+	if name := strings.TrimSpace(strings.TrimRight(string(data[:binaryHeaderSize]), "\x00")); name != "" {
+		s.Metadata["stl_header"] = name
+	}
+	s.Bodies = []scene.Body{builder.body()}
+	return s, nil
+}
+
+func decodeASCII(data []byte) (*scene.Scene, error) {
+	s := scene.New()
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var builder *meshBuilder
+	var corners []scene.Vec3
+	line := 0
+
+	for scanner.Scan() {
+		line++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "solid":
+			if builder != nil {
+				return nil, fmt.Errorf("%w: line %d: nested solid", ErrMalformed, line)
+			}
+			builder = newMeshBuilder(strings.Join(fields[1:], " "))
+		case "endsolid":
+			if builder == nil {
+				return nil, fmt.Errorf("%w: line %d: endsolid without solid", ErrMalformed, line)
+			}
+			s.Bodies = append(s.Bodies, builder.body())
+			builder = nil
+		case "outer":
+			corners = corners[:0]
+		case "vertex":
+			if builder == nil || len(fields) != 4 {
+				return nil, fmt.Errorf("%w: line %d: unexpected vertex", ErrMalformed, line)
+			}
+			var v scene.Vec3
+			for i := range v {
+				value, err := strconv.ParseFloat(fields[i+1], 64)
+				if err != nil {
+					return nil, fmt.Errorf("%w: line %d: %v", ErrMalformed, line, err)
+				}
+				v[i] = value
+			}
+			corners = append(corners, v)
+		case "endloop":
+			if len(corners) != 3 {
+				return nil, fmt.Errorf("%w: line %d: facet with %d vertices", ErrMalformed, line, len(corners))
+			}
+			builder.add(corners[0], corners[1], corners[2])
+		case "facet", "endfacet":
+		default:
+			return nil, fmt.Errorf("%w: line %d: unknown keyword %q", ErrMalformed, line, fields[0])
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if builder != nil {
+		// tolerate a missing endsolid at the end of the file
+		s.Bodies = append(s.Bodies, builder.body())
+	}
+
+	if len(s.Bodies) == 0 {
+		return nil, fmt.Errorf("%w: no solid found", ErrMalformed)
+	}
+
+	return s, nil
+}
+
+// meshBuilder merges identical positions into shared, indexed vertices
+type meshBuilder struct {
+	name  string
+	index map[scene.Vec3]uint32
+	mesh  scene.Mesh
+}
+
+func newMeshBuilder(name string) *meshBuilder {
+	return &meshBuilder{
+		name:  name,
+		index: make(map[scene.Vec3]uint32),
+		mesh:  scene.Mesh{Name: name, Material: scene.NoMaterial},
+	}
+}
+
+func (b *meshBuilder) add(corners ...scene.Vec3) {
+	for _, v := range corners {
+		i, ok := b.index[v]
+		if !ok {
+			i = uint32(len(b.mesh.Positions))
+			b.index[v] = i
+			b.mesh.Positions = append(b.mesh.Positions, v)
+		}
+		b.mesh.Indices = append(b.mesh.Indices, i)
+	}
+}
+
+func (b *meshBuilder) body() scene.Body {
+	return scene.Body{Name: b.name, Transform: scene.Identity(), Meshes: []scene.Mesh{b.mesh}}
+}
+
+func readVec3(b []byte) scene.Vec3 {
+	return scene.Vec3{
+		float64(math.Float32frombits(binary.LittleEndian.Uint32(b[0:]))),
+		float64(math.Float32frombits(binary.LittleEndian.Uint32(b[4:]))),
+		float64(math.Float32frombits(binary.LittleEndian.Uint32(b[8:]))),
+	}
+}
@@ -0,0 +1,34 @@
+package stl
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wildan3105/converto/pkg/scene/scenetest"
+)
+
+func TestDecodeRoundTrip(t *testing.T) {
+	for _, ascii := range []bool{false, true} {
+		var buf bytes.Buffer
+		require.NoError(t, Encode(&buf, scenetest.Cube(2), EncodeOptions{ASCII: ascii}))
+
+		s, err := Decode(&buf)
+		require.NoError(t, err)
+		require.NoError(t, s.Validate())
+
+		assert.Equal(t, 12, s.TriangleCount(), "ascii=%v", ascii)
+		// shared corners are welded back into the cube's 8 vertices
+		assert.Equal(t, 8, s.VertexCount(), "ascii=%v", ascii)
+	}
+}
+
+func TestDecodeMalformed(t *testing.T) {
+	_, err := Decode(strings.NewReader("solid broken\nfacet normal 0 0 1\nouter loop\nvertex 0 0\n"))
+	assert.ErrorIs(t, err, ErrMalformed)
+
+	_, err = Decode(strings.NewReader("not an stl"))
+	assert.ErrorIs(t, err, ErrMalformed)
+}
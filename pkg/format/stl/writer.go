@@ -0,0 +1,126 @@
+// Package stl reads and writes stereolithography files in their binary and ASCII flavours.
+package stl
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/wildan3105/converto/pkg/scene"
+)
+
+// binaryHeaderSize is the size of the free-form header of a binary STL file
+const binaryHeaderSize = 80
+
+// ErrTooManyTriangles is returned when a scene does not fit the 32-bit triangle count of binary STL
+var ErrTooManyTriangles = errors.New("stl: too many triangles for binary STL")
+
+// EncodeOptions controls the STL flavour written by Encode
+type EncodeOptions struct {
+	ASCII bool
+}
+
+// Encode writes every body of the scene as STL. Binary STL holds a single solid
+// named after the bodies in its header; ASCII STL writes one named solid per body.
+func Encode(w io.Writer, s *scene.Scene, opts EncodeOptions) error {
+	bw := bufio.NewWriter(w)
+
+	var err error
+	if opts.ASCII {
+		err = encodeASCII(bw, s)
+	} else {
+		err = encodeBinary(bw, s)
+	}
+	if err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
+
+func encodeBinary(w *bufio.Writer, s *scene.Scene) error {
+	count := s.TriangleCount()
+	if count > math.MaxUint32 {
+		return ErrTooManyTriangles
+	}
+
+	names := make([]string, len(s.Bodies))
+	for i := range s.Bodies {
+		names[i] = solidName(&s.Bodies[i], i)
+	}
+
+	header := make([]byte, binaryHeaderSize)
+	// a binary header must never start with "solid", or readers mistake it for ASCII
+	copy(header, "converto: "+strings.Join(names, ", "))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	if err := binary.Write(w, binary.LittleEndian, uint32(count)); err != nil {
+		return err
+	}
+
+	record := make([]byte, 50)
+	var writeErr error
+
+	for i := range s.Bodies {
+		s.Bodies[i].ForEachTriangle(func(a, b, c scene.Vec3) {
+			if writeErr != nil {
+				return
+			}
+
+			putVec3(record[0:], scene.FaceNormal(a, b, c))
+			putVec3(record[12:], a)
+			putVec3(record[24:], b)
+			putVec3(record[36:], c)
+			// bytes 48-49 are the unused attribute byte count
+
+			_, writeErr = w.Write(record)
+		})
+	}
+
+	return writeErr
+}
+
+func encodeASCII(w *bufio.Writer, s *scene.Scene) error {
+	for i := range s.Bodies {
+		body := &s.Bodies[i]
+		name := solidName(body, i)
+
+		fmt.Fprintf(w, "solid %s\n", name)
+		body.ForEachTriangle(func(a, b, c scene.Vec3) {
+			n := scene.FaceNormal(a, b, c)
+			fmt.Fprintf(w, "  facet normal %e %e %e\n", n[0], n[1], n[2])
+			fmt.Fprintf(w, "    outer loop\n")
+			for _, v := range []scene.Vec3{a, b, c} {
+				fmt.Fprintf(w, "      vertex %e %e %e\n", v[0], v[1], v[2])
+			}
+			fmt.Fprintf(w, "    endloop\n")
+			fmt.Fprintf(w, "  endfacet\n")
+		})
+		if _, err := fmt.Fprintf(w, "endsolid %s\n", name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// solidName returns a single-line name for the body, falling back to its position
+func solidName(body *scene.Body, index int) string {
+	name := strings.Join(strings.Fields(body.Name), "_")
+	if name == "" {
+		name = fmt.Sprintf("body_%d", index+1)
+	}
+	return name
+}
+
+func putVec3(b []byte, v scene.Vec3) {
+	binary.LittleEndian.PutUint32(b[0:], math.Float32bits(float32(v[0])))
+	binary.LittleEndian.PutUint32(b[4:], math.Float32bits(float32(v[1])))
+	binary.LittleEndian.PutUint32(b[8:], math.Float32bits(float32(v[2])))
+}
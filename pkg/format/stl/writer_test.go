@@ -0,0 +1,52 @@
+package stl
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wildan3105/converto/pkg/scene/scenetest"
+)
+
+func TestEncodeBinary(t *testing.T) {
+	s := scenetest.Cube(2)
+
+	var buf bytes.Buffer
+	require.NoError(t, Encode(&buf, s, EncodeOptions{}))
+
+	data := buf.Bytes()
+	require.Len(t, data, binaryHeaderSize+4+12*50)
+	assert.False(t, strings.HasPrefix(string(data), "solid"))
+	assert.Contains(t, string(data[:binaryHeaderSize]), "Body_1")
+	assert.Equal(t, uint32(12), binary.LittleEndian.Uint32(data[binaryHeaderSize:]))
+
+	// the first cube triangle lies on the bottom face and must point down
+	record := data[binaryHeaderSize+4:]
+	normal := [3]float32{}
+	for i := range normal {
+		normal[i] = math.Float32frombits(binary.LittleEndian.Uint32(record[4*i:]))
+	}
+	assert.Equal(t, [3]float32{0, 0, -1}, normal)
+}
+
+func TestEncodeASCII(t *testing.T) {
+	s := scenetest.Cube(2)
+	s.Bodies = append(s.Bodies, s.Bodies[0])
+	s.Bodies[1].Name = ""
+	s.Bodies[1].Transform[12] = 10
+
+	var buf bytes.Buffer
+	require.NoError(t, Encode(&buf, s, EncodeOptions{ASCII: true}))
+
+	out := buf.String()
+	assert.True(t, strings.HasPrefix(out, "solid Body_1\n"))
+	assert.Contains(t, out, "endsolid Body_1\nsolid body_2\n")
+	assert.True(t, strings.HasSuffix(out, "endsolid body_2\n"))
+	assert.Equal(t, 24, strings.Count(out, "facet normal"))
+	assert.Contains(t, out, "facet normal 0.000000e+00 0.000000e+00 -1.000000e+00")
+	assert.Contains(t, out, "vertex 1.200000e+01")
+}
@@ -0,0 +1,255 @@
+// Package threemf writes 3D Manufacturing Format (3MF) packages.
+package threemf
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/wildan3105/converto/pkg/scene"
+)
+
+const (
+	coreNamespace = "http://schemas.microsoft.com/3dmanufacturing/core/2015/02"
+	modelPath     = "3D/3dmodel.model"
+
+	contentTypes = `<?xml version="1.0" encoding="UTF-8"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+  <Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+  <Default Extension="model" ContentType="application/vnd.ms-package.3dmanufacturing-3dmodel+xml"/>
+</Types>
+`
+	relationships = `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+  <Relationship Target="/` + modelPath + `" Id="rel0" Type="http://schemas.microsoft.com/3dmanufacturing/2013/01/3dmodel"/>
+</Relationships>
+`
+)
+
+// units maps scene units to the unit names defined by the 3MF core specification
+var units = map[scene.Units]string{
+	scene.UnitsMillimeter: "millimeter",
+	scene.UnitsCentimeter: "centimeter",
+	scene.UnitsMeter:      "meter",
+	scene.UnitsInch:       "inch",
+	scene.UnitsFoot:       "foot",
+}
+
+// metadataNames are the metadata names defined by the 3MF core specification;
+// other scene metadata is not carried over because unprefixed names are reserved
+var metadataNames = []string{"Title", "Designer", "Description", "Copyright", "LicenseTerms", "Rating", "CreationDate", "ModificationDate"}
+
+// Encode writes the scene as a 3MF package. Each body becomes an object placed
+// by a build item; scene materials become a base material group so that every
+// object (and, where meshes differ, every triangle) carries its colour.
+func Encode(w io.Writer, s *scene.Scene) error {
+	archive := zip.NewWriter(w)
+
+	for _, part := range []struct{ name, content string }{
+		{"[Content_Types].xml", contentTypes},
+		{"_rels/.rels", relationships},
+	} {
+		f, err := archive.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, part.content); err != nil {
+			return err
+		}
+	}
+
+	f, err := archive.Create(modelPath)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.WriteString(f, xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(f)
+	encoder.Indent("", " ")
+	if err := encoder.Encode(buildModel(s)); err != nil {
+		return err
+	}
+
+	return archive.Close()
+}
+
+// buildModel maps the scene to the 3MF model document
+func buildModel(s *scene.Scene) model {
+	m := model{
+		Namespace: coreNamespace,
+		Unit:      units[s.Units],
+		Lang:      "en-US",
+	}
+	if m.Unit == "" {
+		m.Unit = "millimeter"
+	}
+
+	m.Metadata = append(m.Metadata, metadata{Name: "Application", Value: "converto"})
+	for _, name := range metadataNames {
+		for key, value := range s.Metadata {
+			if strings.EqualFold(key, name) {
+				m.Metadata = append(m.Metadata, metadata{Name: name, Value: value})
+				break
+			}
+		}
+	}
+
+	nextID := 1
+	materialsID := 0
+	if len(s.Materials) > 0 {
+		materialsID = nextID
+		nextID++
+
+		group := &baseMaterials{ID: materialsID}
+		for _, material := range s.Materials {
+			group.Bases = append(group.Bases, base{Name: material.Name, DisplayColor: hexColor(material.BaseColor)})
+		}
+		m.Resources.BaseMaterials = group
+	}
+
+	for i := range s.Bodies {
+		body := &s.Bodies[i]
+		obj := object{ID: nextID, Type: "model", Name: body.Name}
+
+		objectMaterial := scene.NoMaterial
+		for _, mesh := range body.Meshes {
+			if mesh.Material != scene.NoMaterial {
+				objectMaterial = mesh.Material
+				break
+			}
+		}
+		if objectMaterial != scene.NoMaterial {
+			obj.PID = strconv.Itoa(materialsID)
+			obj.PIndex = strconv.Itoa(objectMaterial)
+		}
+
+		for _, mesh := range body.Meshes {
+			offset := len(obj.Mesh.Vertices)
+			for _, p := range mesh.Positions {
+				obj.Mesh.Vertices = append(obj.Mesh.Vertices, vertex{X: p[0], Y: p[1], Z: p[2]})
+			}
+			for t := 0; t < len(mesh.Indices); t += 3 {
+				tri := triangle{
+					V1: offset + int(mesh.Indices[t]),
+					V2: offset + int(mesh.Indices[t+1]),
+					V3: offset + int(mesh.Indices[t+2]),
+				}
+				if mesh.Material != scene.NoMaterial && mesh.Material != objectMaterial {
+					tri.PID = strconv.Itoa(materialsID)
+					tri.P1 = strconv.Itoa(mesh.Material)
+				}
+				obj.Mesh.Triangles = append(obj.Mesh.Triangles, tri)
+			}
+		}
+
+		// the core specification does not allow objects without triangles
+		if len(obj.Mesh.Triangles) == 0 {
+			continue
+		}
+
+		m.Resources.Objects = append(m.Resources.Objects, obj)
+
+		item := item{ObjectID: obj.ID}
+		if !body.Transform.IsIdentity() && body.Transform != (scene.Mat4{}) {
+			item.Transform = transform(body.Transform)
+		}
+		m.Build.Items = append(m.Build.Items, item)
+
+		nextID++
+	}
+
+	return m
+}
+
+// transform formats the affine part of a column-major matrix as a 3MF transform,
+// which uses row vectors and therefore lists our columns one after the other
+func transform(t scene.Mat4) string {
+	values := make([]string, 0, 12)
+	for col := 0; col < 4; col++ {
+		for row := 0; row < 3; row++ {
+			values = append(values, strconv.FormatFloat(t[col*4+row], 'g', -1, 64))
+		}
+	}
+	return strings.Join(values, " ")
+}
+
+// hexColor formats a linear RGBA color as #RRGGBBAA
+func hexColor(c scene.Color) string {
+	channel := func(v float64) int {
+		return int(min(max(v, 0), 1)*255 + 0.5)
+	}
+	return fmt.Sprintf("#%02X%02X%02X%02X", channel(c[0]), channel(c[1]), channel(c[2]), channel(c[3]))
+}
+
+type model struct {
+	XMLName   xml.Name   `xml:"model"`
+	Namespace string     `xml:"xmlns,attr"`
+	Unit      string     `xml:"unit,attr"`
+	Lang      string     `xml:"xml:lang,attr"`
+	Metadata  []metadata `xml:"metadata"`
+	Resources resources  `xml:"resources"`
+	Build     build      `xml:"build"`
+}
+
+type metadata struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:",chardata"`
+}
+
+type resources struct {
+	BaseMaterials *baseMaterials `xml:"basematerials,omitempty"`
+	Objects       []object       `xml:"object"`
+}
+
+type baseMaterials struct {
+	ID    int    `xml:"id,attr"`
+	Bases []base `xml:"base"`
+}
+
+type base struct {
+	Name         string `xml:"name,attr"`
+	DisplayColor string `xml:"displaycolor,attr"`
+}
+
+type object struct {
+	ID     int    `xml:"id,attr"`
+	Type   string `xml:"type,attr"`
+	Name   string `xml:"name,attr,omitempty"`
+	PID    string `xml:"pid,attr,omitempty"`
+	PIndex string `xml:"pindex,attr,omitempty"`
+	Mesh   mesh   `xml:"mesh"`
+}
+
+type mesh struct {
+	Vertices  []vertex   `xml:"vertices>vertex"`
+	Triangles []triangle `xml:"triangles>triangle"`
+}
+
+type vertex struct {
+	X float64 `xml:"x,attr"`
+	Y float64 `xml:"y,attr"`
+	Z float64 `xml:"z,attr"`
+}
+
+type triangle struct {
+	V1  int    `xml:"v1,attr"`
+	V2  int    `xml:"v2,attr"`
+	V3  int    `xml:"v3,attr"`
+	PID string `xml:"pid,attr,omitempty"`
+	P1  string `xml:"p1,attr,omitempty"`
+}
+
+type build struct {
+	Items []item `xml:"item"`
+}
+
+type item struct {
+	ObjectID  int    `xml:"objectid,attr"`
+	Transform string `xml:"transform,attr,omitempty"`
+}
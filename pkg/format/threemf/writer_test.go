@@ -0,0 +1,52 @@
+package threemf
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wildan3105/converto/pkg/scene"
+	"github.com/wildan3105/converto/pkg/scene/scenetest"
+)
+
+func TestEncode(t *testing.T) {
+	s := scenetest.Cube(2)
+	s.Units = scene.UnitsInch
+	s.Metadata["title"] = "Bracket"
+	s.Materials = append(s.Materials, scene.Material{Name: "red", BaseColor: scene.Color{1, 0, 0, 1}})
+	red := scenetest.CubeMesh(1)
+	red.Material = 1
+	s.Bodies[0].Meshes = append(s.Bodies[0].Meshes, red)
+	s.Bodies[0].Transform[13] = 7
+
+	var buf bytes.Buffer
+	require.NoError(t, Encode(&buf, s))
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+
+	names := make([]string, 0, len(archive.File))
+	var modelXML string
+	for _, f := range archive.File {
+		names = append(names, f.Name)
+		if f.Name == modelPath {
+			r, err := f.Open()
+			require.NoError(t, err)
+			data, err := io.ReadAll(r)
+			require.NoError(t, err)
+			modelXML = string(data)
+		}
+	}
+
+	assert.ElementsMatch(t, []string{"[Content_Types].xml", "_rels/.rels", modelPath}, names)
+	assert.Contains(t, modelXML, `unit="inch"`)
+	assert.Contains(t, modelXML, `<metadata name="Title">Bracket</metadata>`)
+	assert.Contains(t, modelXML, `<base name="grey" displaycolor="#808080FF"></base>`)
+	assert.Contains(t, modelXML, `<base name="red" displaycolor="#FF0000FF"></base>`)
+	assert.Contains(t, modelXML, `<object id="2" type="model" name="Body 1" pid="1" pindex="0">`)
+	assert.Contains(t, modelXML, `<triangle v1="8" v2="10" v3="9" pid="1" p1="1"></triangle>`)
+	assert.Contains(t, modelXML, `<item objectid="2" transform="1 0 0 0 1 0 0 0 1 0 7 0"></item>`)
+}
@@ -0,0 +1,228 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+
+	"github.com/gofiber/fiber/v2"
+	config "github.com/wildan3105/converto/configs"
+	"github.com/wildan3105/converto/pkg/api/schema"
+	"github.com/wildan3105/converto/pkg/archive"
+	"github.com/wildan3105/converto/pkg/service"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CreateBatch handles the creation of a batch of conversions from several files.
+// Each file part may be a model or a ZIP archive of models; every model becomes an
+// upload converted to each requested target format. Archives are rejected as a whole
+// when an entry name is unsafe or their content exceeds the configured limits; models
+// that cannot be converted are reported per entry.
+func (h *ConversionHandlerManager) CreateBatch(c *fiber.Ctx) error {
+	form, err := c.MultipartForm()
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse multipart form",
+		})
+	}
+
+	files := form.File["file"]
+	if len(files) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "File is required"})
+	}
+
+	targets, err := h.requestTargets(form)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	limits := archive.Limits{
+		MaxEntries:          config.AppConfig.BatchMaxEntries,
+		MaxEntrySize:        config.AppConfig.BatchMaxEntrySize,
+		MaxTotalSize:        config.AppConfig.BatchMaxTotalSize,
+		MaxCompressionRatio: config.AppConfig.BatchMaxCompressionRatio,
+	}
+
+	var uploads []uploadedFile
+	for _, file := range files {
+		f, err := file.Open()
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Failed to read uploaded file",
+			})
+		}
+		// archive entries are read from the part until the batch is created
+		defer f.Close()
+
+		header := make([]byte, 4)
+		n, _ := f.ReadAt(header, 0)
+		if !archive.IsZip(header[:n]) {
+			uploads = append(uploads, partFile(file))
+			continue
+		}
+
+		// the limits apply to the batch as a whole
+		remaining := limits
+		remaining.MaxEntries -= len(uploads)
+		for _, upload := range uploads {
+			remaining.MaxTotalSize -= upload.size
+		}
+
+		entries, err := archive.ReadZip(f, file.Size, remaining)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": fmt.Sprintf("Invalid archive %s: %v", file.Filename, err),
+			})
+		}
+
+		for _, entry := range entries {
+			uploads = append(uploads, uploadedFile{
+				name: path.Join(file.Filename, entry.Name),
+				size: entry.Size,
+				open: entry.Open,
+			})
+		}
+	}
+
+	if len(uploads) > limits.MaxEntries {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("Too many files. At most %d are allowed per batch", limits.MaxEntries),
+		})
+	}
+
+	req := &schema.CreateBatchRequest{Targets: targets}
+	accepted := 0
+	for _, upload := range uploads {
+		entry := h.batchEntry(upload, targets)
+		if entry.Conversion != nil {
+			accepted++
+		}
+		req.Entries = append(req.Entries, entry)
+	}
+
+	if accepted == 0 {
+		rejected := make([]schema.BatchEntryResponse, len(req.Entries))
+		for i, entry := range req.Entries {
+			rejected[i] = schema.BatchEntryResponse{Name: entry.Name, Error: entry.Error}
+		}
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "No convertible files in batch",
+			"entries": rejected,
+		})
+	}
+
+	batch, err := h.conversionService.CreateBatch(context.Background(), req)
+	if err != nil {
+		if err.Error() == "service temporarily unavailable" {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error": "Service temporarily unavailable. Please try again later.",
+			})
+		}
+
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create batch",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(batch)
+}
+
+// GetBatchByID handles fetching a batch with the status of its entries and conversions.
+func (h *ConversionHandlerManager) GetBatchByID(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid ID format. Must be a valid MongoDB ObjectID",
+		})
+	}
+
+	batch, err := h.conversionService.GetBatchByID(context.Background(), objectID.Hex())
+	if err != nil {
+		if errors.Is(err, fiber.ErrNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Batch not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch batch",
+		})
+	}
+
+	return c.JSON(batch)
+}
+
+// batchEntry runs the upload checks of CreateConversion on one file of a batch. Files that
+// fail them are returned with the reason instead of a conversion request.
+func (h *ConversionHandlerManager) batchEntry(upload uploadedFile, targets []schema.ConversionTarget) schema.BatchEntryRequest {
+	entry := schema.BatchEntryRequest{Name: upload.name}
+
+	sourceFormat, err := detectFormat(upload)
+	if err != nil {
+		entry.Error = "Failed to read uploaded file"
+		return entry
+	}
+	if sourceFormat == "" {
+		entry.Error = unrecognisedFileMessage
+		return entry
+	}
+
+	problems, err := checkStructure(upload, sourceFormat)
+	if err != nil {
+		entry.Error = "Failed to read uploaded file"
+		return entry
+	}
+	if len(problems) > 0 {
+		entry.Error = "File failed structural validation: " + problems[0].Message
+		return entry
+	}
+
+	if err := h.checkSupported(sourceFormat, targets); err != nil {
+		entry.Error = err.Error()
+		return entry
+	}
+
+	entry.Conversion = &schema.CreateConversionRequest{
+		Targets:      targets,
+		FileSize:     upload.size,
+		FileName:     path.Base(upload.name),
+		SourceFormat: sourceFormat,
+		Open:         upload.open,
+	}
+	return entry
+}
+
+// GetArchiveByBatchID handles downloading the files of every completed conversion of a batch as one ZIP archive.
+func (h *ConversionHandlerManager) GetArchiveByBatchID(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid ID format. Must be a valid MongoDB ObjectID",
+		})
+	}
+
+	download, err := h.conversionService.ArchiveBatch(context.Background(), objectID.Hex())
+	if err != nil {
+		if errors.Is(err, fiber.ErrNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Batch not found",
+			})
+		}
+		if errors.Is(err, service.ErrArchiveUnavailable) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "No conversion of the batch has completed yet",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to prepare archive",
+		})
+	}
+
+	return streamArchive(c, download)
+}
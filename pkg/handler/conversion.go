@@ -1,15 +1,23 @@
 package handler
 
 import (
+	"bufio"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"path/filepath"
+	"io"
+	"mime/multipart"
+	"slices"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/wildan3105/converto/pkg/api/schema"
+	"github.com/wildan3105/converto/pkg/converter"
 	"github.com/wildan3105/converto/pkg/domain"
+	"github.com/wildan3105/converto/pkg/format"
 	"github.com/wildan3105/converto/pkg/service"
+	"github.com/wildan3105/converto/pkg/validation"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -18,7 +26,15 @@ type ConversionHandler interface {
 	CreateConversion(c *fiber.Ctx) error
 	GetConversions(c *fiber.Ctx) error
 	GetConversionByID(c *fiber.Ctx) error
+	CancelConversion(c *fiber.Ctx) error
+	RetryConversion(c *fiber.Ctx) error
 	GetFileByConversionId(c *fiber.Ctx) error
+	GetAnalysisByConversionID(c *fiber.Ctx) error
+	GetUploadByID(c *fiber.Ctx) error
+	CreateBatch(c *fiber.Ctx) error
+	GetBatchByID(c *fiber.Ctx) error
+	GetArchiveByConversionID(c *fiber.Ctx) error
+	GetArchiveByBatchID(c *fiber.Ctx) error
 }
 
 // ConversionHandlerManager implements the ConversionHandler interface.
@@ -33,8 +49,11 @@ func NewConversionHandler(service service.ConversionService) *ConversionHandlerM
 	}
 }
 
-// CreateConversion handles the creation of a new conversion job.
-// Validates the input, checks file type and format, and triggers the conversion service.
+// CreateConversion handles the creation of new conversion jobs.
+// Validates the input, detects the source format from the file content, rejects
+// structurally broken files with 422 and checks every source/target pair before
+// triggering the conversion service. Several target formats may be requested,
+// either by repeating target_format or as a comma-separated list.
 func (h *ConversionHandlerManager) CreateConversion(c *fiber.Ctx) error {
 	req := new(schema.CreateConversionRequest)
 	if err := c.BodyParser(req); err != nil {
@@ -55,41 +74,56 @@ func (h *ConversionHandlerManager) CreateConversion(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "File is required"})
 	}
 	if len(files) > 1 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Only one file is allowed"})
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Only one file is allowed. Use /api/v1/batches to convert several files"})
 	}
 
 	file := files[0]
 	req.File = file
+	upload := partFile(file)
 
-	fileName := file.Filename
-	if filepath.Ext(fileName) != ".shapr" {
+	sourceFormat, err := detectFormat(upload)
+	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid file type. Only .shapr files are allowed",
+			"error": "Failed to read uploaded file",
+		})
+	}
+	if sourceFormat == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": unrecognisedFileMessage,
 		})
 	}
 
-	req.FileName = fileName
-
-	targetFormat := c.FormValue("target_format")
-	if targetFormat == "" {
+	problems, err := checkStructure(upload, sourceFormat)
+	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Target format is required",
+			"error": "Failed to read uploaded file",
 		})
 	}
+	if len(problems) > 0 {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error":    "File failed structural validation",
+			"problems": problems,
+		})
+	}
+
+	req.FileName = file.Filename
+	req.SourceFormat = sourceFormat
+	req.Open = upload.open
 
-	allowedFormats := map[string]bool{
-		".step": true,
-		".iges": true,
-		".stl":  true,
-		".obj":  true,
+	targets, err := h.requestTargets(form)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
 	}
 
-	if !allowedFormats[targetFormat] {
+	if err := h.checkSupported(sourceFormat, targets); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid target format. Allowed formats are: .step, .iges, .stl, .obj",
+			"error": err.Error(),
 		})
 	}
 
+	req.Targets = targets
 	req.FileSize = file.Size
 
 	conversion, err := h.conversionService.CreateConversion(context.Background(), req)
@@ -114,7 +148,7 @@ func (h *ConversionHandlerManager) GetConversions(c *fiber.Ctx) error {
 
 	if status != "" && !isValidConversionStatus(status) {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid status. Must be one of 'pending', 'in_progress', 'completed', 'failed'",
+			"error": "Invalid status. Must be one of 'pending', 'in_progress', 'completed', 'failed', 'cancelled'",
 		})
 	}
 
@@ -167,20 +201,155 @@ func (h *ConversionHandlerManager) GetConversionByID(c *fiber.Ctx) error {
 	return c.JSON(conversion)
 }
 
-// GetFileByConversionId handles fetching a file associated with a specific conversion
+// CancelConversion handles cancelling a conversion. Pending conversions are cancelled at once;
+// running conversions are asked to stop and report the request until the worker has stopped.
+func (h *ConversionHandlerManager) CancelConversion(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid ID format. Must be a valid MongoDB ObjectID",
+		})
+	}
+
+	conversion, err := h.conversionService.CancelConversion(context.Background(), objectID.Hex())
+	if err != nil {
+		if errors.Is(err, fiber.ErrNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Conversion not found",
+			})
+		}
+		if errors.Is(err, service.ErrConversionFinished) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "Conversion has already finished",
+			})
+		}
+		if err.Error() == "service temporarily unavailable" {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error": "Service temporarily unavailable. Please try again later.",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to cancel conversion",
+		})
+	}
+
+	if conversion.Status != domain.ConversionCancelled {
+		return c.Status(fiber.StatusAccepted).JSON(conversion)
+	}
+	return c.JSON(conversion)
+}
+
+// RetryConversion handles running a failed conversion again.
+func (h *ConversionHandlerManager) RetryConversion(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid ID format. Must be a valid MongoDB ObjectID",
+		})
+	}
+
+	conversion, err := h.conversionService.RetryConversion(context.Background(), objectID.Hex())
+	if err != nil {
+		if errors.Is(err, fiber.ErrNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Conversion not found",
+			})
+		}
+		if errors.Is(err, service.ErrConversionNotFailed) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "Only failed conversions can be retried",
+			})
+		}
+		if err.Error() == "service temporarily unavailable" {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error": "Service temporarily unavailable. Please try again later.",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retry conversion",
+		})
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(conversion)
+}
+
+// GetAnalysisByConversionID handles fetching the geometry analysis of a conversion's model.
+func (h *ConversionHandlerManager) GetAnalysisByConversionID(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid ID format. Must be a valid MongoDB ObjectID",
+		})
+	}
+
+	analysis, err := h.conversionService.GetAnalysisByConversionID(context.Background(), objectID.Hex())
+	if err != nil {
+		if errors.Is(err, fiber.ErrNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Conversion not found",
+			})
+		}
+		if errors.Is(err, service.ErrAnalysisUnavailable) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Analysis not available yet",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch analysis",
+		})
+	}
+
+	return c.JSON(analysis)
+}
+
+// GetUploadByID handles fetching an upload with the combined status of its conversions.
+func (h *ConversionHandlerManager) GetUploadByID(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid ID format. Must be a valid MongoDB ObjectID",
+		})
+	}
+
+	upload, err := h.conversionService.GetUploadByID(context.Background(), objectID.Hex())
+	if err != nil {
+		if errors.Is(err, fiber.ErrNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Upload not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch upload",
+		})
+	}
+
+	return c.JSON(upload)
+}
+
+// GetFileByConversionId handles fetching a file associated with a specific conversion.
+// For converted files, the optional name query selects one artifact of a multi-file output.
 func (h *ConversionHandlerManager) GetFileByConversionId(c *fiber.Ctx) error {
 	id := c.Params("id")
 	fileType := c.Query("type")
+	name := c.Query("name")
 
 	if fileType == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "File type query is required. Must be one of 'type=original', 'type=converted'",
+			"error": "File type query is required. Must be one of 'type=original', 'type=converted', 'type=preview'",
 		})
 	}
 
 	if !isValidFileType(fileType) {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid file type. Must be one of 'original', 'converted'",
+			"error": "Invalid file type. Must be one of 'original', 'converted', 'preview'",
 		})
 	}
 
@@ -191,7 +360,13 @@ func (h *ConversionHandlerManager) GetFileByConversionId(c *fiber.Ctx) error {
 		})
 	}
 
-	fileDetails, err := h.conversionService.GetFileByConversionIdAndType(context.Background(), objectID.Hex(), fileType)
+	if name != "" && domain.FileCategory(fileType) != domain.FileCategoryConverted {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "The name query is only supported with 'type=converted'",
+		})
+	}
+
+	fileDetails, err := h.conversionService.GetFileByConversionIdAndType(context.Background(), objectID.Hex(), fileType, name)
 	if err != nil {
 		if errors.Is(err, fiber.ErrNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -204,15 +379,225 @@ func (h *ConversionHandlerManager) GetFileByConversionId(c *fiber.Ctx) error {
 	}
 
 	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", fileDetails.FileName))
-	c.Set("Content-Type", "application/octet-stream")
+	c.Set("Content-Type", fileDetails.ContentType)
 
 	return c.SendFile(fileDetails.Path, false)
 }
 
+// requestTargets reads the requested target formats and their options from the form and validates
+// them. The returned error is a *fiber.Error whose message can be shown to the client.
+func (h *ConversionHandlerManager) requestTargets(form *multipart.Form) ([]schema.ConversionTarget, error) {
+	targetFormats := parseTargetFormats(form.Value["target_format"])
+	if len(targetFormats) == 0 {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Target format is required")
+	}
+
+	allowedFormats := h.conversionService.SupportedTargetFormats()
+
+	for _, targetFormat := range targetFormats {
+		if !slices.Contains(allowedFormats, targetFormat) {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid target format. Allowed formats are: "+strings.Join(allowedFormats, ", "))
+		}
+	}
+
+	var options map[string]any
+	if rawOptions := form.Value["options"]; len(rawOptions) > 0 && rawOptions[0] != "" {
+		if err := json.Unmarshal([]byte(rawOptions[0]), &options); err != nil || options == nil {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid options. Must be a JSON object")
+		}
+	}
+
+	targets, err := conversionTargets(targetFormats, options)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid options: "+err.Error())
+	}
+
+	for _, target := range targets {
+		if err := h.conversionService.ValidateOptions(target.Format, target.Options); err != nil {
+			message := "Invalid options: "
+			if len(targets) > 1 {
+				message = fmt.Sprintf("Invalid options for %s: ", target.Format)
+			}
+			return nil, fiber.NewError(fiber.StatusBadRequest, message+strings.Join(optionProblems(err), "; "))
+		}
+	}
+
+	return targets, nil
+}
+
+// checkSupported returns an error naming the first target format the source format cannot be converted to
+func (h *ConversionHandlerManager) checkSupported(sourceFormat string, targets []schema.ConversionTarget) error {
+	for _, target := range targets {
+		if !h.conversionService.SupportsConversion(sourceFormat, target.Format) {
+			return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Conversion from %s to %s is not supported", sourceFormat, target.Format))
+		}
+	}
+	return nil
+}
+
+// parseTargetFormats collects the requested target formats from repeated or comma-separated
+// values, dropping blanks and duplicates while keeping the order of first appearance
+func parseTargetFormats(values []string) []string {
+	var formats []string
+	for _, value := range values {
+		for _, targetFormat := range strings.Split(value, ",") {
+			targetFormat = strings.TrimSpace(targetFormat)
+			if targetFormat != "" && !slices.Contains(formats, targetFormat) {
+				formats = append(formats, targetFormat)
+			}
+		}
+	}
+	return formats
+}
+
+// conversionTargets pairs every target format with its options. The options object either applies
+// to every target, or is keyed by target format (e.g. {".stl": {"encoding": "ascii"}}) to give each
+// target its own options.
+func conversionTargets(targetFormats []string, options map[string]any) ([]schema.ConversionTarget, error) {
+	perTarget := len(options) > 0
+	for key := range options {
+		if !strings.HasPrefix(key, ".") {
+			perTarget = false
+			break
+		}
+	}
+
+	targets := make([]schema.ConversionTarget, len(targetFormats))
+	for i, targetFormat := range targetFormats {
+		targets[i] = schema.ConversionTarget{Format: targetFormat, Options: options}
+	}
+
+	if !perTarget {
+		return targets, nil
+	}
+
+	for key, value := range options {
+		if !slices.Contains(targetFormats, key) {
+			return nil, fmt.Errorf("%s is not a requested target format", key)
+		}
+		if _, ok := value.(map[string]any); !ok {
+			return nil, fmt.Errorf("options for %s must be a JSON object", key)
+		}
+	}
+
+	for i := range targets {
+		targetOptions, _ := options[targets[i].Format].(map[string]any)
+		targets[i].Options = targetOptions
+	}
+
+	return targets, nil
+}
+
+// GetArchiveByConversionID handles downloading every file produced by a conversion as one ZIP archive.
+func (h *ConversionHandlerManager) GetArchiveByConversionID(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid ID format. Must be a valid MongoDB ObjectID",
+		})
+	}
+
+	download, err := h.conversionService.ArchiveConversion(context.Background(), objectID.Hex())
+	if err != nil {
+		if errors.Is(err, fiber.ErrNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Conversion not found",
+			})
+		}
+		if errors.Is(err, service.ErrArchiveUnavailable) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "Conversion has not completed yet",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to prepare archive",
+		})
+	}
+
+	return streamArchive(c, download)
+}
+
+// streamArchive sends a ZIP archive as it is produced, without staging it on disk. Once
+// streaming started the status cannot change, so a failure truncates the archive instead.
+func streamArchive(c *fiber.Ctx, download schema.ArchiveDownload) error {
+	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", download.FileName))
+	c.Set("Content-Type", "application/zip")
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		if err := download.Write(w); err != nil {
+			log.Error("Failed to stream archive %s: %v", download.FileName, err)
+			return
+		}
+		if err := w.Flush(); err != nil {
+			log.Warn("Failed to flush archive %s: %v", download.FileName, err)
+		}
+	})
+
+	return nil
+}
+
+// optionProblems lists the individual problems of an options validation error
+func optionProblems(err error) []string {
+	var optionsErr *converter.OptionsError
+	if errors.As(err, &optionsErr) {
+		return optionsErr.Problems
+	}
+	return []string{err.Error()}
+}
+
+// unrecognisedFileMessage is returned for uploads whose format cannot be detected
+const unrecognisedFileMessage = "Unrecognised file type. Supported inputs are .shapr, .stl, .obj, .ply, .gltf and .glb"
+
+// uploadedFile is a model file received in a request: a multipart part or an entry of an uploaded archive
+type uploadedFile struct {
+	name string
+	size int64
+	open func() (io.ReadCloser, error)
+}
+
+// partFile wraps a multipart part as an uploadedFile
+func partFile(file *multipart.FileHeader) uploadedFile {
+	return uploadedFile{
+		name: file.Filename,
+		size: file.Size,
+		open: func() (io.ReadCloser, error) { return file.Open() },
+	}
+}
+
+// detectFormat identifies the uploaded file's format from its leading bytes
+func detectFormat(file uploadedFile) (string, error) {
+	f, err := file.open()
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	header := make([]byte, format.SniffSize)
+	n, err := io.ReadFull(f, header)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+
+	return format.Detect(header[:n], file.size), nil
+}
+
+// checkStructure verifies the uploaded file's container structure before it is stored
+func checkStructure(file uploadedFile, sourceFormat string) ([]domain.ValidationProblem, error) {
+	f, err := file.open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return validation.Structure(f, sourceFormat, file.size), nil
+}
+
 // isValidConversionStatus checks if the provided status is a valid ConversionStatus
 func isValidConversionStatus(status string) bool {
 	switch domain.ConversionStatus(status) {
-	case domain.ConversionPending, domain.ConversionInProgress, domain.ConversionCompleted, domain.ConversionFailed:
+	case domain.ConversionPending, domain.ConversionInProgress, domain.ConversionCompleted, domain.ConversionFailed, domain.ConversionCancelled:
 		return true
 	default:
 		return false
@@ -222,7 +607,7 @@ func isValidConversionStatus(status string) bool {
 // isValidFileType checks if the provided file type is a valid FileCategory
 func isValidFileType(fileType string) bool {
 	switch domain.FileCategory(fileType) {
-	case domain.FileCategoryConverted, domain.FileCategoryOriginal:
+	case domain.FileCategoryConverted, domain.FileCategoryOriginal, domain.FileCategoryPreview:
 		return true
 	default:
 		return false
@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/wildan3105/converto/pkg/service"
+)
+
+// DeadLetterHandler serves the administration of the dead-letter queue
+type DeadLetterHandler struct {
+	deadLetterService service.DeadLetterService
+}
+
+// NewDeadLetterHandler creates a new DeadLetterHandler
+func NewDeadLetterHandler(service service.DeadLetterService) *DeadLetterHandler {
+	return &DeadLetterHandler{
+		deadLetterService: service,
+	}
+}
+
+// ListDeadLetters handles listing the oldest messages of the dead-letter queue.
+func (h *DeadLetterHandler) ListDeadLetters(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", 20)
+	if limit < 1 || limit > 100 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid limit value. Must be below 101 and above 0",
+		})
+	}
+
+	letters, err := h.deadLetterService.ListDeadLetters(context.Background(), limit)
+	if err != nil {
+		log.Error("Failed to list dead letters: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch dead letters",
+		})
+	}
+
+	return c.JSON(letters)
+}
+
+// GetDeadLetter handles inspecting a message of the dead-letter queue with its headers.
+func (h *DeadLetterHandler) GetDeadLetter(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if !isValidDeadLetterID(id) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid ID format. Must be a valid UUID",
+		})
+	}
+
+	letter, err := h.deadLetterService.GetDeadLetter(context.Background(), id)
+	if err != nil {
+		return deadLetterFailure(c, err, "Failed to fetch dead letter")
+	}
+
+	return c.JSON(letter)
+}
+
+// ReplayDeadLetter handles processing a message of the dead-letter queue again.
+func (h *DeadLetterHandler) ReplayDeadLetter(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if !isValidDeadLetterID(id) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid ID format. Must be a valid UUID",
+		})
+	}
+
+	if err := h.deadLetterService.ReplayDeadLetter(context.Background(), id); err != nil {
+		if errors.Is(err, service.ErrDeadLetterStale) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "The conversion of this message is no longer failed. Delete the message instead",
+			})
+		}
+		return deadLetterFailure(c, err, "Failed to replay dead letter")
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"message": "Dead letter replayed successfully",
+	})
+}
+
+// DeleteDeadLetter handles removing a message of the dead-letter queue without processing it.
+func (h *DeadLetterHandler) DeleteDeadLetter(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if !isValidDeadLetterID(id) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid ID format. Must be a valid UUID",
+		})
+	}
+
+	if err := h.deadLetterService.DeleteDeadLetter(context.Background(), id); err != nil {
+		return deadLetterFailure(c, err, "Failed to delete dead letter")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// PurgeDeadLetters handles removing every message of the dead-letter queue.
+func (h *DeadLetterHandler) PurgeDeadLetters(c *fiber.Ctx) error {
+	purged, err := h.deadLetterService.PurgeDeadLetters(context.Background())
+	if err != nil {
+		log.Error("Failed to purge dead letters: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to purge dead letters",
+		})
+	}
+
+	return c.JSON(purged)
+}
+
+// isValidDeadLetterID checks that a dead letter ID is a UUID, as assigned when dead-lettering
+func isValidDeadLetterID(id string) bool {
+	_, err := uuid.Parse(id)
+	return err == nil
+}
+
+// deadLetterFailure responds to a failed dead-letter operation
+func deadLetterFailure(c *fiber.Ctx, err error, message string) error {
+	if errors.Is(err, fiber.ErrNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Dead letter not found",
+		})
+	}
+
+	log.Error("%s: %v", message, err)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": message,
+	})
+}
@@ -3,19 +3,18 @@ package filestorage
 import (
 	"fmt"
 	"io"
-	"mime/multipart"
 	"os"
 	"path/filepath"
-	"time"
 
 	"github.com/wildan3105/converto/pkg/domain"
 )
 
 // FileStorage interface to abstract file storage operations
 type FileStorage interface {
-	SaveFile(file *multipart.FileHeader, fileCategory domain.FileCategory, id string, destPath string) (string, error)
-	CopyFile(srcPath, destPath string, progressCb func(progress int)) (string, error)
+	OpenFile(path string) (io.ReadSeekCloser, error)
+	CreateFile(fileCategory domain.FileCategory, id string, fileName string) (io.WriteCloser, string, error)
 	GetFullPath(fileCategory domain.FileCategory, id string, fileName string) string
+	DeleteFiles(fileCategory domain.FileCategory, id string) error
 }
 
 // LocalFileStorage is an implementation of FileStorage using local filesystem
@@ -28,95 +27,47 @@ func NewLocalFileStorage(baseDir string) *LocalFileStorage {
 	return &LocalFileStorage{baseDir: baseDir}
 }
 
-// SaveFile saves the uploaded file to the specified path or default directory
-func (l *LocalFileStorage) SaveFile(file *multipart.FileHeader, fileCategory domain.FileCategory, id string, destPath string) (string, error) {
-	src, err := file.Open()
+// OpenFile opens a stored file for reading
+func (l *LocalFileStorage) OpenFile(path string) (io.ReadSeekCloser, error) {
+	file, err := os.Open(path)
 	if err != nil {
-		return "", fmt.Errorf("failed to open file: %w", err)
+		return nil, fmt.Errorf("failed to open file: %w", err)
 	}
-	defer src.Close()
 
-	if destPath == "" {
-		destPath = filepath.Join(l.baseDir, fmt.Sprintf("%d_%s", time.Now().UnixNano(), file.Filename))
-	} else {
-		destPath = filepath.Join(l.baseDir+string(fileCategory)+"/"+id, destPath)
-	}
-
-	dir := filepath.Dir(destPath)
-	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
-		return "", fmt.Errorf("failed to create directory: %w", err)
-	}
-
-	dest, err := os.Create(destPath)
-	if err != nil {
-		return "", fmt.Errorf("failed to create destination file: %w", err)
-	}
-	defer dest.Close()
-
-	if _, err := io.Copy(dest, src); err != nil {
-		return "", fmt.Errorf("failed to save file: %w", err)
-	}
-
-	return destPath, nil
+	return file, nil
 }
 
-// CopyFile copies a file from srcPath to destPath and updates progress
-func (l *LocalFileStorage) CopyFile(srcPath, destPath string, progressCb func(progress int)) (string, error) {
-	src, err := os.Open(srcPath)
-	if err != nil {
-		return "", fmt.Errorf("failed to open source file: %w", err)
-	}
-	defer src.Close()
+// CreateFile creates (or truncates) a file for the given category and ID and returns it with its full path
+func (l *LocalFileStorage) CreateFile(fileCategory domain.FileCategory, id string, fileName string) (io.WriteCloser, string, error) {
+	destPath := l.GetFullPath(fileCategory, id, fileName)
 
 	dir := filepath.Dir(destPath)
 	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
-		return "", fmt.Errorf("failed to create directory: %w", err)
+		return nil, "", fmt.Errorf("failed to create directory: %w", err)
 	}
 
 	dest, err := os.Create(destPath)
 	if err != nil {
-		return "", fmt.Errorf("failed to create destination file: %w", err)
+		return nil, "", fmt.Errorf("failed to create destination file: %w", err)
 	}
-	defer dest.Close()
 
-	srcInfo, err := src.Stat()
-	if err != nil {
-		return "", err
-	}
-
-	totalBytes := srcInfo.Size()
-	buffer := make([]byte, 1024*1024) // 1 MB buffer
-	var copiedBytes int64
-	lastReportedProgress := 0
-
-	for {
-		n, err := src.Read(buffer)
-		if n > 0 {
-			if _, writeErr := dest.Write(buffer[:n]); writeErr != nil {
-				return "", writeErr
-			}
-			copiedBytes += int64(n)
-			progress := int((copiedBytes * 100) / totalBytes)
-
-			if progress >= lastReportedProgress+10 {
-				progressCb(progress)
-				lastReportedProgress = progress
-			}
-		}
-
-		if err != nil {
-			if err == io.EOF {
-				break
-			}
-			return "", err
-		}
-	}
-
-	progressCb(100)
-	return destPath, nil
+	return dest, destPath, nil
 }
 
 // GetFullPath constructs the full path for a file given its category and name
 func (l *LocalFileStorage) GetFullPath(fileCategory domain.FileCategory, id string, fileName string) string {
 	return filepath.Join(l.baseDir, string(fileCategory), id, fileName)
 }
+
+// DeleteFiles removes every file stored for the given category and ID
+func (l *LocalFileStorage) DeleteFiles(fileCategory domain.FileCategory, id string) error {
+	if id == "" {
+		return fmt.Errorf("failed to delete files: empty ID")
+	}
+
+	if err := os.RemoveAll(filepath.Join(l.baseDir, string(fileCategory), id)); err != nil {
+		return fmt.Errorf("failed to delete files: %w", err)
+	}
+
+	return nil
+}
@@ -128,6 +128,7 @@ func (cm *ConnectionManager) Close() {
 }
 
 // Ping checks if the connection to RabbitMQ is still open and functioning correctly.
+// It inspects the job queue, which leaves the channel's settings, such as its prefetch count, untouched.
 func (cm *ConnectionManager) Ping() error {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
@@ -136,7 +137,7 @@ func (cm *ConnectionManager) Ping() error {
 		return errors.New("RabbitMQ connection is closed")
 	}
 
-	err := cm.channel.Qos(1, 0, false)
+	_, err := cm.channel.QueueDeclarePassive(config.AppConfig.RabbitMQQueueName, true, false, false, false, nil)
 	if err != nil {
 		log.Warn("RabbitMQ connection ping failed: %v", err)
 		return err
@@ -145,7 +146,9 @@ func (cm *ConnectionManager) Ping() error {
 	return nil
 }
 
-// SetupExchangeQueueBinding sets up an exchange, queue, and binding with the routing key.
+// SetupExchangeQueueBinding sets up an exchange, queue, and binding with the routing key,
+// along with the retry queues delaying the redelivery of failed jobs and the dead-letter
+// exchange and queue holding the messages that cannot be processed.
 func (cm *ConnectionManager) SetupExchangeQueueBinding(exchangeName, routingKey, queueName string) error {
 	if err := cm.channel.ExchangeDeclare(exchangeName, "direct", true, false, false, false, nil); err != nil {
 		log.Warn("Failed to declare exchange: %v", err)
@@ -162,6 +165,15 @@ func (cm *ConnectionManager) SetupExchangeQueueBinding(exchangeName, routingKey,
 		return err
 	}
 
+	delays := RetryDelays(config.AppConfig.RetryMaxAttempts, config.AppConfig.RetryBaseDelay, config.AppConfig.RetryMaxDelay)
+	if err := cm.setupRetryQueues(exchangeName, routingKey, queueName, delays); err != nil {
+		return err
+	}
+
+	if err := cm.setupDeadLetter(exchangeName, routingKey, queueName); err != nil {
+		return err
+	}
+
 	log.Info("Set up exchange %s, queue %s, with routing key %s", exchangeName, queueName, routingKey)
 	return nil
 }
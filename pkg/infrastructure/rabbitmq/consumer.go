@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"fmt"
 
+	"github.com/rabbitmq/amqp091-go"
+	config "github.com/wildan3105/converto/configs"
 	"github.com/wildan3105/converto/pkg/api/schema"
 )
 
@@ -20,9 +22,33 @@ func NewConsumer(cm *ConnectionManager) *Consumer {
 	}
 }
 
-func (c *Consumer) Consume(ctx context.Context, queueName string) (<-chan schema.ConversionEvent, error) {
+// Job is a conversion job received from a queue. It must be acknowledged once it has been processed,
+// or negatively acknowledged to be delivered again.
+type Job struct {
+	Event    schema.ConversionEvent
+	delivery amqp091.Delivery
+}
+
+// Ack acknowledges the job, removing it from the queue
+func (j Job) Ack() error {
+	return j.delivery.Ack(false)
+}
+
+// Nack negatively acknowledges the job; with requeue, it is delivered again
+func (j Job) Nack(requeue bool) error {
+	return j.delivery.Nack(false, requeue)
+}
+
+// Consume delivers the jobs of a queue without acknowledging them, so that the jobs of a worker that
+// stops before acknowledging them are delivered again. At most prefetch jobs are delivered to the
+// consumer before being acknowledged. Messages that cannot be decoded are moved to the dead-letter queue.
+func (c *Consumer) Consume(ctx context.Context, queueName string, prefetch int) (<-chan Job, error) {
 	channel := c.conn.GetChannel()
 
+	if err := channel.Qos(prefetch, 0, false); err != nil {
+		return nil, fmt.Errorf("failed to set prefetch count: %w", err)
+	}
+
 	msgs, err := channel.Consume(
 		queueName,
 		"",
@@ -37,7 +63,7 @@ func (c *Consumer) Consume(ctx context.Context, queueName string) (<-chan schema
 		return nil, fmt.Errorf("failed to consume messages: %w", err)
 	}
 
-	jobChan := make(chan schema.ConversionEvent)
+	jobChan := make(chan Job)
 
 	go func() {
 		defer close(jobChan)
@@ -52,22 +78,39 @@ func (c *Consumer) Consume(ctx context.Context, queueName string) (<-chan schema
 					return
 				}
 
-				job := schema.ConversionEvent{}
-				if err := json.Unmarshal(msg.Body, &job); err != nil {
+				job := Job{delivery: msg}
+				if err := json.Unmarshal(msg.Body, &job.Event); err != nil {
 					log.Warn("Failed to unmarshal job: %v", err)
-					_ = msg.Nack(false, true)
+					c.deadLetter(ctx, channel, msg, fmt.Errorf("failed to unmarshal job: %w", err))
 					continue
 				}
 
-				jobChan <- job
-
-				if err := msg.Ack(false); err != nil {
-					log.Warn("Failed to ack message: %v", err)
+				select {
+				case jobChan <- job:
+				case <-ctx.Done():
+					_ = msg.Nack(false, true)
+					log.Info("Context cancelled, stopping message consumption...")
+					return
 				}
-				log.Info("Processed job %v", job)
 			}
 		}
 	}()
 
 	return jobChan, nil
 }
+
+// deadLetter moves a message that cannot be processed to the dead-letter queue. Should that fail,
+// the message is requeued rather than lost.
+func (c *Consumer) deadLetter(ctx context.Context, channel *amqp091.Channel, msg amqp091.Delivery, cause error) {
+	// the exchange the message came through may be a retry queue's, so the configured one is used
+	err := publishDeadLetter(ctx, channel, config.AppConfig.RabbitMQExchangeName, config.AppConfig.RabbitMQRoutingKey, msg.Body, msg.ContentType, DeadLetterPoison, cause, nil)
+	if err != nil {
+		log.Error("Failed to dead-letter message: %v", err)
+		_ = msg.Nack(false, true)
+		return
+	}
+
+	if err := msg.Ack(false); err != nil {
+		log.Warn("Failed to ack dead-lettered message: %v", err)
+	}
+}
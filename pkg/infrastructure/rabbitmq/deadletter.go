@@ -0,0 +1,292 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// Headers describing why a message was dead-lettered
+const (
+	HeaderDeadLetterReason = "x-dead-letter-reason"
+	HeaderDeadLetterError  = "x-dead-letter-error"
+	HeaderDeadLetteredAt   = "x-dead-lettered-at"
+	HeaderAttempts         = "x-attempts"
+)
+
+// DeadLetterReason tells why a message was dead-lettered
+type DeadLetterReason string
+
+const (
+	// DeadLetterPoison marks a message that could not be decoded
+	DeadLetterPoison DeadLetterReason = "poison"
+	// DeadLetterExhausted marks a job that failed on every attempt it was allowed
+	DeadLetterExhausted DeadLetterReason = "exhausted"
+	// DeadLetterPanicked marks a job whose handling crashed the worker
+	DeadLetterPanicked DeadLetterReason = "panicked"
+)
+
+// ErrDeadLetterNotFound is returned when no dead-lettered message has the requested ID
+var ErrDeadLetterNotFound = errors.New("dead letter not found")
+
+// DeadLetterExchangeName returns the name of the exchange receiving the dead letters of exchangeName
+func DeadLetterExchangeName(exchangeName string) string {
+	return exchangeName + ".dlx"
+}
+
+// DeadLetterQueueName returns the name of the queue holding the dead letters of queueName
+func DeadLetterQueueName(queueName string) string {
+	return queueName + ".dead"
+}
+
+// setupDeadLetter declares the dead-letter exchange of exchangeName and binds the dead-letter queue
+// of queueName to it with the routing key of the job queue
+func (cm *ConnectionManager) setupDeadLetter(exchangeName, routingKey, queueName string) error {
+	dlx := DeadLetterExchangeName(exchangeName)
+	dlq := DeadLetterQueueName(queueName)
+
+	if err := cm.channel.ExchangeDeclare(dlx, "direct", true, false, false, false, nil); err != nil {
+		log.Warn("Failed to declare dead-letter exchange: %v", err)
+		return err
+	}
+
+	if _, err := cm.channel.QueueDeclare(dlq, true, false, false, false, nil); err != nil {
+		log.Warn("Failed to declare dead-letter queue: %v", err)
+		return err
+	}
+
+	if err := cm.channel.QueueBind(dlq, routingKey, dlx, false, nil); err != nil {
+		log.Warn("Failed to bind dead-letter queue: %v", err)
+		return err
+	}
+
+	log.Info("Set up dead-letter exchange %s and queue %s", dlx, dlq)
+	return nil
+}
+
+// publishDeadLetter publishes a message body to the dead-letter exchange of exchangeName, with the
+// reason and error in its headers. Extra headers are added to them.
+func publishDeadLetter(ctx context.Context, channel *amqp091.Channel, exchangeName, routingKey string, body []byte, contentType string, reason DeadLetterReason, cause error, extra amqp091.Table) error {
+	headers := amqp091.Table{
+		HeaderDeadLetterReason: string(reason),
+		HeaderDeadLetterError:  cause.Error(),
+		HeaderDeadLetteredAt:   time.Now().UTC().Format(time.RFC3339),
+	}
+	for key, value := range extra {
+		headers[key] = value
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	err := channel.PublishWithContext(
+		ctx,
+		DeadLetterExchangeName(exchangeName),
+		routingKey,
+		false, // mandatory
+		false, // immediate
+		amqp091.Publishing{
+			MessageId:    uuid.NewString(),
+			DeliveryMode: amqp091.Persistent,
+			ContentType:  contentType,
+			Headers:      headers,
+			Body:         body,
+			Timestamp:    time.Now(),
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to publish dead letter: %w", err)
+	}
+
+	log.Warn("Dead-lettered message (%s): %v", reason, cause)
+	return nil
+}
+
+// DeadLetter is a message held in the dead-letter queue
+type DeadLetter struct {
+	ID             string
+	Reason         DeadLetterReason
+	Error          string
+	DeadLetteredAt time.Time
+	Headers        amqp091.Table
+	ContentType    string
+	Body           []byte
+}
+
+// DeadLetterQueue inspects and drains the dead-letter queue of a job queue. Messages are read
+// without being acknowledged on a dedicated channel, whose closing puts them back in order.
+type DeadLetterQueue struct {
+	conn         *ConnectionManager
+	exchangeName string
+	routingKey   string
+	queueName    string
+}
+
+// NewDeadLetterQueue creates a DeadLetterQueue for the dead letters of the job queue queueName,
+// replayed to exchangeName with routingKey
+func NewDeadLetterQueue(cm *ConnectionManager, exchangeName, routingKey, queueName string) *DeadLetterQueue {
+	return &DeadLetterQueue{
+		conn:         cm,
+		exchangeName: exchangeName,
+		routingKey:   routingKey,
+		queueName:    queueName,
+	}
+}
+
+// List returns up to limit dead letters, oldest first, along with the number of messages in the queue
+func (q *DeadLetterQueue) List(limit int) ([]DeadLetter, int, error) {
+	var letters []DeadLetter
+	total := 0
+
+	err := q.scan(func(msg amqp091.Delivery, remaining int) (bool, error) {
+		if total == 0 {
+			total = remaining + 1
+		}
+		letters = append(letters, toDeadLetter(msg))
+		return len(letters) < limit, nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return letters, total, nil
+}
+
+// Get returns the dead letter with the given ID
+func (q *DeadLetterQueue) Get(id string) (*DeadLetter, error) {
+	var found *DeadLetter
+
+	err := q.scan(func(msg amqp091.Delivery, _ int) (bool, error) {
+		if msg.MessageId != id {
+			return true, nil
+		}
+		letter := toDeadLetter(msg)
+		found = &letter
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if found == nil {
+		return nil, ErrDeadLetterNotFound
+	}
+
+	return found, nil
+}
+
+// Remove deletes the dead letter with the given ID from the queue
+func (q *DeadLetterQueue) Remove(id string) error {
+	removed := false
+
+	err := q.scan(func(msg amqp091.Delivery, _ int) (bool, error) {
+		if msg.MessageId != id {
+			return true, nil
+		}
+		if err := msg.Ack(false); err != nil {
+			return false, fmt.Errorf("failed to remove dead letter: %w", err)
+		}
+		removed = true
+		return false, nil
+	})
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrDeadLetterNotFound
+	}
+
+	return nil
+}
+
+// Republish publishes the body of a dead letter unchanged to the job exchange, then removes the dead letter
+func (q *DeadLetterQueue) Republish(ctx context.Context, id string) error {
+	letter, err := q.Get(id)
+	if err != nil {
+		return err
+	}
+
+	channel, err := q.conn.GetConnection().Channel()
+	if err != nil {
+		return fmt.Errorf("failed to open channel: %w", err)
+	}
+	defer channel.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	err = channel.PublishWithContext(ctx, q.exchangeName, q.routingKey, false, false, amqp091.Publishing{
+		DeliveryMode: amqp091.Persistent,
+		ContentType:  letter.ContentType,
+		Body:         letter.Body,
+		Timestamp:    time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to republish dead letter: %w", err)
+	}
+
+	return q.Remove(id)
+}
+
+// Purge deletes every dead letter and returns how many there were
+func (q *DeadLetterQueue) Purge() (int, error) {
+	channel, err := q.conn.GetConnection().Channel()
+	if err != nil {
+		return 0, fmt.Errorf("failed to open channel: %w", err)
+	}
+	defer channel.Close()
+
+	return channel.QueuePurge(DeadLetterQueueName(q.queueName), false)
+}
+
+// scan reads the dead letters in order and passes each to visit, with the number of messages left
+// after it, until visit returns false or the queue is exhausted. Messages that visit does not
+// acknowledge go back to the queue.
+func (q *DeadLetterQueue) scan(visit func(msg amqp091.Delivery, remaining int) (bool, error)) error {
+	channel, err := q.conn.GetConnection().Channel()
+	if err != nil {
+		return fmt.Errorf("failed to open channel: %w", err)
+	}
+	// closing the channel requeues every message read but not acknowledged
+	defer channel.Close()
+
+	for {
+		msg, ok, err := channel.Get(DeadLetterQueueName(q.queueName), false)
+		if err != nil {
+			return fmt.Errorf("failed to read dead letters: %w", err)
+		}
+		if !ok {
+			return nil
+		}
+
+		more, err := visit(msg, int(msg.MessageCount))
+		if err != nil || !more {
+			return err
+		}
+	}
+}
+
+// toDeadLetter reads the reason headers of a dead-lettered message
+func toDeadLetter(msg amqp091.Delivery) DeadLetter {
+	letter := DeadLetter{
+		ID:          msg.MessageId,
+		Headers:     msg.Headers,
+		ContentType: msg.ContentType,
+		Body:        msg.Body,
+	}
+
+	if reason, ok := msg.Headers[HeaderDeadLetterReason].(string); ok {
+		letter.Reason = DeadLetterReason(reason)
+	}
+	if cause, ok := msg.Headers[HeaderDeadLetterError].(string); ok {
+		letter.Error = cause
+	}
+	if at, ok := msg.Headers[HeaderDeadLetteredAt].(string); ok {
+		letter.DeadLetteredAt, _ = time.Parse(time.RFC3339, at)
+	}
+
+	return letter
+}
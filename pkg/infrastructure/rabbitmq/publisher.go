@@ -30,7 +30,9 @@ func NewPublisher(cm *ConnectionManager) *Publisher {
 	return p
 }
 
-// enableConfirmMode sets the channel into confirm mode and attaches a listener to handle confirmation
+// enableConfirmMode sets the channel into confirm mode and attaches a listener to handle confirmation,
+// and a listener to the mandatory messages the broker could not route. Both listeners live as long as
+// the channel.
 func (p *Publisher) enableConfirmMode() error {
 	err := p.channel.Confirm(false)
 	if err != nil {
@@ -47,11 +49,30 @@ func (p *Publisher) enableConfirmMode() error {
 		}
 	}()
 
+	// messages are told apart by their ID, the job and attempt, and their correlation ID, the conversion
+	go func() {
+		for returned := range p.channel.NotifyReturn(make(chan amqp091.Return)) {
+			log.Warn("Message %s of conversion ID %s returned: %s", returned.MessageId, returned.CorrelationId, returned.ReplyText)
+		}
+	}()
+
 	log.Info("Enabling confirm mode")
 
 	return nil
 }
 
+// jobPublishing builds the message of a conversion job
+func jobPublishing(conversionEvent schema.ConversionEvent, body []byte) amqp091.Publishing {
+	return amqp091.Publishing{
+		MessageId:     fmt.Sprintf("%s/%d", conversionEvent.JobID, conversionEvent.Attempt),
+		CorrelationId: conversionEvent.ConversionID,
+		DeliveryMode:  amqp091.Persistent,
+		ContentType:   "application/json",
+		Body:          body,
+		Timestamp:     time.Now(),
+	}
+}
+
 func (p *Publisher) PublishConversionJob(ctx context.Context, conversionEvent schema.ConversionEvent, exchange, routingKey string) error {
 	jobBytes, err := json.Marshal(conversionEvent)
 	if err != nil {
@@ -69,28 +90,13 @@ func (p *Publisher) PublishConversionJob(ctx context.Context, conversionEvent sc
 	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
-	mandatory := true
-	returnCh := make(chan amqp091.Return)
-	p.channel.NotifyReturn(returnCh)
-
-	go func() {
-		for returned := range returnCh {
-			log.Warn("Message returned: %s", returned.ReplyText)
-		}
-	}()
-
 	err = p.channel.PublishWithContext(
 		ctx,
 		exchange,
 		routingKey,
-		mandatory,
+		true,  // mandatory, unroutable messages are returned to the listener of the channel
 		false, // immediate
-		amqp091.Publishing{
-			DeliveryMode: amqp091.Persistent,
-			ContentType:  "application/json",
-			Body:         jobBytes,
-			Timestamp:    time.Now(),
-		},
+		jobPublishing(conversionEvent, jobBytes),
 	)
 
 	if err != nil {
@@ -107,6 +113,69 @@ func (p *Publisher) PublishConversionJob(ctx context.Context, conversionEvent sc
 	}
 }
 
+// ConfirmTimeout bounds the wait of PublishConversionJobConfirmed for the broker to confirm a message
+const ConfirmTimeout = 30 * time.Second
+
+// PublishConversionJobConfirmed publishes a conversion job and waits until the broker confirms it
+// has taken responsibility for the message. An error means the job may not have been queued.
+func (p *Publisher) PublishConversionJobConfirmed(ctx context.Context, conversionEvent schema.ConversionEvent, exchange, routingKey string) error {
+	jobBytes, err := json.Marshal(conversionEvent)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job: %w", err)
+	}
+
+	if p.channel.IsClosed() {
+		log.Warn("RabbitMQ channel is closed, attempting to re-open it.")
+		if err := p.reOpenChannel(); err != nil {
+			log.Error("Failed to re-open RabbitMQ channel: %v", err)
+			return amqp091.ErrClosed
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, ConfirmTimeout)
+	defer cancel()
+
+	confirmation, err := p.channel.PublishWithDeferredConfirmWithContext(
+		ctx,
+		exchange,
+		routingKey,
+		false, // mandatory
+		false, // immediate
+		jobPublishing(conversionEvent, jobBytes),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to publish message: %w", err)
+	}
+	if confirmation == nil {
+		return fmt.Errorf("failed to publish message: channel is not in confirm mode")
+	}
+
+	acked, err := confirmation.WaitContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to confirm message: %w", err)
+	}
+	if !acked {
+		return fmt.Errorf("message was rejected by the broker")
+	}
+
+	log.Info("Published job %s to exchange %s with routing key %s, confirmed", conversionEvent.JobID, exchange, routingKey)
+	return nil
+}
+
+// PublishDeadLetter moves the job of a conversion that failed for good, usually on every attempt it
+// was allowed, to the dead-letter queue of exchange, with the reason, the failure and the number of
+// attempts in its headers
+func (p *Publisher) PublishDeadLetter(ctx context.Context, conversionEvent schema.ConversionEvent, exchange, routingKey string, reason DeadLetterReason, cause error) error {
+	jobBytes, err := json.Marshal(conversionEvent)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job: %w", err)
+	}
+
+	return publishDeadLetter(ctx, p.channel, exchange, routingKey, jobBytes, "application/json", reason, cause, amqp091.Table{
+		HeaderAttempts: int32(conversionEvent.Attempt),
+	})
+}
+
 // reOpenChannel attempts to re-open the RabbitMQ channel
 func (p *Publisher) reOpenChannel() error {
 	var err error
@@ -0,0 +1,59 @@
+package rabbitmq
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// RetryDelay returns the backoff before the attempt following a failed one: base doubled for every
+// earlier failure, capped at max
+func RetryDelay(failedAttempt int, base, max time.Duration) time.Duration {
+	delay := base
+	for i := 1; i < failedAttempt && delay < max; i++ {
+		delay *= 2
+	}
+	return min(delay, max)
+}
+
+// RetryDelays returns the distinct backoffs used by a job allowed maxAttempts attempts, shortest first
+func RetryDelays(maxAttempts int, base, max time.Duration) []time.Duration {
+	var delays []time.Duration
+	for attempt := 1; attempt < maxAttempts; attempt++ {
+		delay := RetryDelay(attempt, base, max)
+		if !slices.Contains(delays, delay) {
+			delays = append(delays, delay)
+		}
+	}
+	return delays
+}
+
+// RetryQueueName returns the name of the queue holding the jobs of queueName that wait delay before
+// their next attempt. The delay is part of the name so that changing the backoff declares new queues
+// instead of conflicting with the TTL of existing ones.
+func RetryQueueName(queueName string, delay time.Duration) string {
+	return fmt.Sprintf("%s.retry.%s", queueName, delay)
+}
+
+// setupRetryQueues declares a queue per retry delay. Messages published to one, through the default
+// exchange, expire after the delay and are dead-lettered back to the exchange with the routing key
+// of the job queue.
+func (cm *ConnectionManager) setupRetryQueues(exchangeName, routingKey, queueName string, delays []time.Duration) error {
+	for _, delay := range delays {
+		args := amqp091.Table{
+			"x-message-ttl":             delay.Milliseconds(),
+			"x-dead-letter-exchange":    exchangeName,
+			"x-dead-letter-routing-key": routingKey,
+		}
+
+		if _, err := cm.channel.QueueDeclare(RetryQueueName(queueName, delay), true, false, false, false, args); err != nil {
+			log.Warn("Failed to declare retry queue: %v", err)
+			return err
+		}
+	}
+
+	log.Info("Set up %d retry queues for queue %s", len(delays), queueName)
+	return nil
+}
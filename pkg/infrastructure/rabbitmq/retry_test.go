@@ -0,0 +1,30 @@
+package rabbitmq
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryDelay(t *testing.T) {
+	base, max := 10*time.Second, time.Minute
+
+	assert.Equal(t, 10*time.Second, RetryDelay(1, base, max))
+	assert.Equal(t, 20*time.Second, RetryDelay(2, base, max))
+	assert.Equal(t, 40*time.Second, RetryDelay(3, base, max))
+	assert.Equal(t, time.Minute, RetryDelay(4, base, max))
+	assert.Equal(t, time.Minute, RetryDelay(100, base, max))
+}
+
+func TestRetryDelays(t *testing.T) {
+	assert.Empty(t, RetryDelays(1, time.Second, time.Minute))
+	assert.Equal(t,
+		[]time.Duration{10 * time.Second, 20 * time.Second, 40 * time.Second, time.Minute},
+		RetryDelays(10, 10*time.Second, time.Minute),
+	)
+}
+
+func TestRetryQueueName(t *testing.T) {
+	assert.Equal(t, "conversion_queue.retry.1m20s", RetryQueueName("conversion_queue", 80*time.Second))
+}
@@ -0,0 +1,361 @@
+package meshops
+
+import (
+	"container/heap"
+	"math"
+
+	"github.com/wildan3105/converto/pkg/scene"
+)
+
+const (
+	// boundaryWeight scales the constraint planes that keep open borders in place
+	boundaryWeight = 1000
+	// minFlipCosine rejects collapses that turn a neighbouring triangle by more than about 78 degrees
+	minFlipCosine = 0.2
+)
+
+// quadric is a symmetric 4x4 error matrix stored as its upper triangle:
+// a² ab ac ad b² bc bd c² cd d²
+type quadric [10]float64
+
+// planeQuadric returns the quadric measuring the squared distance to the plane n·p + d = 0, scaled by weight
+func planeQuadric(n scene.Vec3, d, weight float64) quadric {
+	a, b, c := n[0], n[1], n[2]
+	return quadric{
+		a * a * weight, a * b * weight, a * c * weight, a * d * weight,
+		b * b * weight, b * c * weight, b * d * weight,
+		c * c * weight, c * d * weight,
+		d * d * weight,
+	}
+}
+
+func (q quadric) add(o quadric) quadric {
+	for i := range q {
+		q[i] += o[i]
+	}
+	return q
+}
+
+// error returns the quadric error of placing a vertex at p
+func (q quadric) error(p scene.Vec3) float64 {
+	x, y, z := p[0], p[1], p[2]
+	return q[0]*x*x + 2*q[1]*x*y + 2*q[2]*x*z + 2*q[3]*x +
+		q[4]*y*y + 2*q[5]*y*z + 2*q[6]*y +
+		q[7]*z*z + 2*q[8]*z +
+		q[9]
+}
+
+// minimum returns the position minimizing the error, if the quadric is well-conditioned
+func (q quadric) minimum() (scene.Vec3, bool) {
+	// solve the 3x3 system A p = -b with Cramer's rule
+	a11, a12, a13 := q[0], q[1], q[2]
+	a22, a23, a33 := q[4], q[5], q[7]
+	b1, b2, b3 := -q[3], -q[6], -q[8]
+
+	det := a11*(a22*a33-a23*a23) - a12*(a12*a33-a23*a13) + a13*(a12*a23-a22*a13)
+	scale := math.Abs(a11) + math.Abs(a22) + math.Abs(a33)
+	if scale == 0 || math.Abs(det) < 1e-12*scale*scale*scale {
+		return scene.Vec3{}, false
+	}
+
+	x := (b1*(a22*a33-a23*a23) - a12*(b2*a33-a23*b3) + a13*(b2*a23-a22*b3)) / det
+	y := (a11*(b2*a33-a23*b3) - b1*(a12*a33-a23*a13) + a13*(a12*b3-b2*a13)) / det
+	z := (a11*(a22*b3-b2*a23) - a12*(a12*b3-b2*a13) + b1*(a12*a23-a22*a13)) / det
+	return scene.Vec3{x, y, z}, true
+}
+
+// collapse is a candidate edge collapse in the priority queue
+type collapse struct {
+	cost     float64
+	u, v     uint32
+	target   scene.Vec3
+	versions [2]int
+}
+
+type collapseQueue []collapse
+
+func (q collapseQueue) Len() int           { return len(q) }
+func (q collapseQueue) Less(i, j int) bool { return q[i].cost < q[j].cost }
+func (q collapseQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *collapseQueue) Push(x any)        { *q = append(*q, x.(collapse)) }
+func (q *collapseQueue) Pop() any          { old := *q; x := old[len(old)-1]; *q = old[:len(old)-1]; return x }
+
+// decimator holds the working state of a decimation
+type decimator struct {
+	mesh      *scene.Mesh
+	quadrics  []quadric
+	versions  []int
+	removed   []bool
+	triangles [][3]uint32
+	dead      []bool
+	incident  [][]int
+	queue     collapseQueue
+}
+
+// Decimate reduces the mesh to at most target triangles by repeatedly collapsing the
+// edge whose removal changes the surface least, as measured by quadric error metrics
+// (Garland and Heckbert). Open borders are kept in place and collapses that would
+// fold the surface over are skipped, so the target may not be reached on meshes that
+// cannot be simplified further. Vertices should be welded first. Collapsed vertices
+// keep the attributes of the surviving endpoint. It returns the number of triangles removed.
+func Decimate(m *scene.Mesh, target int, progress Progress) int {
+	initial := m.TriangleCount()
+	if target < 1 {
+		target = 1
+	}
+	if initial <= target {
+		progress.report(1)
+		return 0
+	}
+
+	d := &decimator{
+		mesh:      m,
+		quadrics:  make([]quadric, len(m.Positions)),
+		versions:  make([]int, len(m.Positions)),
+		removed:   make([]bool, len(m.Positions)),
+		triangles: make([][3]uint32, initial),
+		dead:      make([]bool, initial),
+		incident:  make([][]int, len(m.Positions)),
+	}
+
+	for t := range d.triangles {
+		tri := [3]uint32{m.Indices[3*t], m.Indices[3*t+1], m.Indices[3*t+2]}
+		d.triangles[t] = tri
+		for _, v := range tri {
+			d.incident[v] = append(d.incident[v], t)
+		}
+	}
+
+	d.initQuadrics()
+	d.initQueue()
+
+	live := initial
+	lastReported := -1
+	for live > target && d.queue.Len() > 0 {
+		c := heap.Pop(&d.queue).(collapse)
+		if d.removed[c.u] || d.removed[c.v] || d.versions[c.u] != c.versions[0] || d.versions[c.v] != c.versions[1] {
+			continue
+		}
+
+		live -= d.collapse(c)
+
+		if step := (initial - live) * 100 / (initial - target); step != lastReported {
+			lastReported = step
+			progress.report(float64(initial-live) / float64(initial-target))
+		}
+	}
+
+	d.rebuild()
+	progress.report(1)
+	return initial - live
+}
+
+// initQuadrics sums the area-weighted plane quadrics of the triangles around each vertex,
+// plus constraint planes perpendicular to the border edges
+func (d *decimator) initQuadrics() {
+	edgeCount := make(map[[2]uint32]int)
+	for _, tri := range d.triangles {
+		for k := 0; k < 3; k++ {
+			edgeCount[undirectedEdge(tri[k], tri[(k+1)%3])]++
+		}
+	}
+
+	for _, tri := range d.triangles {
+		a, b, c := d.mesh.Positions[tri[0]], d.mesh.Positions[tri[1]], d.mesh.Positions[tri[2]]
+		cross := b.Sub(a).Cross(c.Sub(a))
+		area := cross.Length() / 2
+		if area == 0 {
+			continue
+		}
+		n := cross.Normalize()
+		q := planeQuadric(n, -n.Dot(a), area)
+		for _, v := range tri {
+			d.quadrics[v] = d.quadrics[v].add(q)
+		}
+
+		for k := 0; k < 3; k++ {
+			u, v := tri[k], tri[(k+1)%3]
+			if edgeCount[undirectedEdge(u, v)] != 1 {
+				continue
+			}
+			pu, pv := d.mesh.Positions[u], d.mesh.Positions[v]
+			edge := pv.Sub(pu)
+			constraint := edge.Cross(n).Normalize()
+			q := planeQuadric(constraint, -constraint.Dot(pu), boundaryWeight*edge.Dot(edge))
+			d.quadrics[u] = d.quadrics[u].add(q)
+			d.quadrics[v] = d.quadrics[v].add(q)
+		}
+	}
+}
+
+// initQueue pushes a collapse candidate for every edge
+func (d *decimator) initQueue() {
+	seen := make(map[[2]uint32]bool)
+	for _, tri := range d.triangles {
+		for k := 0; k < 3; k++ {
+			e := undirectedEdge(tri[k], tri[(k+1)%3])
+			if seen[e] {
+				continue
+			}
+			seen[e] = true
+			d.queue = append(d.queue, d.candidate(e[0], e[1]))
+		}
+	}
+	heap.Init(&d.queue)
+}
+
+// candidate computes the cheapest position and cost of collapsing v into u
+func (d *decimator) candidate(u, v uint32) collapse {
+	q := d.quadrics[u].add(d.quadrics[v])
+	pu, pv := d.mesh.Positions[u], d.mesh.Positions[v]
+
+	options := []scene.Vec3{pu, pv, pu.Add(pv).Scale(0.5)}
+	if p, ok := q.minimum(); ok {
+		options = append(options, p)
+	}
+
+	best := collapse{cost: math.Inf(1), u: u, v: v, versions: [2]int{d.versions[u], d.versions[v]}}
+	for _, p := range options {
+		if cost := q.error(p); cost < best.cost {
+			best.cost, best.target = cost, p
+		}
+	}
+	return best
+}
+
+// collapse merges v into u if the result stays manifold and unfolded; it returns the number of triangles removed
+func (d *decimator) collapse(c collapse) int {
+	u, v := c.u, c.v
+
+	// link condition: the endpoints may only share the vertices opposite the collapsed edge
+	shared := 0
+	neighbours := d.neighbours(u)
+	for w := range d.neighbours(v) {
+		if neighbours[w] {
+			shared++
+		}
+	}
+	opposite := 0
+	for _, t := range d.incident[u] {
+		if !d.dead[t] && d.contains(t, v) {
+			opposite++
+		}
+	}
+	if shared != opposite {
+		return 0
+	}
+
+	// reject collapses that flip a remaining triangle
+	for _, vertex := range [2]uint32{u, v} {
+		for _, t := range d.incident[vertex] {
+			if d.dead[t] || (d.contains(t, u) && d.contains(t, v)) {
+				continue
+			}
+			if !d.keepsOrientation(t, vertex, c.target) {
+				return 0
+			}
+		}
+	}
+
+	d.mesh.Positions[u] = c.target
+	d.quadrics[u] = d.quadrics[u].add(d.quadrics[v])
+	d.removed[v] = true
+	d.versions[u]++
+
+	removedTriangles := 0
+	for _, t := range d.incident[v] {
+		if d.dead[t] {
+			continue
+		}
+		if d.contains(t, u) {
+			d.dead[t] = true
+			removedTriangles++
+			continue
+		}
+		for k := range d.triangles[t] {
+			if d.triangles[t][k] == v {
+				d.triangles[t][k] = u
+			}
+		}
+		d.incident[u] = append(d.incident[u], t)
+	}
+	d.incident[v] = nil
+
+	// drop dead triangles from u's list and queue the edges around it again
+	incident := d.incident[u][:0]
+	for _, t := range d.incident[u] {
+		if !d.dead[t] {
+			incident = append(incident, t)
+		}
+	}
+	d.incident[u] = incident
+
+	for w := range d.neighbours(u) {
+		heap.Push(&d.queue, d.candidate(u, w))
+	}
+
+	return removedTriangles
+}
+
+// keepsOrientation reports whether triangle t keeps roughly its normal when vertex moves to p
+func (d *decimator) keepsOrientation(t int, vertex uint32, p scene.Vec3) bool {
+	var before, after [3]scene.Vec3
+	for k, i := range d.triangles[t] {
+		before[k] = d.mesh.Positions[i]
+		after[k] = before[k]
+		if i == vertex {
+			after[k] = p
+		}
+	}
+
+	n0 := before[1].Sub(before[0]).Cross(before[2].Sub(before[0])).Normalize()
+	n1 := after[1].Sub(after[0]).Cross(after[2].Sub(after[0])).Normalize()
+	if n1 == (scene.Vec3{}) {
+		return false
+	}
+	return n0.Dot(n1) >= minFlipCosine
+}
+
+// neighbours returns the vertices sharing a live triangle with v
+func (d *decimator) neighbours(v uint32) map[uint32]bool {
+	result := make(map[uint32]bool)
+	for _, t := range d.incident[v] {
+		if d.dead[t] {
+			continue
+		}
+		for _, w := range d.triangles[t] {
+			if w != v {
+				result[w] = true
+			}
+		}
+	}
+	return result
+}
+
+func (d *decimator) contains(t int, v uint32) bool {
+	tri := d.triangles[t]
+	return tri[0] == v || tri[1] == v || tri[2] == v
+}
+
+// rebuild writes the live triangles back to the mesh and drops unused vertices
+func (d *decimator) rebuild() {
+	indices := make([]uint32, 0, 3*len(d.triangles))
+	for t, tri := range d.triangles {
+		if !d.dead[t] {
+			indices = append(indices, tri[0], tri[1], tri[2])
+		}
+	}
+	d.mesh.Indices = indices
+	compact(d.mesh)
+
+	if len(d.mesh.Normals) > 0 {
+		RecomputeNormals(d.mesh)
+	}
+}
+
+func undirectedEdge(a, b uint32) [2]uint32 {
+	if a > b {
+		a, b = b, a
+	}
+	return [2]uint32{a, b}
+}
@@ -0,0 +1,211 @@
+// Package meshops implements mesh processing steps run between parsing and export:
+// vertex welding, hole filling, normal orientation repair and quadric decimation.
+//
+// Every operation works on a single mesh in its local space and keeps the optional
+// per-vertex attributes (normals, UVs, colours) consistent with the positions.
+package meshops
+
+import (
+	"math"
+
+	"github.com/wildan3105/converto/pkg/scene"
+)
+
+// Progress receives the completed fraction of an operation, between 0 and 1
+type Progress func(fraction float64)
+
+// report calls p when it is set
+func (p Progress) report(fraction float64) {
+	if p != nil {
+		p(fraction)
+	}
+}
+
+// Weld merges vertices whose positions are within tolerance of each other (exactly
+// equal when tolerance is 0) and drops the triangles that collapse as a result.
+// The attributes of the first vertex of each group are kept. It returns the number
+// of vertices removed.
+func Weld(m *scene.Mesh, tolerance float64) int {
+	remap := make([]uint32, len(m.Positions))
+	var kept []int
+
+	if tolerance <= 0 {
+		seen := make(map[scene.Vec3]uint32, len(m.Positions))
+		for i, p := range m.Positions {
+			if j, ok := seen[p]; ok {
+				remap[i] = j
+				continue
+			}
+			seen[p] = uint32(len(kept))
+			remap[i] = uint32(len(kept))
+			kept = append(kept, i)
+		}
+	} else {
+		grid := make(map[[3]int64][]uint32)
+		cell := func(p scene.Vec3) [3]int64 {
+			return [3]int64{
+				int64(math.Floor(p[0] / tolerance)),
+				int64(math.Floor(p[1] / tolerance)),
+				int64(math.Floor(p[2] / tolerance)),
+			}
+		}
+
+		for i, p := range m.Positions {
+			c := cell(p)
+			match, found := uint32(0), false
+		search:
+			for dx := int64(-1); dx <= 1; dx++ {
+				for dy := int64(-1); dy <= 1; dy++ {
+					for dz := int64(-1); dz <= 1; dz++ {
+						for _, j := range grid[[3]int64{c[0] + dx, c[1] + dy, c[2] + dz}] {
+							if m.Positions[kept[j]].Sub(p).Length() <= tolerance {
+								match, found = j, true
+								break search
+							}
+						}
+					}
+				}
+			}
+
+			if found {
+				remap[i] = match
+				continue
+			}
+			remap[i] = uint32(len(kept))
+			grid[c] = append(grid[c], uint32(len(kept)))
+			kept = append(kept, i)
+		}
+	}
+
+	removed := len(m.Positions) - len(kept)
+	if removed == 0 {
+		return 0
+	}
+
+	for i, index := range m.Indices {
+		m.Indices[i] = remap[index]
+	}
+	keepVertices(m, kept)
+	removeDegenerate(m)
+
+	return removed
+}
+
+// keepVertices reduces the vertex arrays to the given vertices, in order.
+// Indices must already refer to the new positions.
+func keepVertices(m *scene.Mesh, kept []int) {
+	positions := make([]scene.Vec3, len(kept))
+	for i, k := range kept {
+		positions[i] = m.Positions[k]
+	}
+	m.Positions = positions
+
+	if len(m.Normals) > 0 {
+		normals := make([]scene.Vec3, len(kept))
+		for i, k := range kept {
+			normals[i] = m.Normals[k]
+		}
+		m.Normals = normals
+	}
+	if len(m.UVs) > 0 {
+		uvs := make([]scene.Vec2, len(kept))
+		for i, k := range kept {
+			uvs[i] = m.UVs[k]
+		}
+		m.UVs = uvs
+	}
+	if len(m.Colors) > 0 {
+		colors := make([]scene.Color, len(kept))
+		for i, k := range kept {
+			colors[i] = m.Colors[k]
+		}
+		m.Colors = colors
+	}
+}
+
+// compact drops the vertices no triangle refers to
+func compact(m *scene.Mesh) {
+	remap := make([]int, len(m.Positions))
+	for i := range remap {
+		remap[i] = -1
+	}
+
+	var kept []int
+	for i, index := range m.Indices {
+		if remap[index] < 0 {
+			remap[index] = len(kept)
+			kept = append(kept, int(index))
+		}
+		m.Indices[i] = uint32(remap[index])
+	}
+
+	if len(kept) != len(m.Positions) {
+		keepVertices(m, kept)
+	}
+}
+
+// removeDegenerate drops the triangles referring to the same vertex twice
+func removeDegenerate(m *scene.Mesh) {
+	indices := m.Indices[:0]
+	for t := 0; t+2 < len(m.Indices); t += 3 {
+		a, b, c := m.Indices[t], m.Indices[t+1], m.Indices[t+2]
+		if a == b || b == c || a == c {
+			continue
+		}
+		indices = append(indices, a, b, c)
+	}
+	m.Indices = indices
+}
+
+// appendVertex adds a vertex at p whose attributes average those of the given vertices
+func appendVertex(m *scene.Mesh, p scene.Vec3, from []uint32) uint32 {
+	index := uint32(len(m.Positions))
+	weight := 1 / float64(len(from))
+
+	if len(m.Normals) > 0 {
+		var n scene.Vec3
+		for _, i := range from {
+			n = n.Add(m.Normals[i])
+		}
+		m.Normals = append(m.Normals, n.Normalize())
+	}
+	if len(m.UVs) > 0 {
+		var uv scene.Vec2
+		for _, i := range from {
+			uv[0] += m.UVs[i][0] * weight
+			uv[1] += m.UVs[i][1] * weight
+		}
+		m.UVs = append(m.UVs, uv)
+	}
+	if len(m.Colors) > 0 {
+		var c scene.Color
+		for _, i := range from {
+			for k := range c {
+				c[k] += m.Colors[i][k] * weight
+			}
+		}
+		m.Colors = append(m.Colors, c)
+	}
+
+	m.Positions = append(m.Positions, p)
+	return index
+}
+
+// RecomputeNormals replaces the vertex normals with area-weighted averages of the face normals
+func RecomputeNormals(m *scene.Mesh) {
+	normals := make([]scene.Vec3, len(m.Positions))
+	for t := 0; t < m.TriangleCount(); t++ {
+		a, b, c := m.Triangle(t)
+		// the cross product's length is twice the area, which weights the average
+		n := b.Sub(a).Cross(c.Sub(a))
+		for k := 0; k < 3; k++ {
+			i := m.Indices[3*t+k]
+			normals[i] = normals[i].Add(n)
+		}
+	}
+
+	for i := range normals {
+		normals[i] = normals[i].Normalize()
+	}
+	m.Normals = normals
+}
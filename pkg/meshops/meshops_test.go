@@ -0,0 +1,183 @@
+package meshops
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wildan3105/converto/pkg/analysis"
+	"github.com/wildan3105/converto/pkg/scene"
+	"github.com/wildan3105/converto/pkg/scene/scenetest"
+)
+
+// unwelded returns the mesh with three vertices of its own for every triangle, as STL files store them
+func unwelded(m scene.Mesh) scene.Mesh {
+	var positions []scene.Vec3
+	var indices []uint32
+	for _, i := range m.Indices {
+		indices = append(indices, uint32(len(positions)))
+		positions = append(positions, m.Positions[i])
+	}
+	m.Positions, m.Indices = positions, indices
+	return m
+}
+
+// subdividedCube returns a closed cube of edge length size whose faces are split into n by n quads
+func subdividedCube(size float64, n int) scene.Mesh {
+	var mesh scene.Mesh
+	// each face is spanned from an origin by two axes, ordered so that their cross product points outward
+	faces := [][3]scene.Vec3{
+		{{0, 0, 0}, {0, 1, 0}, {1, 0, 0}},
+		{{0, 0, 1}, {1, 0, 0}, {0, 1, 0}},
+		{{0, 0, 0}, {1, 0, 0}, {0, 0, 1}},
+		{{0, 1, 0}, {0, 0, 1}, {1, 0, 0}},
+		{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}},
+		{{0, 0, 0}, {0, 0, 1}, {0, 1, 0}},
+	}
+	for _, f := range faces {
+		base := uint32(len(mesh.Positions))
+		for i := 0; i <= n; i++ {
+			for j := 0; j <= n; j++ {
+				p := f[0].Add(f[1].Scale(float64(i) / float64(n))).Add(f[2].Scale(float64(j) / float64(n)))
+				mesh.Positions = append(mesh.Positions, p.Scale(size))
+			}
+		}
+		for i := 0; i < n; i++ {
+			for j := 0; j < n; j++ {
+				a := base + uint32(i*(n+1)+j)
+				b, c, d := a+uint32(n+1), a+uint32(n+2), a+1
+				mesh.Indices = append(mesh.Indices, a, b, c, a, c, d)
+			}
+		}
+	}
+	Weld(&mesh, 0)
+	return mesh
+}
+
+// analyze returns the analysis of a scene holding only m
+func analyze(m scene.Mesh) (volume float64, watertight bool) {
+	s := scene.New()
+	s.Bodies = []scene.Body{{Transform: scene.Identity(), Meshes: []scene.Mesh{m}}}
+	result := analysis.Analyze(s)
+	return result.Volume, result.Watertight
+}
+
+func TestWeld(t *testing.T) {
+	mesh := unwelded(scenetest.CubeMesh(10))
+	require.Len(t, mesh.Positions, 36)
+
+	removed := Weld(&mesh, 0)
+
+	assert.Equal(t, 28, removed)
+	assert.Len(t, mesh.Positions, 8)
+	assert.Equal(t, 12, mesh.TriangleCount())
+	assert.NoError(t, mesh.Validate())
+	_, watertight := analyze(mesh)
+	assert.True(t, watertight)
+}
+
+func TestWeldWithTolerance(t *testing.T) {
+	mesh := unwelded(scenetest.CubeMesh(10))
+	mesh.Positions[0] = mesh.Positions[0].Add(scene.Vec3{0.001, 0, 0})
+
+	Weld(&mesh, 0)
+	assert.Len(t, mesh.Positions, 9, "an exact weld keeps the displaced vertex")
+
+	Weld(&mesh, 0.01)
+	assert.Len(t, mesh.Positions, 8)
+}
+
+func TestFillHoles(t *testing.T) {
+	tests := []struct {
+		name      string
+		remove    int
+		triangles int
+	}{
+		{name: "triangular hole", remove: 1, triangles: 12},
+		{name: "square hole", remove: 2, triangles: 14},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mesh := scenetest.CubeMesh(10)
+			mesh.Indices = mesh.Indices[3*test.remove:]
+
+			filled := FillHoles(&mesh, nil)
+
+			assert.Equal(t, 1, filled)
+			assert.Equal(t, test.triangles, mesh.TriangleCount())
+			assert.NoError(t, mesh.Validate())
+			volume, watertight := analyze(mesh)
+			assert.True(t, watertight)
+			assert.InDelta(t, 1000, volume, 1e-9)
+		})
+	}
+}
+
+func TestFixNormals(t *testing.T) {
+	mesh := scenetest.CubeMesh(10)
+	for _, tri := range []int{0, 5, 7} {
+		mesh.Indices[3*tri+1], mesh.Indices[3*tri+2] = mesh.Indices[3*tri+2], mesh.Indices[3*tri+1]
+	}
+
+	flipped := FixNormals(&mesh, false, nil)
+
+	assert.Equal(t, 3, flipped)
+	assert.Equal(t, scenetest.CubeMesh(10).Indices, mesh.Indices)
+}
+
+func TestFixNormalsTurnsClosedMeshesOutward(t *testing.T) {
+	mesh := scenetest.CubeMesh(10)
+	for tri := 0; tri < mesh.TriangleCount(); tri++ {
+		mesh.Indices[3*tri+1], mesh.Indices[3*tri+2] = mesh.Indices[3*tri+2], mesh.Indices[3*tri+1]
+	}
+
+	assert.Equal(t, 12, FixNormals(&mesh, false, nil))
+	assert.Equal(t, scenetest.CubeMesh(10).Indices, mesh.Indices)
+
+	// a mirroring transform turns the mesh inside out, so it must be stored inverted
+	assert.Equal(t, 12, FixNormals(&mesh, true, nil))
+}
+
+func TestDecimate(t *testing.T) {
+	mesh := subdividedCube(10, 8)
+	require.Equal(t, 768, mesh.TriangleCount())
+
+	var reported []float64
+	removed := Decimate(&mesh, 100, func(fraction float64) { reported = append(reported, fraction) })
+
+	assert.Equal(t, 768-mesh.TriangleCount(), removed)
+	assert.LessOrEqual(t, mesh.TriangleCount(), 100)
+	assert.NoError(t, mesh.Validate())
+
+	// flat faces are simplified without changing the shape
+	volume, watertight := analyze(mesh)
+	assert.True(t, watertight)
+	assert.InDelta(t, 1000, volume, 1e-6)
+
+	require.NotEmpty(t, reported)
+	assert.IsNonDecreasing(t, reported)
+	assert.Equal(t, 1.0, reported[len(reported)-1])
+}
+
+func TestDecimateKeepsBorders(t *testing.T) {
+	mesh := subdividedCube(10, 4)
+	// keep the bottom face only: a flat, open square
+	mesh.Indices = mesh.Indices[:3*32]
+	compact(&mesh)
+
+	Decimate(&mesh, 2, nil)
+
+	assert.Equal(t, 2, mesh.TriangleCount())
+	assert.Len(t, mesh.Positions, 4)
+	for _, p := range mesh.Positions {
+		assert.Contains(t, []scene.Vec3{{0, 0, 0}, {10, 0, 0}, {0, 10, 0}, {10, 10, 0}}, p)
+	}
+}
+
+func TestDecimateBelowTarget(t *testing.T) {
+	mesh := scenetest.CubeMesh(10)
+
+	assert.Zero(t, Decimate(&mesh, 100, nil))
+	assert.Equal(t, scenetest.CubeMesh(10), mesh)
+}
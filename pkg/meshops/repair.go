@@ -0,0 +1,214 @@
+package meshops
+
+import (
+	"github.com/wildan3105/converto/pkg/scene"
+)
+
+// directedEdge is an edge between two vertex indices, in the order a triangle traverses it
+type directedEdge [2]uint32
+
+// FillHoles closes every boundary loop of the mesh. Loops of three edges get a single
+// triangle; longer loops are closed by a fan around a new vertex at their centroid.
+// The new triangles follow the orientation of their neighbours. Vertices should be
+// welded first, since holes are found through shared edges. It returns the number of
+// holes filled.
+func FillHoles(m *scene.Mesh, progress Progress) int {
+	edges := make(map[directedEdge]bool, len(m.Indices))
+	for t := 0; t+2 < len(m.Indices); t += 3 {
+		a, b, c := m.Indices[t], m.Indices[t+1], m.Indices[t+2]
+		edges[directedEdge{a, b}] = true
+		edges[directedEdge{b, c}] = true
+		edges[directedEdge{c, a}] = true
+	}
+
+	// boundary edges are traversed by a single triangle only
+	outgoing := make(map[uint32][]uint32)
+	boundary := 0
+	for t := 0; t+2 < len(m.Indices); t += 3 {
+		for k := 0; k < 3; k++ {
+			a, b := m.Indices[t+k], m.Indices[t+(k+1)%3]
+			if !edges[directedEdge{b, a}] {
+				outgoing[a] = append(outgoing[a], b)
+				boundary++
+			}
+		}
+	}
+
+	filled, visited := 0, 0
+	// walk the boundary in triangle order, so that the result does not depend on map iteration
+	for t := 0; t+2 < len(m.Indices) && visited < boundary; t += 3 {
+		for k := 0; k < 3; k++ {
+			start := m.Indices[t+k]
+			if len(outgoing[start]) == 0 {
+				continue
+			}
+
+			loop := []uint32{start}
+			for current := start; ; {
+				next := outgoing[current]
+				if len(next) == 0 {
+					// an open chain through a non-manifold vertex cannot be closed
+					loop = nil
+					break
+				}
+				outgoing[current] = next[1:]
+				visited++
+				if next[0] == start {
+					break
+				}
+				current = next[0]
+				loop = append(loop, current)
+			}
+
+			if len(loop) >= 3 {
+				closeLoop(m, loop)
+				filled++
+			}
+			progress.report(float64(visited) / float64(boundary))
+		}
+	}
+
+	progress.report(1)
+	return filled
+}
+
+// closeLoop triangulates a boundary loop; the triangles traverse its edges in reverse
+func closeLoop(m *scene.Mesh, loop []uint32) {
+	if len(loop) == 3 {
+		m.Indices = append(m.Indices, loop[2], loop[1], loop[0])
+		return
+	}
+
+	var centroid scene.Vec3
+	for _, i := range loop {
+		centroid = centroid.Add(m.Positions[i])
+	}
+	center := appendVertex(m, centroid.Scale(1/float64(len(loop))), loop)
+
+	for i := range loop {
+		a, b := loop[i], loop[(i+1)%len(loop)]
+		m.Indices = append(m.Indices, b, a, center)
+	}
+}
+
+// FixNormals makes the winding of every connected part of the mesh consistent. Closed
+// parts are turned outward, judged by the sign of their enclosed volume; open parts keep
+// the winding most of their triangles had. Set mirrored when the mesh is placed by a
+// transform with a negative determinant, which turns it inside out. Vertex normals,
+// when present, are recomputed. It returns the number of triangles flipped.
+func FixNormals(m *scene.Mesh, mirrored bool, progress Progress) int {
+	triangles := m.TriangleCount()
+	if triangles == 0 {
+		return 0
+	}
+
+	// triangles around each undirected edge
+	type undirected [2]uint32
+	key := func(a, b uint32) undirected {
+		if a > b {
+			a, b = b, a
+		}
+		return undirected{a, b}
+	}
+	adjacent := make(map[undirected][]int, len(m.Indices))
+	for t := 0; t < triangles; t++ {
+		for k := 0; k < 3; k++ {
+			e := key(m.Indices[3*t+k], m.Indices[3*t+(k+1)%3])
+			adjacent[e] = append(adjacent[e], t)
+		}
+	}
+
+	// traverses reports whether triangle t, as currently wound, runs from a to b
+	traverses := func(t int, a, b uint32) bool {
+		for k := 0; k < 3; k++ {
+			if m.Indices[3*t+k] == a && m.Indices[3*t+(k+1)%3] == b {
+				return true
+			}
+		}
+		return false
+	}
+
+	flip := make([]bool, triangles)
+	visited := make([]bool, triangles)
+	done := 0
+
+	for seed := 0; seed < triangles; seed++ {
+		if visited[seed] {
+			continue
+		}
+
+		// orient the component consistently with its seed triangle
+		component := []int{seed}
+		visited[seed] = true
+		for queue := []int{seed}; len(queue) > 0; {
+			t := queue[0]
+			queue = queue[1:]
+
+			for k := 0; k < 3; k++ {
+				a, b := m.Indices[3*t+k], m.Indices[3*t+(k+1)%3]
+				if flip[t] {
+					a, b = b, a
+				}
+				for _, n := range adjacent[key(a, b)] {
+					if visited[n] {
+						continue
+					}
+					// a neighbour must run along the shared edge in the opposite direction
+					flip[n] = traverses(n, a, b)
+					visited[n] = true
+					component = append(component, n)
+					queue = append(queue, n)
+				}
+			}
+
+			done++
+			if done%1024 == 0 {
+				progress.report(float64(done) / float64(triangles))
+			}
+		}
+
+		// a closed component faces inward when its enclosed volume is negative;
+		// an open one keeps the winding most of its triangles already had
+		var volume float64
+		closed, flips := true, 0
+		for _, t := range component {
+			a, b, c := m.Triangle(t)
+			if flip[t] {
+				b, c = c, b
+				flips++
+			}
+			volume += a.Dot(b.Cross(c))
+
+			for k := 0; k < 3; k++ {
+				if len(adjacent[key(m.Indices[3*t+k], m.Indices[3*t+(k+1)%3])]) != 2 {
+					closed = false
+				}
+			}
+		}
+
+		invert := 2*flips > len(component)
+		if closed {
+			invert = (volume < 0) != mirrored
+		}
+		if invert {
+			for _, t := range component {
+				flip[t] = !flip[t]
+			}
+		}
+	}
+
+	flipped := 0
+	for t, f := range flip {
+		if f {
+			m.Indices[3*t+1], m.Indices[3*t+2] = m.Indices[3*t+2], m.Indices[3*t+1]
+			flipped++
+		}
+	}
+
+	if len(m.Normals) > 0 {
+		RecomputeNormals(m)
+	}
+
+	progress.report(1)
+	return flipped
+}
@@ -0,0 +1,211 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	externalLog "log"
+	"time"
+
+	config "github.com/wildan3105/converto/configs"
+	"github.com/wildan3105/converto/pkg/api/schema"
+	"github.com/wildan3105/converto/pkg/domain"
+	"github.com/wildan3105/converto/pkg/infrastructure/mongodb"
+	"github.com/wildan3105/converto/pkg/infrastructure/rabbitmq"
+	"github.com/wildan3105/converto/pkg/logger"
+	"github.com/wildan3105/converto/pkg/repository"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var log = logger.GetInstance()
+
+const (
+	// relayLock is the lock electing the instance that relays outbox messages
+	relayLock = "outbox-relay"
+	// relayBatchSize bounds the messages published on each tick
+	relayBatchSize = 100
+	// maxBackoff bounds the wait between two publishes of a message
+	maxBackoff = time.Minute
+)
+
+// jobPublisher publishes job events and waits for the broker to confirm them. It is implemented by
+// rabbitmq.Publisher.
+type jobPublisher interface {
+	PublishConversionJobConfirmed(ctx context.Context, conversionEvent schema.ConversionEvent, exchange, routingKey string) error
+}
+
+// Relay publishes the job events written to the outbox of conversions. A message is marked as sent
+// once the broker confirmed it; a failed publish is retried with a backoff, and a conversion whose
+// message still fails after the allowed attempts is marked as failed. Every instance runs a relay,
+// but only the one holding the relay lock publishes, so that a message is not published twice.
+type Relay struct {
+	id        string
+	repo      repository.ConversionRepository
+	locks     repository.LockRepository
+	publisher jobPublisher
+}
+
+// NewRelay creates a new Relay instance
+func NewRelay(repo repository.ConversionRepository, locks repository.LockRepository, publisher *rabbitmq.Publisher) *Relay {
+	if repo == nil {
+		externalLog.Fatal("ConversionRepository cannot be nil")
+	}
+	if locks == nil {
+		externalLog.Fatal("LockRepository cannot be nil")
+	}
+	if publisher == nil {
+		externalLog.Fatal("Publisher cannot be nil")
+	}
+
+	return &Relay{
+		id:        repository.NewOwnerID(),
+		repo:      repo,
+		locks:     locks,
+		publisher: publisher,
+	}
+}
+
+// Run relays pending messages every poll interval until ctx is done. The lock outlives two intervals,
+// so that leadership moves to another instance soon after the leader stops.
+func (r *Relay) Run(ctx context.Context) {
+	interval := config.AppConfig.OutboxPollInterval
+	if interval <= 0 {
+		log.Warn("Outbox relay disabled, conversion jobs will not be published")
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	defer func() {
+		if err := r.locks.ReleaseLock(context.WithoutCancel(ctx), relayLock, r.id); err != nil {
+			log.Warn("Failed to release outbox relay lock: %v", err)
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			leader, err := r.locks.AcquireLock(ctx, relayLock, r.id, 2*interval)
+			if err != nil {
+				log.Warn("Failed to acquire outbox relay lock: %v", err)
+				continue
+			}
+			if leader {
+				r.relay(ctx)
+			}
+		}
+	}
+}
+
+// relay publishes the messages that are due. The lock is renewed before each message for long
+// enough to publish it and record the outcome, and the batch stops once the lock is lost, so that
+// another instance taking over never publishes the same messages concurrently.
+func (r *Relay) relay(ctx context.Context) {
+	conversions, err := r.repo.ListPendingOutbox(ctx, time.Now(), relayBatchSize)
+	if err != nil {
+		log.Warn("Failed to list pending outbox messages: %v", err)
+		return
+	}
+
+	// a message takes at most a confirmed publish and two database operations
+	ttl := 2*config.AppConfig.OutboxPollInterval + rabbitmq.ConfirmTimeout + 2*mongodb.DefaultTimeout
+	for _, conversion := range conversions {
+		leader, err := r.locks.AcquireLock(ctx, relayLock, r.id, ttl)
+		if err != nil {
+			log.Warn("Failed to renew outbox relay lock: %v", err)
+			return
+		}
+		if !leader {
+			log.Warn("Lost outbox relay lock, stopping the batch")
+			return
+		}
+
+		if err := r.publish(ctx, conversion); err != nil {
+			log.Warn("Failed to relay outbox message of conversion ID %s: %v", conversion.ID, err)
+		}
+	}
+}
+
+// publish publishes the outbox message of a conversion and records the outcome. A message published
+// but not marked as sent is published again; workers skip the duplicate job.
+func (r *Relay) publish(ctx context.Context, conversion *domain.Conversion) error {
+	message := conversion.Outbox
+	now := time.Now()
+
+	event := schema.ConversionEvent{
+		JobID:        message.JobID,
+		ConversionID: conversion.ID,
+		Source:       conversion.Job.Source,
+		Options:      conversion.Conversion.Options,
+		Attempt:      message.Attempt,
+		CreatedAt:    conversion.Job.CreatedAt,
+		UpdatedAt:    now,
+	}
+
+	publishErr := r.publisher.PublishConversionJobConfirmed(ctx, event, config.AppConfig.RabbitMQExchangeName, config.AppConfig.RabbitMQRoutingKey)
+	if publishErr == nil {
+		_, err := r.repo.UpdateOutbox(ctx, conversion.ID, message.ID, bson.M{
+			"outbox.status": domain.OutboxSent,
+			"outbox.sentAt": now,
+		})
+		return err
+	}
+
+	attempts := message.PublishAttempts + 1
+	if attempts >= config.AppConfig.OutboxMaxAttempts {
+		return r.fail(ctx, conversion, attempts, publishErr)
+	}
+
+	backoff := rabbitmq.RetryDelay(attempts, config.AppConfig.OutboxPollInterval, maxBackoff)
+	if _, err := r.repo.UpdateOutbox(ctx, conversion.ID, message.ID, bson.M{
+		"outbox.publishAttempts": attempts,
+		"outbox.lastError":       publishErr.Error(),
+		"outbox.nextAttemptAt":   now.Add(backoff),
+	}); err != nil {
+		return err
+	}
+
+	return publishErr
+}
+
+// fail gives up on the outbox message of a conversion and marks the conversion as failed. It can be retried.
+func (r *Relay) fail(ctx context.Context, conversion *domain.Conversion, attempts int, cause error) error {
+	message := conversion.Outbox
+	record := &domain.ConversionError{
+		Code:       domain.ErrorCodePublishFailed,
+		Message:    "failed to publish",
+		Stage:      domain.ErrorStagePublish,
+		Retryable:  true,
+		OccurredAt: time.Now(),
+	}
+
+	outboxData := bson.M{
+		"outbox.status":          domain.OutboxFailed,
+		"outbox.publishAttempts": attempts,
+		"outbox.lastError":       cause.Error(),
+	}
+
+	updateData := bson.M{
+		"conversion.error":        record,
+		"conversion.errorMessage": record.Message,
+		"job.errorMessage":        record.Message,
+		"conversion.completedAt":  record.OccurredAt,
+	}
+	for key, value := range outboxData {
+		updateData[key] = value
+	}
+
+	err := r.repo.TransitionConversion(ctx, conversion.ID, domain.ConversionFailed, updateData, domain.ConversionPending)
+	if errors.Is(err, domain.ErrIllegalTransition) {
+		// cancelled meanwhile: only the message is given up
+		_, err = r.repo.UpdateOutbox(ctx, conversion.ID, message.ID, outboxData)
+	}
+	if err != nil {
+		return err
+	}
+
+	log.Warn("Gave up publishing job of conversion ID %s after %d attempts: %v", conversion.ID, attempts, cause)
+	return nil
+}
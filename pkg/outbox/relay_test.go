@@ -0,0 +1,345 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	config "github.com/wildan3105/converto/configs"
+	"github.com/wildan3105/converto/pkg/api/schema"
+	"github.com/wildan3105/converto/pkg/domain"
+	"github.com/wildan3105/converto/pkg/repository"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// memoryRepository keeps conversions in memory and implements the operations of the relay with the
+// rule of the Mongo repository; the other operations are not used
+type memoryRepository struct {
+	repository.ConversionRepository
+
+	mu          sync.Mutex
+	conversions map[string]*domain.Conversion
+}
+
+func newMemoryRepository(conversions ...*domain.Conversion) *memoryRepository {
+	repo := &memoryRepository{conversions: map[string]*domain.Conversion{}}
+	for _, conversion := range conversions {
+		repo.conversions[conversion.ID] = conversion
+	}
+	return repo
+}
+
+func (r *memoryRepository) get(id string) domain.Conversion {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	conversion := *r.conversions[id]
+	message := *conversion.Outbox
+	conversion.Outbox = &message
+	return conversion
+}
+
+func (r *memoryRepository) ListPendingOutbox(_ context.Context, now time.Time, _ int) ([]*domain.Conversion, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var conversions []*domain.Conversion
+	for _, conversion := range r.conversions {
+		message := conversion.Outbox
+		if message != nil && message.Status == domain.OutboxPending && !message.NextAttemptAt.After(now) {
+			copied := *conversion
+			copiedMessage := *message
+			copied.Outbox = &copiedMessage
+			conversions = append(conversions, &copied)
+		}
+	}
+	return conversions, nil
+}
+
+func (r *memoryRepository) UpdateOutbox(_ context.Context, id, messageID string, updateData bson.M) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	message := r.conversions[id].Outbox
+	if message == nil || message.ID != messageID || message.Status != domain.OutboxPending {
+		return false, nil
+	}
+	applyOutbox(message, updateData)
+	return true, nil
+}
+
+func (r *memoryRepository) TransitionConversion(_ context.Context, id string, to domain.ConversionStatus, updateData bson.M, from ...domain.ConversionStatus) error {
+	if err := domain.ValidateTransition(to, from...); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	conversion := r.conversions[id]
+	for _, status := range from {
+		if conversion.Conversion.Status == status {
+			conversion.Conversion.Status = to
+			if record, ok := updateData["conversion.error"].(*domain.ConversionError); ok {
+				conversion.Conversion.Error = record
+			}
+			applyOutbox(conversion.Outbox, updateData)
+			return nil
+		}
+	}
+	return &domain.TransitionError{ConversionID: id, From: conversion.Conversion.Status, To: to}
+}
+
+// applyOutbox sets the outbox fields of updateData on message
+func applyOutbox(message *domain.OutboxMessage, updateData bson.M) {
+	for key, value := range updateData {
+		switch key {
+		case "outbox.status":
+			message.Status = value.(domain.OutboxStatus)
+		case "outbox.publishAttempts":
+			message.PublishAttempts = value.(int)
+		case "outbox.lastError":
+			message.LastError = value.(string)
+		case "outbox.nextAttemptAt":
+			message.NextAttemptAt = value.(time.Time)
+		case "outbox.sentAt":
+			sentAt := value.(time.Time)
+			message.SentAt = &sentAt
+		}
+	}
+}
+
+// memoryLocks keeps named locks in memory with the rule of the Mongo lock repository
+type memoryLocks struct {
+	mu     sync.Mutex
+	owners map[string]string
+	expiry map[string]time.Time
+}
+
+func newMemoryLocks() *memoryLocks {
+	return &memoryLocks{owners: map[string]string{}, expiry: map[string]time.Time{}}
+}
+
+func (l *memoryLocks) AcquireLock(_ context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if current, ok := l.owners[name]; ok && current != owner && l.expiry[name].After(now) {
+		return false, nil
+	}
+	l.owners[name] = owner
+	l.expiry[name] = now.Add(ttl)
+	return true, nil
+}
+
+func (l *memoryLocks) ReleaseLock(_ context.Context, name, owner string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.owners[name] == owner {
+		delete(l.owners, name)
+		delete(l.expiry, name)
+	}
+	return nil
+}
+
+func (l *memoryLocks) owner(name string) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.owners[name]
+}
+
+// memoryPublisher records the published events, failing every publish with err when set
+type memoryPublisher struct {
+	mu     sync.Mutex
+	err    error
+	events []schema.ConversionEvent
+}
+
+func (p *memoryPublisher) PublishConversionJobConfirmed(_ context.Context, event schema.ConversionEvent, _, _ string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return p.err
+	}
+	p.events = append(p.events, event)
+	return nil
+}
+
+func (p *memoryPublisher) published() []schema.ConversionEvent {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]schema.ConversionEvent(nil), p.events...)
+}
+
+// newTestRelay creates a relay polling every second and giving up after 3 publishes
+func newTestRelay(repo *memoryRepository, locks *memoryLocks, publisher *memoryPublisher) *Relay {
+	config.AppConfig.OutboxPollInterval = time.Second
+	config.AppConfig.OutboxMaxAttempts = 3
+
+	return &Relay{id: repository.NewOwnerID(), repo: repo, locks: locks, publisher: publisher}
+}
+
+func queuedConversion() *domain.Conversion {
+	now := time.Now()
+	return &domain.Conversion{
+		ID:         "conversion-1",
+		Conversion: domain.ConversionData{TargetFormat: ".stl", Status: domain.ConversionPending},
+		Job:        domain.ConversionJob{ID: "job-1", CreatedAt: now},
+		Outbox: &domain.OutboxMessage{
+			ID:            "message-1",
+			JobID:         "job-1",
+			Attempt:       1,
+			Status:        domain.OutboxPending,
+			CreatedAt:     now,
+			NextAttemptAt: now,
+		},
+	}
+}
+
+func TestRelayMarksPublishedMessageSent(t *testing.T) {
+	repo := newMemoryRepository(queuedConversion())
+	publisher := &memoryPublisher{}
+	r := newTestRelay(repo, newMemoryLocks(), publisher)
+
+	r.relay(context.Background())
+
+	events := publisher.published()
+	require.Len(t, events, 1)
+	assert.Equal(t, "job-1", events[0].JobID)
+	assert.Equal(t, "conversion-1", events[0].ConversionID)
+	assert.Equal(t, 1, events[0].Attempt)
+
+	message := repo.get("conversion-1").Outbox
+	assert.Equal(t, domain.OutboxSent, message.Status)
+	assert.NotNil(t, message.SentAt)
+
+	// a sent message is not published again
+	r.relay(context.Background())
+	assert.Len(t, publisher.published(), 1)
+}
+
+func TestRelayBacksOffFailedPublish(t *testing.T) {
+	repo := newMemoryRepository(queuedConversion())
+	publisher := &memoryPublisher{err: errors.New("channel closed")}
+	r := newTestRelay(repo, newMemoryLocks(), publisher)
+
+	before := time.Now()
+	r.relay(context.Background())
+
+	message := repo.get("conversion-1").Outbox
+	assert.Equal(t, domain.OutboxPending, message.Status)
+	assert.Equal(t, 1, message.PublishAttempts)
+	assert.Equal(t, "channel closed", message.LastError)
+	assert.False(t, message.NextAttemptAt.Before(before.Add(time.Second)))
+
+	// the message is not due before its backoff elapsed
+	r.relay(context.Background())
+	assert.Equal(t, 1, repo.get("conversion-1").Outbox.PublishAttempts)
+
+	// the backoff doubles with every failure
+	repo.conversions["conversion-1"].Outbox.NextAttemptAt = time.Now()
+	before = time.Now()
+	r.relay(context.Background())
+
+	message = repo.get("conversion-1").Outbox
+	assert.Equal(t, 2, message.PublishAttempts)
+	assert.False(t, message.NextAttemptAt.Before(before.Add(2*time.Second)))
+	assert.Equal(t, domain.ConversionPending, repo.get("conversion-1").Conversion.Status)
+}
+
+func TestRelayFailsConversionAfterMaxAttempts(t *testing.T) {
+	conversion := queuedConversion()
+	conversion.Outbox.PublishAttempts = 2
+	repo := newMemoryRepository(conversion)
+	publisher := &memoryPublisher{err: errors.New("channel closed")}
+	r := newTestRelay(repo, newMemoryLocks(), publisher)
+
+	r.relay(context.Background())
+
+	stored := repo.get("conversion-1")
+	assert.Equal(t, domain.ConversionFailed, stored.Conversion.Status)
+	require.NotNil(t, stored.Conversion.Error)
+	assert.Equal(t, domain.ErrorCodePublishFailed, stored.Conversion.Error.Code)
+	assert.True(t, stored.Conversion.Error.Retryable)
+	assert.Equal(t, domain.OutboxFailed, stored.Outbox.Status)
+	assert.Equal(t, 3, stored.Outbox.PublishAttempts)
+}
+
+func TestRelayGivesUpMessageOfCancelledConversion(t *testing.T) {
+	conversion := queuedConversion()
+	conversion.Conversion.Status = domain.ConversionCancelled
+	conversion.Outbox.PublishAttempts = 2
+	repo := newMemoryRepository(conversion)
+	publisher := &memoryPublisher{err: errors.New("channel closed")}
+	r := newTestRelay(repo, newMemoryLocks(), publisher)
+
+	r.relay(context.Background())
+
+	stored := repo.get("conversion-1")
+	assert.Equal(t, domain.ConversionCancelled, stored.Conversion.Status)
+	assert.Equal(t, domain.OutboxFailed, stored.Outbox.Status)
+}
+
+func TestRelayStopsBatchOnceLockIsLost(t *testing.T) {
+	second := queuedConversion()
+	second.ID = "conversion-2"
+	second.Outbox.ID = "message-2"
+	repo := newMemoryRepository(queuedConversion(), second)
+	locks := newMemoryLocks()
+	publisher := &memoryPublisher{}
+	r := newTestRelay(repo, locks, publisher)
+
+	// another instance takes over while the first message is published
+	takeover := &takeoverPublisher{memoryPublisher: publisher, locks: locks}
+	r.publisher = takeover
+
+	r.relay(context.Background())
+
+	assert.Len(t, publisher.published(), 1)
+	assert.Equal(t, "other-relay", locks.owner(relayLock))
+}
+
+// takeoverPublisher hands the relay lock to another instance on the first publish
+type takeoverPublisher struct {
+	*memoryPublisher
+	locks *memoryLocks
+}
+
+func (p *takeoverPublisher) PublishConversionJobConfirmed(ctx context.Context, event schema.ConversionEvent, exchange, routingKey string) error {
+	p.locks.mu.Lock()
+	p.locks.owners[relayLock] = "other-relay"
+	p.locks.expiry[relayLock] = time.Now().Add(time.Minute)
+	p.locks.mu.Unlock()
+	return p.memoryPublisher.PublishConversionJobConfirmed(ctx, event, exchange, routingKey)
+}
+
+func TestRelayPublishesOnlyAsLeader(t *testing.T) {
+	repo := newMemoryRepository(queuedConversion())
+	locks := newMemoryLocks()
+	publisher := &memoryPublisher{}
+	r := newTestRelay(repo, locks, publisher)
+	config.AppConfig.OutboxPollInterval = 10 * time.Millisecond
+
+	_, err := locks.AcquireLock(context.Background(), relayLock, "other-relay", 100*time.Millisecond)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.Run(ctx)
+	}()
+
+	// another instance leads until its lock expires
+	time.Sleep(50 * time.Millisecond)
+	assert.Empty(t, publisher.published())
+
+	assert.Eventually(t, func() bool {
+		return len(publisher.published()) == 1
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, r.id, locks.owner(relayLock))
+
+	cancel()
+	<-done
+	assert.Empty(t, locks.owner(relayLock))
+}
@@ -0,0 +1,290 @@
+// Package render draws PNG previews of a scene on the CPU: an orthographic view
+// from a configurable direction, rasterized with a depth buffer and flat shading.
+package render
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+
+	"github.com/wildan3105/converto/pkg/scene"
+)
+
+const (
+	// DefaultSize is the default width and height of a preview, in pixels
+	DefaultSize = 512
+	// DefaultAzimuth and DefaultElevation give the classic isometric view, in degrees
+	DefaultAzimuth   = 45
+	DefaultElevation = 35.264
+	// MaxSize bounds the width and height of a preview
+	MaxSize = 4096
+
+	// supersample is the number of samples per pixel along each axis
+	supersample = 2
+	// margin is the fraction of the image left empty around the model
+	margin = 0.05
+	// ambient is the share of the light that does not depend on the surface orientation
+	ambient = 0.3
+)
+
+// defaultColor shades meshes without a material or vertex colours
+var defaultColor = scene.Color{0.7, 0.72, 0.75, 1}
+
+// ErrEmptyScene is returned when the scene has no triangles to draw
+var ErrEmptyScene = errors.New("render: scene has no triangles")
+
+// Options configures the view. Azimuth is the camera angle around the Z (up) axis,
+// measured from +X towards +Y, and Elevation the angle above the XY plane, both in degrees.
+// The background is transparent.
+type Options struct {
+	Width     int
+	Height    int
+	Azimuth   float64
+	Elevation float64
+}
+
+// DefaultOptions returns a square isometric view
+func DefaultOptions() Options {
+	return Options{
+		Width:     DefaultSize,
+		Height:    DefaultSize,
+		Azimuth:   DefaultAzimuth,
+		Elevation: DefaultElevation,
+	}
+}
+
+// EncodePNG renders the scene and writes it as a PNG image
+func EncodePNG(w io.Writer, s *scene.Scene, opts Options) error {
+	img, err := Render(s, opts)
+	if err != nil {
+		return err
+	}
+
+	return png.Encode(w, img)
+}
+
+// Render draws the scene, scaled to fit the image
+func Render(s *scene.Scene, opts Options) (*image.NRGBA, error) {
+	if opts.Width <= 0 || opts.Height <= 0 || opts.Width > MaxSize || opts.Height > MaxSize {
+		return nil, errors.New("render: image size out of range")
+	}
+
+	triangles := collect(s)
+	if len(triangles) == 0 {
+		return nil, ErrEmptyScene
+	}
+
+	cam := newCamera(opts.Azimuth, opts.Elevation)
+	width, height := opts.Width*supersample, opts.Height*supersample
+	fb := newFramebuffer(width, height)
+
+	// fit the projected model into the image
+	min := [2]float64{math.Inf(1), math.Inf(1)}
+	max := [2]float64{math.Inf(-1), math.Inf(-1)}
+	for i := range triangles {
+		for _, p := range triangles[i].corners {
+			x, y := p.Dot(cam.right), p.Dot(cam.up)
+			min[0], min[1] = math.Min(min[0], x), math.Min(min[1], y)
+			max[0], max[1] = math.Max(max[0], x), math.Max(max[1], y)
+		}
+	}
+
+	extent := math.Max((max[0]-min[0])/float64(width), (max[1]-min[1])/float64(height))
+	scale := (1 - 2*margin) / math.Max(extent, 1e-300)
+	center := [2]float64{(min[0] + max[0]) / 2, (min[1] + max[1]) / 2}
+
+	project := func(p scene.Vec3) scene.Vec3 {
+		return scene.Vec3{
+			float64(width)/2 + (p.Dot(cam.right)-center[0])*scale,
+			float64(height)/2 - (p.Dot(cam.up)-center[1])*scale,
+			p.Dot(cam.back),
+		}
+	}
+
+	for i := range triangles {
+		t := &triangles[i]
+		normal := scene.FaceNormal(t.corners[0], t.corners[1], t.corners[2])
+		// lit from both sides so that open or inconsistently wound meshes still read well
+		intensity := ambient + (1-ambient)*math.Abs(normal.Dot(cam.light))
+
+		c := color.NRGBA{
+			R: channel(t.color[0] * intensity),
+			G: channel(t.color[1] * intensity),
+			B: channel(t.color[2] * intensity),
+			A: channel(t.color[3]),
+		}
+		fb.triangle(project(t.corners[0]), project(t.corners[1]), project(t.corners[2]), c)
+	}
+
+	return fb.downsample(opts.Width, opts.Height), nil
+}
+
+// triangle is a world-space triangle with its flat colour
+type triangle struct {
+	corners [3]scene.Vec3
+	color   scene.Color
+}
+
+// collect gathers every triangle of the scene in world space with its colour
+func collect(s *scene.Scene) []triangle {
+	var triangles []triangle
+	for b := range s.Bodies {
+		body := &s.Bodies[b]
+		identity := body.Transform.IsIdentity() || body.Transform == (scene.Mat4{})
+
+		for m := range body.Meshes {
+			mesh := &body.Meshes[m]
+			base := defaultColor
+			if mesh.Material >= 0 && mesh.Material < len(s.Materials) {
+				base = s.Materials[mesh.Material].BaseColor
+			}
+
+			for i := 0; i < mesh.TriangleCount(); i++ {
+				a, b, c := mesh.Triangle(i)
+				if !identity {
+					a, b, c = body.Transform.TransformPoint(a), body.Transform.TransformPoint(b), body.Transform.TransformPoint(c)
+				}
+
+				t := triangle{corners: [3]scene.Vec3{a, b, c}, color: base}
+				if len(mesh.Colors) > 0 {
+					t.color = averageColor(mesh, i)
+				}
+				triangles = append(triangles, t)
+			}
+		}
+	}
+	return triangles
+}
+
+// averageColor returns the mean of the vertex colours of the i-th triangle
+func averageColor(mesh *scene.Mesh, i int) scene.Color {
+	var c scene.Color
+	for k := 0; k < 3; k++ {
+		vertex := mesh.Colors[mesh.Indices[3*i+k]]
+		for j := range c {
+			c[j] += vertex[j] / 3
+		}
+	}
+	return c
+}
+
+// camera holds an orthonormal view basis: back points from the model towards the viewer
+type camera struct {
+	right, up, back scene.Vec3
+	light           scene.Vec3
+}
+
+func newCamera(azimuth, elevation float64) camera {
+	// looking straight down or up leaves the right vector undefined
+	elevation = math.Max(-89.9, math.Min(89.9, elevation))
+	a, e := azimuth*math.Pi/180, elevation*math.Pi/180
+
+	back := scene.Vec3{math.Cos(e) * math.Cos(a), math.Cos(e) * math.Sin(a), math.Sin(e)}
+	right := scene.Vec3{0, 0, 1}.Cross(back).Normalize()
+	up := back.Cross(right)
+
+	// a key light above and to the left of the viewer
+	light := back.Add(up.Scale(0.6)).Sub(right.Scale(0.4)).Normalize()
+
+	return camera{right: right, up: up, back: back, light: light}
+}
+
+// framebuffer is a supersampled colour and depth buffer
+type framebuffer struct {
+	width, height int
+	pixels        []color.NRGBA
+	depth         []float64
+}
+
+func newFramebuffer(width, height int) *framebuffer {
+	fb := &framebuffer{
+		width:  width,
+		height: height,
+		pixels: make([]color.NRGBA, width*height),
+		depth:  make([]float64, width*height),
+	}
+	for i := range fb.depth {
+		fb.depth[i] = math.Inf(-1)
+	}
+	return fb
+}
+
+// triangle rasterizes a projected triangle; x and y are in pixels, z grows towards the viewer
+func (fb *framebuffer) triangle(p0, p1, p2 scene.Vec3, c color.NRGBA) {
+	area := edge(p0, p1, p2)
+	if area == 0 || math.IsNaN(area) {
+		return
+	}
+
+	minX := int(math.Max(0, math.Floor(math.Min(p0[0], math.Min(p1[0], p2[0])))))
+	maxX := int(math.Min(float64(fb.width-1), math.Ceil(math.Max(p0[0], math.Max(p1[0], p2[0])))))
+	minY := int(math.Max(0, math.Floor(math.Min(p0[1], math.Min(p1[1], p2[1])))))
+	maxY := int(math.Min(float64(fb.height-1), math.Ceil(math.Max(p0[1], math.Max(p1[1], p2[1])))))
+
+	for y := minY; y <= maxY; y++ {
+		for x := minX; x <= maxX; x++ {
+			p := scene.Vec3{float64(x) + 0.5, float64(y) + 0.5, 0}
+
+			// barycentric weights; their sign matches the area's for points inside
+			w0 := edge(p1, p2, p) / area
+			w1 := edge(p2, p0, p) / area
+			w2 := edge(p0, p1, p) / area
+			if w0 < 0 || w1 < 0 || w2 < 0 {
+				continue
+			}
+
+			z := w0*p0[2] + w1*p1[2] + w2*p2[2]
+			i := y*fb.width + x
+			if z > fb.depth[i] {
+				fb.depth[i] = z
+				fb.pixels[i] = c
+			}
+		}
+	}
+}
+
+// downsample averages each supersample block into one output pixel
+func (fb *framebuffer) downsample(width, height int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	samples := supersample * supersample
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			// average premultiplied colours so that edges blend into the transparent background
+			var r, g, b, a int
+			for sy := 0; sy < supersample; sy++ {
+				for sx := 0; sx < supersample; sx++ {
+					p := fb.pixels[(y*supersample+sy)*fb.width+x*supersample+sx]
+					r += int(p.R) * int(p.A)
+					g += int(p.G) * int(p.A)
+					b += int(p.B) * int(p.A)
+					a += int(p.A)
+				}
+			}
+
+			if a == 0 {
+				continue
+			}
+			img.SetNRGBA(x, y, color.NRGBA{
+				R: uint8(r / a),
+				G: uint8(g / a),
+				B: uint8(b / a),
+				A: uint8(a / samples),
+			})
+		}
+	}
+	return img
+}
+
+// edge returns twice the signed area of the triangle (a, b, c) in screen space
+func edge(a, b, c scene.Vec3) float64 {
+	return (b[0]-a[0])*(c[1]-a[1]) - (b[1]-a[1])*(c[0]-a[0])
+}
+
+// channel converts a colour component in [0, 1] to 8 bits
+func channel(v float64) uint8 {
+	return uint8(math.Round(math.Max(0, math.Min(1, v)) * 255))
+}
@@ -0,0 +1,47 @@
+package render
+
+import (
+	"bytes"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wildan3105/converto/pkg/scene"
+	"github.com/wildan3105/converto/pkg/scene/scenetest"
+)
+
+func TestRenderCube(t *testing.T) {
+	img, err := Render(scenetest.Cube(10), Options{Width: 64, Height: 48, Azimuth: DefaultAzimuth, Elevation: DefaultElevation})
+	require.NoError(t, err)
+
+	assert.Equal(t, 64, img.Bounds().Dx())
+	assert.Equal(t, 48, img.Bounds().Dy())
+
+	// the model is centred on a transparent background
+	assert.Equal(t, uint8(0), img.NRGBAAt(0, 0).A)
+	assert.Equal(t, uint8(255), img.NRGBAAt(32, 24).A)
+
+	// the three visible faces are shaded differently
+	top, left, right := img.NRGBAAt(32, 10), img.NRGBAAt(22, 30), img.NRGBAAt(42, 30)
+	assert.NotEqual(t, top, left)
+	assert.NotEqual(t, left, right)
+	assert.NotEqual(t, top, right)
+}
+
+func TestEncodePNG(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, EncodePNG(&buf, scenetest.Cube(1), DefaultOptions()))
+
+	img, err := png.Decode(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultSize, img.Bounds().Dx())
+}
+
+func TestRenderErrors(t *testing.T) {
+	_, err := Render(scene.New(), DefaultOptions())
+	assert.ErrorIs(t, err, ErrEmptyScene)
+
+	_, err = Render(scenetest.Cube(1), Options{Width: 0, Height: 10})
+	assert.Error(t, err)
+}
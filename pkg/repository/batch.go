@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	config "github.com/wildan3105/converto/configs"
+	"github.com/wildan3105/converto/pkg/domain"
+	"github.com/wildan3105/converto/pkg/infrastructure/circuitbreaker"
+	"github.com/wildan3105/converto/pkg/infrastructure/mongodb"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// BatchRepository defines database operations for batches
+type BatchRepository interface {
+	CreateBatch(ctx context.Context, batch *domain.Batch) (string, error)
+	GetBatchByID(ctx context.Context, batchID string) (*domain.Batch, error)
+	UpdateBatch(ctx context.Context, batchID string, updateData bson.M) error
+}
+
+// BatchRepositoryHandler is the concrete implementation of BatchRepository
+type BatchRepositoryHandler struct {
+	collection     *mongo.Collection
+	circuitbreaker *circuitbreaker.CircuitBreaker
+}
+
+// NewMongoBatchRepository creates a new instance of BatchRepository
+func NewMongoBatchRepository(mongoClient *mongo.Client, dbName string) *BatchRepositoryHandler {
+	cb := circuitbreaker.NewCircuitBreaker(3, 10*time.Second) // 3 failures, 10second cooldown
+	return &BatchRepositoryHandler{
+		collection:     mongoClient.Database(dbName).Collection(config.AppConfig.BatchCollection),
+		circuitbreaker: cb,
+	}
+}
+
+// CreateBatch inserts a new batch document
+func (r *BatchRepositoryHandler) CreateBatch(ctx context.Context, batch *domain.Batch) (string, error) {
+	ctx, cancel := mongodb.WithTimeout(ctx)
+	defer cancel()
+
+	batch.ID = primitive.NewObjectID().Hex()
+	batch.CreatedAt = time.Now()
+
+	err := r.circuitbreaker.Execute(func() error {
+		_, err := r.collection.InsertOne(ctx, batch)
+		if err != nil {
+			if mongo.IsTimeout(err) || errors.Is(err, context.DeadlineExceeded) {
+				log.Info("Error: database operation timed out")
+				return errors.New("database operation timed out")
+			}
+			return err
+		}
+		return nil
+	})
+
+	if err != nil {
+		log.Error("CreateBatch failed: %v\n", err)
+		return "", err
+	}
+
+	return batch.ID, nil
+}
+
+// GetBatchByID retrieves a batch document by ID
+func (r *BatchRepositoryHandler) GetBatchByID(ctx context.Context, batchID string) (*domain.Batch, error) {
+	ctx, cancel := mongodb.WithTimeout(ctx)
+	defer cancel()
+
+	var batch domain.Batch
+	err := r.collection.FindOne(ctx, bson.M{"_id": batchID}).Decode(&batch)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &batch, nil
+}
+
+// UpdateBatch updates a batch document by ID
+func (r *BatchRepositoryHandler) UpdateBatch(ctx context.Context, batchID string, updateData bson.M) error {
+	ctx, cancel := mongodb.WithTimeout(ctx)
+	defer cancel()
+
+	res, err := r.collection.UpdateOne(ctx, bson.M{"_id": batchID}, bson.M{"$set": updateData})
+	if err != nil {
+		return err
+	}
+
+	if res.MatchedCount == 0 {
+		return errors.New("batch not found")
+	}
+
+	return nil
+}
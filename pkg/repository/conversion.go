@@ -22,9 +22,30 @@ type ConversionRepository interface {
 	CreateConversion(ctx context.Context, conversion *domain.Conversion) (string, error)
 	GetConversionByID(ctx context.Context, conversionID string) (*domain.Conversion, error)
 	UpdateConversion(ctx context.Context, conversionID string, updateData bson.M) error
+	TransitionConversion(ctx context.Context, conversionID string, to domain.ConversionStatus, updateData bson.M, from ...domain.ConversionStatus) error
+	AppendAttempt(ctx context.Context, conversionID string, attempt domain.Attempt) error
+	ClaimJob(ctx context.Context, conversionID, jobID string, lease domain.Lease) (bool, error)
+	RenewLease(ctx context.Context, conversionID, owner string, expiresAt time.Time) (bool, error)
+	ReleaseLease(ctx context.Context, conversionID, owner string) error
+	ListExpiredLeases(ctx context.Context, now, unleasedBefore time.Time, limit int) ([]*domain.Conversion, error)
+	ReclaimConversion(ctx context.Context, conversionID string, lease *domain.Lease, to domain.ConversionStatus, updateData bson.M) (bool, error)
+	ListPendingOutbox(ctx context.Context, now time.Time, limit int) ([]*domain.Conversion, error)
+	UpdateOutbox(ctx context.Context, conversionID, messageID string, updateData bson.M) (bool, error)
 	ListConversions(ctx context.Context, status string, limit, offset int) ([]*domain.Conversion, error)
+	ListConversionsByUploadID(ctx context.Context, uploadID string) ([]*domain.Conversion, error)
+	ListConversionsByBatchID(ctx context.Context, batchID string) ([]*domain.Conversion, error)
 }
 
+// Unset, as the value of a field in the update data of TransitionConversion, removes the field
+// from the document instead of storing a value
+var Unset = unsetField{}
+
+type unsetField struct{}
+
+// ErrStatusUpdate is returned when a plain update sets the status of a conversion, which only
+// TransitionConversion may change
+var ErrStatusUpdate = errors.New("conversion status can only change through a transition")
+
 // ConversionRepositoryHandler is the concrete implementation of ConversionRepository
 type ConversionRepositoryHandler struct {
 	collection     *mongo.Collection
@@ -42,6 +63,19 @@ func NewMongoRepository(mongoClient *mongo.Client, dbName string) *ConversionRep
 	}
 }
 
+// EnsureIndexes creates the indexes the queries of the repository rely on, unless they exist. The
+// outbox relay polls for pending messages every poll interval.
+func (r *ConversionRepositoryHandler) EnsureIndexes(ctx context.Context) error {
+	ctx, cancel := mongodb.WithTimeout(ctx)
+	defer cancel()
+
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "outbox.status", Value: 1}, {Key: "outbox.nextAttemptAt", Value: 1}},
+		Options: options.Index().SetName("outbox_pending"),
+	})
+	return err
+}
+
 // CreateConversion inserts a new conversion document
 func (r *ConversionRepositoryHandler) CreateConversion(ctx context.Context, conversion *domain.Conversion) (string, error) {
 	ctx, cancel := mongodb.WithTimeout(ctx)
@@ -87,8 +121,12 @@ func (r *ConversionRepositoryHandler) GetConversionByID(ctx context.Context, con
 	return &conversion, nil
 }
 
-// UpdateConversion updates a conversion document by ID
+// UpdateConversion updates a conversion document by ID. It does not change its status.
 func (r *ConversionRepositoryHandler) UpdateConversion(ctx context.Context, conversionID string, updateData bson.M) error {
+	if _, ok := updateData["conversion.status"]; ok {
+		return ErrStatusUpdate
+	}
+
 	ctx, cancel := mongodb.WithTimeout(ctx)
 	defer cancel()
 
@@ -105,7 +143,7 @@ func (r *ConversionRepositoryHandler) UpdateConversion(ctx context.Context, conv
 
 	if res.MatchedCount == 0 {
 		log.Info("No document matched the provided conversion ID.")
-		return errors.New("conversion not found")
+		return domain.ErrConversionNotFound
 	}
 
 	if res.ModifiedCount > 0 {
@@ -117,6 +155,288 @@ func (r *ConversionRepositoryHandler) UpdateConversion(ctx context.Context, conv
 	return nil
 }
 
+// TransitionConversion moves a conversion to status to and applies updateData, removing the fields
+// set to Unset, only while its status is one of from, or any status that may move to to when from is empty. The transition
+// must be allowed by the domain state machine. It returns a domain.TransitionError when the
+// conversion is in another status, and domain.ErrConversionNotFound when it does not exist.
+func (r *ConversionRepositoryHandler) TransitionConversion(ctx context.Context, conversionID string, to domain.ConversionStatus, updateData bson.M, from ...domain.ConversionStatus) error {
+	if err := domain.ValidateTransition(to, from...); err != nil {
+		return err
+	}
+	if len(from) == 0 {
+		from = domain.TransitionSources(to)
+	}
+
+	set := bson.M{"conversion.status": to}
+	unset := bson.M{}
+	for key, value := range updateData {
+		if value == Unset {
+			unset[key] = ""
+			continue
+		}
+		set[key] = value
+	}
+
+	ctx, cancel := mongodb.WithTimeout(ctx)
+	defer cancel()
+
+	filter := bson.M{
+		"_id":               conversionID,
+		"conversion.status": bson.M{"$in": from},
+	}
+	update := bson.M{
+		"$set":         set,
+		"$currentDate": bson.M{"job.updatedAt": true},
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	res, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount > 0 {
+		return nil
+	}
+
+	var current domain.Conversion
+	err = r.collection.FindOne(ctx, bson.M{"_id": conversionID}, options.FindOne().SetProjection(bson.M{"conversion.status": 1})).Decode(&current)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return domain.ErrConversionNotFound
+		}
+		return err
+	}
+
+	return &domain.TransitionError{ConversionID: conversionID, From: current.Conversion.Status, To: to}
+}
+
+// AppendAttempt records a run of a conversion's job
+func (r *ConversionRepositoryHandler) AppendAttempt(ctx context.Context, conversionID string, attempt domain.Attempt) error {
+	ctx, cancel := mongodb.WithTimeout(ctx)
+	defer cancel()
+
+	filter := bson.M{"_id": conversionID}
+	update := bson.M{
+		"$push":        bson.M{"conversion.attempts": attempt},
+		"$currentDate": bson.M{"job.updatedAt": true},
+	}
+
+	res, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if res.MatchedCount == 0 {
+		return errors.New("conversion not found")
+	}
+
+	return nil
+}
+
+// ClaimJob atomically claims the attempt lease.Attempt of the job jobID on a conversion for a
+// worker: it takes the lease, records the attempt and marks the conversion in progress. It reports
+// whether the job was claimed. The conversion must still run that job, be pending or in progress and
+// not be leased. The attempt must be newer than the last claimed one, or be that one when it is still
+// in progress, as it is when the worker running it stopped before finishing it. Any other delivery is
+// a duplicate. Events published without a job ID match any job.
+func (r *ConversionRepositoryHandler) ClaimJob(ctx context.Context, conversionID, jobID string, lease domain.Lease) (bool, error) {
+	ctx, cancel := mongodb.WithTimeout(ctx)
+	defer cancel()
+
+	filter := bson.M{
+		"_id":               conversionID,
+		"conversion.status": bson.M{"$in": domain.TransitionSources(domain.ConversionInProgress)},
+		"$and": bson.A{
+			bson.M{"$or": bson.A{
+				bson.M{"job.lease": nil},
+				bson.M{"job.lease.expiresAt": bson.M{"$lte": lease.AcquiredAt}},
+			}},
+			bson.M{"$or": bson.A{
+				bson.M{"job.attempt": nil},
+				bson.M{"job.attempt": bson.M{"$lt": lease.Attempt}},
+				bson.M{"job.attempt": lease.Attempt, "conversion.status": domain.ConversionInProgress},
+			}},
+		},
+	}
+	if jobID != "" {
+		filter["job.id"] = jobID
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"job.lease":         lease,
+			"job.attempt":       lease.Attempt,
+			"conversion.status": domain.ConversionInProgress,
+		},
+		"$currentDate": bson.M{"job.updatedAt": true},
+	}
+
+	res, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+
+	return res.MatchedCount > 0, nil
+}
+
+// RenewLease extends the lease of owner on a conversion. It reports whether owner still held it.
+func (r *ConversionRepositoryHandler) RenewLease(ctx context.Context, conversionID, owner string, expiresAt time.Time) (bool, error) {
+	ctx, cancel := mongodb.WithTimeout(ctx)
+	defer cancel()
+
+	filter := bson.M{"_id": conversionID, "job.lease.owner": owner}
+	update := bson.M{
+		"$set":         bson.M{"job.lease.expiresAt": expiresAt},
+		"$currentDate": bson.M{"job.updatedAt": true},
+	}
+
+	res, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+
+	return res.MatchedCount > 0, nil
+}
+
+// ReleaseLease removes the lease of owner on a conversion, if it still holds it
+func (r *ConversionRepositoryHandler) ReleaseLease(ctx context.Context, conversionID, owner string) error {
+	ctx, cancel := mongodb.WithTimeout(ctx)
+	defer cancel()
+
+	filter := bson.M{"_id": conversionID, "job.lease.owner": owner}
+	update := bson.M{"$unset": bson.M{"job.lease": ""}}
+
+	_, err := r.collection.UpdateOne(ctx, filter, update)
+	return err
+}
+
+// ListExpiredLeases retrieves up to limit conversions in progress whose lease expired before now.
+// Conversions in progress without a lease, left by workers predating leases, are included once
+// they have not been updated since unleasedBefore.
+func (r *ConversionRepositoryHandler) ListExpiredLeases(ctx context.Context, now, unleasedBefore time.Time, limit int) ([]*domain.Conversion, error) {
+	ctx, cancel := mongodb.WithTimeout(ctx)
+	defer cancel()
+
+	filter := bson.M{
+		"conversion.status": domain.ConversionInProgress,
+		"$or": bson.A{
+			bson.M{"job.lease.expiresAt": bson.M{"$lt": now}},
+			bson.M{"job.lease": nil, "job.updatedAt": bson.M{"$lt": unleasedBefore}},
+		},
+	}
+
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetLimit(int64(limit)))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var conversions []*domain.Conversion
+	for cursor.Next(ctx) {
+		var conversion domain.Conversion
+		if err := cursor.Decode(&conversion); err != nil {
+			return nil, err
+		}
+		conversions = append(conversions, &conversion)
+	}
+
+	return conversions, nil
+}
+
+// ReclaimConversion moves a conversion in progress to status to and removes its lease, while the
+// lease is still the given expired one, or while it has no lease when lease is nil. It reports
+// whether the conversion was updated; it is not once the worker renewed its lease or the
+// conversion moved on.
+func (r *ConversionRepositoryHandler) ReclaimConversion(ctx context.Context, conversionID string, lease *domain.Lease, to domain.ConversionStatus, updateData bson.M) (bool, error) {
+	if err := domain.ValidateTransition(to, domain.ConversionInProgress); err != nil {
+		return false, err
+	}
+
+	set := bson.M{"conversion.status": to}
+	for key, value := range updateData {
+		set[key] = value
+	}
+
+	ctx, cancel := mongodb.WithTimeout(ctx)
+	defer cancel()
+
+	filter := bson.M{
+		"_id":               conversionID,
+		"conversion.status": domain.ConversionInProgress,
+	}
+	if lease == nil {
+		filter["job.lease"] = nil
+	} else {
+		filter["job.lease.owner"] = lease.Owner
+		filter["job.lease.expiresAt"] = lease.ExpiresAt
+	}
+
+	update := bson.M{
+		"$set":         set,
+		"$unset":       bson.M{"job.lease": ""},
+		"$currentDate": bson.M{"job.updatedAt": true},
+	}
+
+	res, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+
+	return res.MatchedCount > 0, nil
+}
+
+// ListPendingOutbox retrieves up to limit conversions whose outbox message waits to be published
+// and is due at now, oldest message first
+func (r *ConversionRepositoryHandler) ListPendingOutbox(ctx context.Context, now time.Time, limit int) ([]*domain.Conversion, error) {
+	ctx, cancel := mongodb.WithTimeout(ctx)
+	defer cancel()
+
+	filter := bson.M{
+		"outbox.status":        domain.OutboxPending,
+		"outbox.nextAttemptAt": bson.M{"$lte": now},
+	}
+	opts := options.Find().SetSort(bson.M{"outbox.createdAt": 1}).SetLimit(int64(limit))
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var conversions []*domain.Conversion
+	for cursor.Next(ctx) {
+		var conversion domain.Conversion
+		if err := cursor.Decode(&conversion); err != nil {
+			return nil, err
+		}
+		conversions = append(conversions, &conversion)
+	}
+
+	return conversions, nil
+}
+
+// UpdateOutbox updates the outbox message of a conversion while it is the pending message
+// messageID. It reports whether it was.
+func (r *ConversionRepositoryHandler) UpdateOutbox(ctx context.Context, conversionID, messageID string, updateData bson.M) (bool, error) {
+	ctx, cancel := mongodb.WithTimeout(ctx)
+	defer cancel()
+
+	filter := bson.M{
+		"_id":           conversionID,
+		"outbox.id":     messageID,
+		"outbox.status": domain.OutboxPending,
+	}
+
+	res, err := r.collection.UpdateOne(ctx, filter, bson.M{"$set": updateData})
+	if err != nil {
+		return false, err
+	}
+
+	return res.MatchedCount > 0, nil
+}
+
 // ListConversions retrieves a list of conversion documents with optional status filtering
 func (r *ConversionRepositoryHandler) ListConversions(ctx context.Context, status string, limit, offset int) ([]*domain.Conversion, error) {
 	ctx, cancel := mongodb.WithTimeout(ctx)
@@ -150,3 +470,40 @@ func (r *ConversionRepositoryHandler) ListConversions(ctx context.Context, statu
 
 	return conversions, nil
 }
+
+// ListConversionsByUploadID retrieves every conversion created from an upload, in creation order
+func (r *ConversionRepositoryHandler) ListConversionsByUploadID(ctx context.Context, uploadID string) ([]*domain.Conversion, error) {
+	return r.findInCreationOrder(ctx, bson.M{"uploadId": uploadID})
+}
+
+// ListConversionsByBatchID retrieves every conversion created from a batch, in creation order
+func (r *ConversionRepositoryHandler) ListConversionsByBatchID(ctx context.Context, batchID string) ([]*domain.Conversion, error) {
+	return r.findInCreationOrder(ctx, bson.M{"batchId": batchID})
+}
+
+// findInCreationOrder retrieves every conversion matching the filter, oldest first
+func (r *ConversionRepositoryHandler) findInCreationOrder(ctx context.Context, filter bson.M) ([]*domain.Conversion, error) {
+	ctx, cancel := mongodb.WithTimeout(ctx)
+	defer cancel()
+
+	var conversions []*domain.Conversion
+
+	findOptions := options.Find()
+	findOptions.SetSort(bson.D{{Key: "job.createdAt", Value: 1}, {Key: "_id", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var conversion domain.Conversion
+		if err := cursor.Decode(&conversion); err != nil {
+			return nil, err
+		}
+		conversions = append(conversions, &conversion)
+	}
+
+	return conversions, nil
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+
+	config "github.com/wildan3105/converto/configs"
+	"github.com/wildan3105/converto/pkg/infrastructure/mongodb"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// LockRepository defines database operations for named locks, used to elect a single instance
+// to run a task
+type LockRepository interface {
+	AcquireLock(ctx context.Context, name, owner string, ttl time.Duration) (bool, error)
+	ReleaseLock(ctx context.Context, name, owner string) error
+}
+
+// LockRepositoryHandler is the concrete implementation of LockRepository.
+// Each lock is a document whose ID is the lock name.
+type LockRepositoryHandler struct {
+	collection *mongo.Collection
+}
+
+// NewMongoLockRepository creates a new instance of LockRepository
+func NewMongoLockRepository(mongoClient *mongo.Client, dbName string) *LockRepositoryHandler {
+	return &LockRepositoryHandler{
+		collection: mongoClient.Database(dbName).Collection(config.AppConfig.LockCollection),
+	}
+}
+
+// AcquireLock takes or extends a lock for ttl. It reports whether owner holds the lock, which
+// fails while another owner holds it and it has not expired.
+func (r *LockRepositoryHandler) AcquireLock(ctx context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	ctx, cancel := mongodb.WithTimeout(ctx)
+	defer cancel()
+
+	now := time.Now()
+	filter := bson.M{
+		"_id": name,
+		"$or": bson.A{
+			bson.M{"owner": owner},
+			bson.M{"expiresAt": bson.M{"$lt": now}},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{"owner": owner, "expiresAt": now.Add(ttl)},
+	}
+
+	_, err := r.collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if err != nil {
+		// the lock exists but is held by another owner, so the upsert collided with it
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+// ReleaseLock gives up a lock held by owner
+func (r *LockRepositoryHandler) ReleaseLock(ctx context.Context, name, owner string) error {
+	ctx, cancel := mongodb.WithTimeout(ctx)
+	defer cancel()
+
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": name, "owner": owner})
+	return err
+}
+
+// NewOwnerID returns an ID identifying the calling process as the owner of a lock or lease
+func NewOwnerID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	return fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), uuid.NewString()[:8])
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	config "github.com/wildan3105/converto/configs"
+	"github.com/wildan3105/converto/pkg/domain"
+	"github.com/wildan3105/converto/pkg/infrastructure/circuitbreaker"
+	"github.com/wildan3105/converto/pkg/infrastructure/mongodb"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// UploadRepository defines database operations for uploads
+type UploadRepository interface {
+	CreateUpload(ctx context.Context, upload *domain.Upload) (string, error)
+	GetUploadByID(ctx context.Context, uploadID string) (*domain.Upload, error)
+}
+
+// UploadRepositoryHandler is the concrete implementation of UploadRepository
+type UploadRepositoryHandler struct {
+	collection     *mongo.Collection
+	circuitbreaker *circuitbreaker.CircuitBreaker
+}
+
+// NewMongoUploadRepository creates a new instance of UploadRepository
+func NewMongoUploadRepository(mongoClient *mongo.Client, dbName string) *UploadRepositoryHandler {
+	cb := circuitbreaker.NewCircuitBreaker(3, 10*time.Second) // 3 failures, 10second cooldown
+	return &UploadRepositoryHandler{
+		collection:     mongoClient.Database(dbName).Collection(config.AppConfig.UploadCollection),
+		circuitbreaker: cb,
+	}
+}
+
+// CreateUpload inserts a new upload document
+func (r *UploadRepositoryHandler) CreateUpload(ctx context.Context, upload *domain.Upload) (string, error) {
+	ctx, cancel := mongodb.WithTimeout(ctx)
+	defer cancel()
+
+	upload.ID = primitive.NewObjectID().Hex()
+	upload.CreatedAt = time.Now()
+
+	err := r.circuitbreaker.Execute(func() error {
+		_, err := r.collection.InsertOne(ctx, upload)
+		if err != nil {
+			if mongo.IsTimeout(err) || errors.Is(err, context.DeadlineExceeded) {
+				log.Info("Error: database operation timed out")
+				return errors.New("database operation timed out")
+			}
+			return err
+		}
+		return nil
+	})
+
+	if err != nil {
+		log.Error("CreateUpload failed: %v\n", err)
+		return "", err
+	}
+
+	return upload.ID, nil
+}
+
+// GetUploadByID retrieves an upload document by ID
+func (r *UploadRepositoryHandler) GetUploadByID(ctx context.Context, uploadID string) (*domain.Upload, error) {
+	ctx, cancel := mongodb.WithTimeout(ctx)
+	defer cancel()
+
+	var upload domain.Upload
+	err := r.collection.FindOne(ctx, bson.M{"_id": uploadID}).Decode(&upload)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &upload, nil
+}
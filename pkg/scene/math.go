@@ -0,0 +1,160 @@
+package scene
+
+import "math"
+
+// Vec2 is a 2D vector, used for texture coordinates
+type Vec2 [2]float64
+
+// Vec3 is a 3D vector, used for positions and normals
+type Vec3 [3]float64
+
+// Color is a linear RGBA color with components between 0 and 1
+type Color [4]float64
+
+// Add returns v + o
+func (v Vec3) Add(o Vec3) Vec3 {
+	return Vec3{v[0] + o[0], v[1] + o[1], v[2] + o[2]}
+}
+
+// Sub returns v - o
+func (v Vec3) Sub(o Vec3) Vec3 {
+	return Vec3{v[0] - o[0], v[1] - o[1], v[2] - o[2]}
+}
+
+// Scale returns v multiplied by s
+func (v Vec3) Scale(s float64) Vec3 {
+	return Vec3{v[0] * s, v[1] * s, v[2] * s}
+}
+
+// Dot returns the dot product of v and o
+func (v Vec3) Dot(o Vec3) float64 {
+	return v[0]*o[0] + v[1]*o[1] + v[2]*o[2]
+}
+
+// Cross returns the cross product of v and o
+func (v Vec3) Cross(o Vec3) Vec3 {
+	return Vec3{
+		v[1]*o[2] - v[2]*o[1],
+		v[2]*o[0] - v[0]*o[2],
+		v[0]*o[1] - v[1]*o[0],
+	}
+}
+
+// Length returns the euclidean length of v
+func (v Vec3) Length() float64 {
+	return math.Sqrt(v.Dot(v))
+}
+
+// Normalize returns v scaled to unit length, or the zero vector if v has no length
+func (v Vec3) Normalize() Vec3 {
+	length := v.Length()
+	if length == 0 {
+		return Vec3{}
+	}
+	return v.Scale(1 / length)
+}
+
+// Min returns the component-wise minimum of v and o
+func (v Vec3) Min(o Vec3) Vec3 {
+	return Vec3{math.Min(v[0], o[0]), math.Min(v[1], o[1]), math.Min(v[2], o[2])}
+}
+
+// Max returns the component-wise maximum of v and o
+func (v Vec3) Max(o Vec3) Vec3 {
+	return Vec3{math.Max(v[0], o[0]), math.Max(v[1], o[1]), math.Max(v[2], o[2])}
+}
+
+// FaceNormal returns the unit normal of the counter-clockwise triangle (a, b, c)
+func FaceNormal(a, b, c Vec3) Vec3 {
+	return b.Sub(a).Cross(c.Sub(a)).Normalize()
+}
+
+// Mat4 is a 4x4 affine transform stored in column-major order
+type Mat4 [16]float64
+
+// Identity returns the identity transform
+func Identity() Mat4 {
+	return Mat4{
+		1, 0, 0, 0,
+		0, 1, 0, 0,
+		0, 0, 1, 0,
+		0, 0, 0, 1,
+	}
+}
+
+// Scaling returns a uniform scale transform
+func Scaling(factor float64) Mat4 {
+	return Mat4{
+		factor, 0, 0, 0,
+		0, factor, 0, 0,
+		0, 0, factor, 0,
+		0, 0, 0, 1,
+	}
+}
+
+// ZUpToYUp returns the rotation taking a Z-up coordinate system to a Y-up one
+func ZUpToYUp() Mat4 {
+	return Mat4{
+		1, 0, 0, 0,
+		0, 0, -1, 0,
+		0, 1, 0, 0,
+		0, 0, 0, 1,
+	}
+}
+
+// IsIdentity reports whether m is the identity transform
+func (m Mat4) IsIdentity() bool {
+	return m == Identity()
+}
+
+// Mul returns the product m * o, applying o first
+func (m Mat4) Mul(o Mat4) Mat4 {
+	var r Mat4
+	for col := 0; col < 4; col++ {
+		for row := 0; row < 4; row++ {
+			var sum float64
+			for k := 0; k < 4; k++ {
+				sum += m[k*4+row] * o[col*4+k]
+			}
+			r[col*4+row] = sum
+		}
+	}
+	return r
+}
+
+// TransformPoint applies m to the point p
+func (m Mat4) TransformPoint(p Vec3) Vec3 {
+	return Vec3{
+		m[0]*p[0] + m[4]*p[1] + m[8]*p[2] + m[12],
+		m[1]*p[0] + m[5]*p[1] + m[9]*p[2] + m[13],
+		m[2]*p[0] + m[6]*p[1] + m[10]*p[2] + m[14],
+	}
+}
+
+// TransformNormal applies the inverse transpose of the linear part of m to n and renormalizes it
+func (m Mat4) TransformNormal(n Vec3) Vec3 {
+	// columns of the 3x3 linear part
+	a := Vec3{m[0], m[1], m[2]}
+	b := Vec3{m[4], m[5], m[6]}
+	c := Vec3{m[8], m[9], m[10]}
+
+	// the inverse transpose is the cofactor matrix divided by the determinant;
+	// the scale does not matter since the result is normalized
+	cofactorA := b.Cross(c)
+	cofactorB := c.Cross(a)
+	cofactorC := a.Cross(b)
+
+	r := cofactorA.Scale(n[0]).Add(cofactorB.Scale(n[1])).Add(cofactorC.Scale(n[2]))
+	if a.Dot(cofactorA) < 0 {
+		r = r.Scale(-1)
+	}
+	return r.Normalize()
+}
+
+// Determinant returns the determinant of the linear part of m; it is negative for mirroring transforms
+func (m Mat4) Determinant() float64 {
+	a := Vec3{m[0], m[1], m[2]}
+	b := Vec3{m[4], m[5], m[6]}
+	c := Vec3{m[8], m[9], m[10]}
+	return a.Dot(b.Cross(c))
+}
@@ -0,0 +1,61 @@
+package scene
+
+import (
+	"errors"
+	"fmt"
+)
+
+// NoMaterial marks a mesh without an assigned material
+const NoMaterial = -1
+
+// Mesh is an indexed triangle list. Normals, UVs and Colors are optional
+// per-vertex attributes and, when present, have one entry per position.
+type Mesh struct {
+	Name      string
+	Positions []Vec3
+	Normals   []Vec3
+	UVs       []Vec2
+	Colors    []Color
+	Indices   []uint32
+	Material  int
+}
+
+// TriangleCount returns the number of triangles in the mesh
+func (m *Mesh) TriangleCount() int {
+	return len(m.Indices) / 3
+}
+
+// Triangle returns the three positions of the i-th triangle
+func (m *Mesh) Triangle(i int) (Vec3, Vec3, Vec3) {
+	return m.Positions[m.Indices[3*i]], m.Positions[m.Indices[3*i+1]], m.Positions[m.Indices[3*i+2]]
+}
+
+// Validate checks that indices and attributes are consistent with the positions
+func (m *Mesh) Validate() error {
+	if len(m.Indices)%3 != 0 {
+		return errors.New("index count is not a multiple of 3")
+	}
+
+	vertexCount := len(m.Positions)
+	if n := len(m.Normals); n != 0 && n != vertexCount {
+		return fmt.Errorf("%d normals for %d positions", n, vertexCount)
+	}
+	if n := len(m.UVs); n != 0 && n != vertexCount {
+		return fmt.Errorf("%d uvs for %d positions", n, vertexCount)
+	}
+	if n := len(m.Colors); n != 0 && n != vertexCount {
+		return fmt.Errorf("%d colors for %d positions", n, vertexCount)
+	}
+
+	for i, index := range m.Indices {
+		if int(index) >= vertexCount {
+			return fmt.Errorf("index %d at position %d out of range", index, i)
+		}
+	}
+
+	if m.Material < NoMaterial {
+		return fmt.Errorf("invalid material index %d", m.Material)
+	}
+
+	return nil
+}
@@ -0,0 +1,152 @@
+// Package scene holds the format-independent, in-memory model of a 3D scene
+// shared by every reader, writer and analysis step in converto.
+package scene
+
+import "fmt"
+
+// Units is the length unit the scene coordinates are expressed in
+type Units string
+
+const (
+	UnitsMillimeter Units = "mm"
+	UnitsCentimeter Units = "cm"
+	UnitsMeter      Units = "m"
+	UnitsInch       Units = "in"
+	UnitsFoot       Units = "ft"
+)
+
+// unitMillimeters maps each unit to its length in millimeters
+var unitMillimeters = map[Units]float64{
+	UnitsMillimeter: 1,
+	UnitsCentimeter: 10,
+	UnitsMeter:      1000,
+	UnitsInch:       25.4,
+	UnitsFoot:       304.8,
+}
+
+// Valid reports whether u is one of the known units
+func (u Units) Valid() bool {
+	_, ok := unitMillimeters[u]
+	return ok
+}
+
+// Millimeters returns the length of one unit in millimeters
+func (u Units) Millimeters() float64 {
+	return unitMillimeters[u]
+}
+
+// Material describes the surface appearance of a mesh using a metallic-roughness model
+type Material struct {
+	Name      string
+	BaseColor Color
+	Metallic  float64
+	Roughness float64
+}
+
+// Body is a named solid made of one or more meshes placed in the scene by a transform
+type Body struct {
+	Name      string
+	Transform Mat4
+	Meshes    []Mesh
+}
+
+// Scene is the root of the model: bodies, their materials, units and free-form metadata
+type Scene struct {
+	Units     Units
+	Metadata  map[string]string
+	Materials []Material
+	Bodies    []Body
+}
+
+// New creates an empty scene in millimeters
+func New() *Scene {
+	return &Scene{
+		Units:    UnitsMillimeter,
+		Metadata: make(map[string]string),
+	}
+}
+
+// TriangleCount returns the number of triangles over every mesh in the scene
+func (s *Scene) TriangleCount() int {
+	count := 0
+	for _, body := range s.Bodies {
+		for _, mesh := range body.Meshes {
+			count += mesh.TriangleCount()
+		}
+	}
+	return count
+}
+
+// VertexCount returns the number of vertices over every mesh in the scene
+func (s *Scene) VertexCount() int {
+	count := 0
+	for _, body := range s.Bodies {
+		for _, mesh := range body.Meshes {
+			count += len(mesh.Positions)
+		}
+	}
+	return count
+}
+
+// Validate checks the internal consistency of the scene
+func (s *Scene) Validate() error {
+	if !s.Units.Valid() {
+		return fmt.Errorf("unknown units %q", s.Units)
+	}
+
+	for b, body := range s.Bodies {
+		for m, mesh := range body.Meshes {
+			if err := mesh.Validate(); err != nil {
+				return fmt.Errorf("body %d (%s) mesh %d: %w", b, body.Name, m, err)
+			}
+			if mesh.Material >= len(s.Materials) {
+				return fmt.Errorf("body %d (%s) mesh %d: material %d out of range", b, body.Name, m, mesh.Material)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Transform applies m to every body, on top of the body's own transform
+func (s *Scene) Transform(m Mat4) {
+	for i := range s.Bodies {
+		transform := s.Bodies[i].Transform
+		if transform == (Mat4{}) {
+			transform = Identity()
+		}
+		s.Bodies[i].Transform = m.Mul(transform)
+	}
+}
+
+// ConvertUnits expresses the scene in the given units, scaling the bodies so that their physical size is kept
+func (s *Scene) ConvertUnits(units Units) error {
+	if !units.Valid() {
+		return fmt.Errorf("unknown units %q", units)
+	}
+	if units == s.Units {
+		return nil
+	}
+	if !s.Units.Valid() {
+		return fmt.Errorf("unknown units %q", s.Units)
+	}
+
+	s.Transform(Scaling(s.Units.Millimeters() / units.Millimeters()))
+	s.Units = units
+	return nil
+}
+
+// ForEachTriangle calls fn with every triangle of the body, transformed into scene space
+func (b *Body) ForEachTriangle(fn func(a, b, c Vec3)) {
+	identity := b.Transform.IsIdentity() || b.Transform == Mat4{}
+	for m := range b.Meshes {
+		mesh := &b.Meshes[m]
+		for i := 0; i < mesh.TriangleCount(); i++ {
+			p0, p1, p2 := mesh.Triangle(i)
+			if !identity {
+				p0, p1, p2 = b.Transform.TransformPoint(p0), b.Transform.TransformPoint(p1), b.Transform.TransformPoint(p2)
+			}
+			fn(p0, p1, p2)
+		}
+	}
+}
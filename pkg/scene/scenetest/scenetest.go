@@ -0,0 +1,46 @@
+// Package scenetest provides small scenes for tests of readers, writers and mesh operations.
+package scenetest
+
+import "github.com/wildan3105/converto/pkg/scene"
+
+// CubeMesh returns a closed, outward-facing cube mesh of the given edge length with one corner at the origin
+func CubeMesh(size float64) scene.Mesh {
+	positions := []scene.Vec3{
+		{0, 0, 0}, {size, 0, 0}, {size, size, 0}, {0, size, 0},
+		{0, 0, size}, {size, 0, size}, {size, size, size}, {0, size, size},
+	}
+
+	indices := []uint32{
+		0, 2, 1, 0, 3, 2, // bottom (-z)
+		4, 5, 6, 4, 6, 7, // top (+z)
+		0, 1, 5, 0, 5, 4, // front (-y)
+		2, 3, 7, 2, 7, 6, // back (+y)
+		1, 2, 6, 1, 6, 5, // right (+x)
+		3, 0, 4, 3, 4, 7, // left (-x)
+	}
+
+	return scene.Mesh{
+		Name:      "cube",
+		Positions: positions,
+		Indices:   indices,
+		Material:  scene.NoMaterial,
+	}
+}
+
+// Cube returns a scene with a single body holding a cube of the given edge length
+func Cube(size float64) *scene.Scene {
+	s := scene.New()
+	s.Metadata["generator"] = "scenetest"
+	s.Materials = []scene.Material{
+		{Name: "grey", BaseColor: scene.Color{0.5, 0.5, 0.5, 1}, Metallic: 0, Roughness: 0.75},
+	}
+
+	mesh := CubeMesh(size)
+	mesh.Material = 0
+
+	s.Bodies = []scene.Body{
+		{Name: "Body 1", Transform: scene.Identity(), Meshes: []scene.Mesh{mesh}},
+	}
+
+	return s
+}
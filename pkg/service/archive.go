@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/wildan3105/converto/pkg/api/schema"
+	"github.com/wildan3105/converto/pkg/archive"
+	"github.com/wildan3105/converto/pkg/domain"
+)
+
+// manifestFileName is the name of the manifest written at the end of every download archive
+const manifestFileName = "manifest.json"
+
+// ErrArchiveUnavailable is returned when there is no completed conversion to archive yet
+var ErrArchiveUnavailable = errors.New("no converted files available")
+
+// ArchiveConversion prepares a ZIP archive of every file produced by a completed conversion
+func (s *ConversionServiceHandler) ArchiveConversion(ctx context.Context, id string) (schema.ArchiveDownload, error) {
+	conversion, err := s.repo.GetConversionByID(ctx, id)
+	if err != nil {
+		return schema.ArchiveDownload{}, err
+	}
+	if conversion == nil {
+		return schema.ArchiveDownload{}, fiber.ErrNotFound
+	}
+	if len(artifactsOf(conversion)) == 0 {
+		return schema.ArchiveDownload{}, ErrArchiveUnavailable
+	}
+
+	return s.archiveDownload("conversion", id, conversion.ID+".zip", []*domain.Conversion{conversion}, false), nil
+}
+
+// ArchiveBatch prepares a ZIP archive of the files produced by every completed conversion of a batch,
+// each conversion in a directory named after its ID
+func (s *ConversionServiceHandler) ArchiveBatch(ctx context.Context, id string) (schema.ArchiveDownload, error) {
+	batch, err := s.batches.GetBatchByID(ctx, id)
+	if err != nil {
+		return schema.ArchiveDownload{}, err
+	}
+	if batch == nil {
+		return schema.ArchiveDownload{}, fiber.ErrNotFound
+	}
+
+	conversions, err := s.repo.ListConversionsByBatchID(ctx, id)
+	if err != nil {
+		return schema.ArchiveDownload{}, err
+	}
+
+	var completed []*domain.Conversion
+	for _, conversion := range conversions {
+		if len(artifactsOf(conversion)) > 0 {
+			completed = append(completed, conversion)
+		}
+	}
+	if len(completed) == 0 {
+		return schema.ArchiveDownload{}, ErrArchiveUnavailable
+	}
+
+	return s.archiveDownload("batch", id, "batch-"+batch.ID+".zip", completed, true), nil
+}
+
+// archiveDownload returns a download that streams the artifacts of the conversions, followed by a
+// manifest of their checksums. With perConversion, the artifacts of each conversion are placed in a
+// directory named after its ID.
+func (s *ConversionServiceHandler) archiveDownload(kind, id, fileName string, conversions []*domain.Conversion, perConversion bool) schema.ArchiveDownload {
+	return schema.ArchiveDownload{
+		FileName: fileName,
+		Write: func(w io.Writer) error {
+			zw := archive.NewWriter(w)
+
+			manifest := schema.ArchiveManifest{
+				Type:      kind,
+				ID:        id,
+				CreatedAt: time.Now(),
+			}
+
+			for _, conversion := range conversions {
+				entry := schema.ArchiveManifestConversion{
+					ID:           conversion.ID,
+					OriginalName: conversion.File.OriginalName,
+					TargetFormat: conversion.Conversion.TargetFormat,
+				}
+
+				prefix := ""
+				if perConversion {
+					prefix = conversion.ID + "/"
+				}
+
+				for _, artifact := range artifactsOf(conversion) {
+					file, err := s.addStoredFile(zw, prefix+artifact.Name, artifact.Path)
+					if err != nil {
+						return err
+					}
+					entry.Files = append(entry.Files, file)
+				}
+
+				manifest.Conversions = append(manifest.Conversions, entry)
+			}
+
+			if err := zw.AddJSON(manifestFileName, manifest); err != nil {
+				return err
+			}
+			return zw.Close()
+		},
+	}
+}
+
+// addStoredFile copies a stored file into the archive
+func (s *ConversionServiceHandler) addStoredFile(zw *archive.Writer, name, storedPath string) (archive.ManifestEntry, error) {
+	src, err := s.storage.OpenFile(storedPath)
+	if err != nil {
+		return archive.ManifestEntry{}, err
+	}
+	defer src.Close()
+
+	entry, err := zw.Add(name, src)
+	if err != nil {
+		return archive.ManifestEntry{}, fmt.Errorf("failed to archive %s: %w", path.Base(storedPath), err)
+	}
+	return entry, nil
+}
+
+// artifactsOf returns the files produced by a completed conversion. Conversions stored before
+// artifacts were tracked only know their primary file.
+func artifactsOf(conversion *domain.Conversion) []domain.Artifact {
+	if conversion.Conversion.Status != domain.ConversionCompleted {
+		return nil
+	}
+	if len(conversion.File.Artifacts) > 0 {
+		return conversion.File.Artifacts
+	}
+	if conversion.File.ConvertedPath == "" {
+		return nil
+	}
+	return []domain.Artifact{{Name: conversion.File.ConvertedName, Path: conversion.File.ConvertedPath}}
+}
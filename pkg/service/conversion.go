@@ -3,17 +3,20 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
-	config "github.com/wildan3105/converto/configs"
 	"github.com/wildan3105/converto/pkg/api/schema"
+	"github.com/wildan3105/converto/pkg/converter"
 	"github.com/wildan3105/converto/pkg/domain"
+	"github.com/wildan3105/converto/pkg/format"
 	"github.com/wildan3105/converto/pkg/infrastructure/circuitbreaker"
 	"github.com/wildan3105/converto/pkg/infrastructure/filestorage"
-	"github.com/wildan3105/converto/pkg/infrastructure/rabbitmq"
 	"github.com/wildan3105/converto/pkg/logger"
 	"github.com/wildan3105/converto/pkg/repository"
 	"go.mongodb.org/mongo-driver/bson"
@@ -25,53 +28,236 @@ type ConversionService interface {
 	CreateConversion(ctx context.Context, req *schema.CreateConversionRequest) (schema.CreateConversionResponse, error)
 	ListConversions(ctx context.Context, status string, page, limit int) (schema.ListConversionsResponse, error)
 	GetConversionByID(ctx context.Context, id string) (schema.ConversionResponse, error)
-	GetFileByConversionIdAndType(ctx context.Context, id string, fileType string) (schema.GetFileByConversionId, error)
+	CancelConversion(ctx context.Context, id string) (schema.ConversionResponse, error)
+	RetryConversion(ctx context.Context, id string) (schema.ConversionResponse, error)
+	GetFileByConversionIdAndType(ctx context.Context, id string, fileType string, name string) (schema.GetFileByConversionId, error)
+	GetAnalysisByConversionID(ctx context.Context, id string) (domain.Analysis, error)
+	GetUploadByID(ctx context.Context, id string) (schema.UploadResponse, error)
+	CreateBatch(ctx context.Context, req *schema.CreateBatchRequest) (schema.BatchResponse, error)
+	GetBatchByID(ctx context.Context, id string) (schema.BatchResponse, error)
+	ArchiveConversion(ctx context.Context, id string) (schema.ArchiveDownload, error)
+	ArchiveBatch(ctx context.Context, id string) (schema.ArchiveDownload, error)
+	SupportedTargetFormats() []string
+	SupportsConversion(sourceFormat, targetFormat string) bool
+	ValidateOptions(targetFormat string, options map[string]any) error
 }
 
 // ConversionServiceHandler is the concrete implementation of ConversionService
 type ConversionServiceHandler struct {
-	repo      repository.ConversionRepository
-	publisher *rabbitmq.Publisher
-	storage   filestorage.FileStorage
+	repo       repository.ConversionRepository
+	uploads    repository.UploadRepository
+	batches    repository.BatchRepository
+	storage    filestorage.FileStorage
+	converters *converter.Registry
 }
 
 // NewConversionService creates a new instance of ConversionService
-func NewConversionService(repo repository.ConversionRepository, publisher *rabbitmq.Publisher, storage filestorage.FileStorage) *ConversionServiceHandler {
+func NewConversionService(repo repository.ConversionRepository, uploads repository.UploadRepository, batches repository.BatchRepository, storage filestorage.FileStorage, converters *converter.Registry) *ConversionServiceHandler {
 	return &ConversionServiceHandler{
-		repo:      repo,
-		publisher: publisher,
-		storage:   storage,
+		repo:       repo,
+		uploads:    uploads,
+		batches:    batches,
+		storage:    storage,
+		converters: converters,
 	}
 }
 
 var log = logger.GetInstance()
 
-// CreateConversion creates a conversion
-func (s *ConversionServiceHandler) CreateConversion(ctx context.Context, req *schema.CreateConversionRequest) (schema.CreateConversionResponse, error) {
-	convertedFileName := strings.TrimSuffix(req.FileName, ".shapr") + req.TargetFormat
+// ErrAnalysisUnavailable is returned when the worker has not analyzed the conversion's model yet
+var ErrAnalysisUnavailable = errors.New("analysis not available")
+
+// ErrConversionFinished is returned when cancelling a conversion that already completed or failed
+var ErrConversionFinished = errors.New("conversion already finished")
+
+// ErrConversionNotFailed is returned when retrying a conversion that has not failed
+var ErrConversionNotFailed = errors.New("conversion has not failed")
 
-	if req.File == nil {
+// CreateConversion stores the uploaded file once and creates one conversion per requested target format,
+// all grouped under a single upload. The jobs are queued by the outbox relay, from the outbox message
+// inserted with each conversion.
+func (s *ConversionServiceHandler) CreateConversion(ctx context.Context, req *schema.CreateConversionRequest) (schema.CreateConversionResponse, error) {
+	if req.Open == nil {
 		return schema.CreateConversionResponse{}, fiber.NewError(fiber.StatusBadRequest, "File is required")
 	}
 
+	response, err := s.createUpload(ctx, req, "")
+	if err != nil {
+		return schema.CreateConversionResponse{}, err
+	}
+
+	if len(response.Conversions) == 1 {
+		response.ID = response.Conversions[0].ID
+		response.Status = response.Conversions[0].Status
+	} else if created := countCreated(response.Conversions); created < len(response.Conversions) {
+		response.Message = fmt.Sprintf("%d of %d conversions created", created, len(response.Conversions))
+	} else {
+		response.Message = fmt.Sprintf("%d conversions created successfully", created)
+	}
+
+	return response, nil
+}
+
+// CreateBatch creates an upload, and its conversions, for every convertible entry of a batch.
+// Entries that were rejected, or whose upload cannot be created, are recorded with the reason.
+func (s *ConversionServiceHandler) CreateBatch(ctx context.Context, req *schema.CreateBatchRequest) (schema.BatchResponse, error) {
+	targetFormats := make([]string, len(req.Targets))
+	for i, target := range req.Targets {
+		targetFormats[i] = target.Format
+	}
+
+	entries := make([]domain.BatchEntry, len(req.Entries))
+	for i, entry := range req.Entries {
+		entries[i] = domain.BatchEntry{Name: entry.Name, Error: entry.Error}
+	}
+
+	batchID, err := s.batches.CreateBatch(ctx, &domain.Batch{
+		Entries:       entries,
+		TargetFormats: targetFormats,
+		Source:        domain.JobSourceAPI,
+	})
+	if err != nil {
+		return schema.BatchResponse{}, repositoryError(err)
+	}
+
+	for i, entry := range req.Entries {
+		if entry.Conversion == nil {
+			continue
+		}
+
+		created, err := s.createUpload(ctx, entry.Conversion, batchID)
+		if err != nil {
+			log.Warn("Failed to create conversions for batch %s entry %s: %v", batchID, entry.Name, err)
+			entries[i].Error = "failed to create conversions"
+			continue
+		}
+		entries[i].UploadID = created.UploadID
+	}
+
+	if err := s.batches.UpdateBatch(ctx, batchID, bson.M{"entries": entries}); err != nil {
+		return schema.BatchResponse{}, repositoryError(err)
+	}
+
+	response, err := s.GetBatchByID(ctx, batchID)
+	if err != nil {
+		return schema.BatchResponse{}, err
+	}
+	response.Message = "Batch created successfully"
+
+	return response, nil
+}
+
+// createUpload stores the original of an upload and creates its conversions, one per target format.
+// A target whose conversion cannot be created is reported as failed, since the conversions created
+// before it are already queued; it fails only when no conversion could be created.
+func (s *ConversionServiceHandler) createUpload(ctx context.Context, req *schema.CreateConversionRequest, batchID string) (schema.CreateConversionResponse, error) {
 	fileID := uuid.NewString()
 
-	originalFilePath, err := s.storage.SaveFile(req.File, domain.FileCategoryOriginal, fileID, req.FileName)
+	originalFilePath, err := s.saveOriginal(req, fileID)
 	if err != nil {
 		log.Info("Error when saving file: %v", err)
 		return schema.CreateConversionResponse{}, fiber.NewError(fiber.StatusInternalServerError, "Failed to save original file")
 	}
 
-	conversionPayload := &domain.Conversion{
+	targetFormats := make([]string, len(req.Targets))
+	for i, target := range req.Targets {
+		targetFormats[i] = target.Format
+	}
+
+	upload := &domain.Upload{
+		BatchID: batchID,
 		File: domain.FileMetadata{
-			OriginalName:  req.FileName,
-			OriginalPath:  originalFilePath,
-			ConvertedName: convertedFileName,
-			SizeInBytes:   req.FileSize,
-			ID:            fileID,
+			OriginalName: req.FileName,
+			OriginalPath: originalFilePath,
+			SourceFormat: req.SourceFormat,
+			SizeInBytes:  req.FileSize,
+			ID:           fileID,
 		},
+		TargetFormats: targetFormats,
+		Source:        domain.JobSourceAPI,
+	}
+
+	uploadID, err := s.uploads.CreateUpload(ctx, upload)
+	if err != nil {
+		// no record points to the original, so it would never be cleaned up
+		if deleteErr := s.storage.DeleteFiles(domain.FileCategoryOriginal, fileID); deleteErr != nil {
+			log.Warn("Failed to remove original file %s of failed upload: %v", fileID, deleteErr)
+		}
+		return schema.CreateConversionResponse{}, repositoryError(err)
+	}
+
+	response := schema.CreateConversionResponse{
+		UploadID: uploadID,
+		Status:   domain.ConversionPending,
+		Message:  "Conversion created successfully",
+	}
+
+	var createErr error
+	for _, target := range req.Targets {
+		created, err := s.createTargetConversion(ctx, upload, target)
+		if err != nil {
+			log.Warn("Failed to create %s conversion of upload %s: %v", target.Format, uploadID, err)
+			createErr = err
+			created = schema.CreatedConversion{
+				TargetFormat: target.Format,
+				Status:       domain.ConversionFailed,
+				Error:        "failed to create conversion",
+			}
+		}
+		response.Conversions = append(response.Conversions, created)
+	}
+
+	if createErr != nil && countCreated(response.Conversions) == 0 {
+		return schema.CreateConversionResponse{}, createErr
+	}
+
+	return response, nil
+}
+
+// countCreated returns how many of the requested conversions were created
+func countCreated(conversions []schema.CreatedConversion) int {
+	created := 0
+	for _, conversion := range conversions {
+		if conversion.ID != "" {
+			created++
+		}
+	}
+	return created
+}
+
+// saveOriginal copies the uploaded content to the original files storage and returns its path
+func (s *ConversionServiceHandler) saveOriginal(req *schema.CreateConversionRequest, fileID string) (string, error) {
+	src, err := req.Open()
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %w", err)
+	}
+	defer src.Close()
+
+	dest, path, err := s.storage.CreateFile(domain.FileCategoryOriginal, fileID, req.FileName)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := io.Copy(dest, src); err != nil {
+		dest.Close()
+		return "", fmt.Errorf("failed to save file: %w", err)
+	}
+
+	return path, dest.Close()
+}
+
+// createTargetConversion creates the conversion of an upload into one target format and writes its job to the outbox
+func (s *ConversionServiceHandler) createTargetConversion(ctx context.Context, upload *domain.Upload, target schema.ConversionTarget) (schema.CreatedConversion, error) {
+	file := upload.File
+	file.ConvertedName = strings.TrimSuffix(file.OriginalName, filepath.Ext(file.OriginalName)) + target.Format
+
+	conversionPayload := &domain.Conversion{
+		UploadID: upload.ID,
+		BatchID:  upload.BatchID,
+		File:     file,
 		Conversion: domain.ConversionData{
-			TargetFormat: req.TargetFormat,
+			TargetFormat: target.Format,
+			Options:      target.Options,
 			Progress:     0,
 			Status:       domain.ConversionPending,
 			StartedAt:    time.Now(),
@@ -83,48 +269,43 @@ func (s *ConversionServiceHandler) CreateConversion(ctx context.Context, req *sc
 			UpdatedAt: time.Now(),
 		},
 	}
+	// the job is queued by the outbox relay, from the message inserted with the conversion
+	conversionPayload.Outbox = newOutboxMessage(conversionPayload.Job.ID)
 
 	id, err := s.repo.CreateConversion(ctx, conversionPayload)
-
 	if err != nil {
-		if errors.Is(err, circuitbreaker.ErrCircuitBreakerOpen) {
-			return schema.CreateConversionResponse{}, errors.New("service temporarily unavailable")
-		}
-		if mongo.IsTimeout(err) || errors.Is(err, context.DeadlineExceeded) {
-			return schema.CreateConversionResponse{}, errors.New("database operation timed out")
-		}
-		return schema.CreateConversionResponse{}, err
+		return schema.CreatedConversion{}, repositoryError(err)
 	}
 
-	event := schema.ConversionEvent{
-		JobID:        conversionPayload.Job.ID,
-		ConversionID: id,
-		Source:       domain.JobSourceAPI,
-		CreatedAt:    conversionPayload.Job.CreatedAt,
-		UpdatedAt:    conversionPayload.Job.UpdatedAt,
-	}
-
-	publishErr := s.publisher.PublishConversionJob(ctx, event, config.AppConfig.RabbitMQExchangeName, config.AppConfig.RabbitMQRoutingKey)
-
-	if publishErr != nil {
-		log.Warn("Error when publishing %v", err)
-		updateData := bson.M{
-			"conversion.status":       domain.ConversionFailed,
-			"conversion.errorMessage": "failed to publish",
-			"conversion.completedAt":  time.Now(),
-		}
+	return schema.CreatedConversion{
+		ID:           id,
+		TargetFormat: target.Format,
+		Status:       conversionPayload.Conversion.Status,
+	}, nil
+}
 
-		if err := s.repo.UpdateConversion(ctx, event.ConversionID, updateData); err != nil {
-			log.Warn("Failed to mark conversion as 'failed': %v", err)
-		}
-		return schema.CreateConversionResponse{}, err
+// newOutboxMessage creates the outbox message of the first attempt of job jobID
+func newOutboxMessage(jobID string) *domain.OutboxMessage {
+	now := time.Now()
+	return &domain.OutboxMessage{
+		ID:            uuid.NewString(),
+		JobID:         jobID,
+		Attempt:       1,
+		Status:        domain.OutboxPending,
+		CreatedAt:     now,
+		NextAttemptAt: now,
 	}
+}
 
-	return schema.CreateConversionResponse{
-		ID:      id,
-		Status:  conversionPayload.Conversion.Status,
-		Message: "Conversion created successfully",
-	}, nil
+// repositoryError maps repository failures to the errors reported by the service
+func repositoryError(err error) error {
+	if errors.Is(err, circuitbreaker.ErrCircuitBreakerOpen) {
+		return errors.New("service temporarily unavailable")
+	}
+	if mongo.IsTimeout(err) || errors.Is(err, context.DeadlineExceeded) {
+		return errors.New("database operation timed out")
+	}
+	return err
 }
 
 // ListConversions fetches conversions from the repository and maps them to the response schema
@@ -138,13 +319,7 @@ func (s *ConversionServiceHandler) ListConversions(ctx context.Context, status s
 
 	responses := make([]schema.ConversionResponse, len(conversions))
 	for i, conversion := range conversions {
-		responses[i] = schema.ConversionResponse{
-			ID:                conversion.ID,
-			Status:            conversion.Conversion.Status,
-			Progress:          conversion.Conversion.Progress,
-			OriginalFilePath:  conversion.File.OriginalPath,
-			ConvertedFilePath: conversion.File.ConvertedPath,
-		}
+		responses[i] = toConversionResponse(conversion)
 	}
 
 	responseData := schema.ListConversionsResponse{
@@ -166,17 +341,277 @@ func (s *ConversionServiceHandler) GetConversionByID(ctx context.Context, id str
 		return schema.ConversionResponse{}, fiber.ErrNotFound
 	}
 
+	return toConversionResponse(conversion), nil
+}
+
+// CancelConversion cancels a conversion. A pending conversion is cancelled at once, so the worker
+// skips its job; an in-progress conversion is asked to stop, and the worker records the cancelled
+// status once it has stopped and removed the partial output.
+func (s *ConversionServiceHandler) CancelConversion(ctx context.Context, id string) (schema.ConversionResponse, error) {
+	err := s.repo.TransitionConversion(ctx, id, domain.ConversionCancelled, bson.M{
+		"conversion.completedAt": time.Now(),
+	}, domain.ConversionPending)
+
+	var transitionErr *domain.TransitionError
+	if errors.As(err, &transitionErr) && transitionErr.From == domain.ConversionInProgress {
+		// the conversion is running: the worker stops it
+		err = s.repo.TransitionConversion(ctx, id, domain.ConversionInProgress, bson.M{
+			"conversion.cancelRequested": true,
+		}, domain.ConversionInProgress)
+	}
+	if errors.Is(err, domain.ErrConversionNotFound) {
+		return schema.ConversionResponse{}, fiber.ErrNotFound
+	}
+	// a conversion that already finished is reported below
+	if err != nil && !errors.Is(err, domain.ErrIllegalTransition) {
+		return schema.ConversionResponse{}, repositoryError(err)
+	}
+
+	conversion, err := s.repo.GetConversionByID(ctx, id)
+	if err != nil {
+		return schema.ConversionResponse{}, err
+	}
+	if conversion == nil {
+		return schema.ConversionResponse{}, fiber.ErrNotFound
+	}
+
+	switch conversion.Conversion.Status {
+	case domain.ConversionCompleted, domain.ConversionFailed:
+		return schema.ConversionResponse{}, ErrConversionFinished
+	}
+
+	return toConversionResponse(conversion), nil
+}
+
+// RetryConversion queues a new job for a failed conversion through the outbox. The conversion is pending until
+// the worker picks the job up, and gets the full number of automatic retries again.
+func (s *ConversionServiceHandler) RetryConversion(ctx context.Context, id string) (schema.ConversionResponse, error) {
+	conversion, err := s.repo.GetConversionByID(ctx, id)
+	if err != nil {
+		return schema.ConversionResponse{}, err
+	}
+	if conversion == nil {
+		return schema.ConversionResponse{}, fiber.ErrNotFound
+	}
+
+	jobID := uuid.NewString()
+	err = s.repo.TransitionConversion(ctx, id, domain.ConversionPending, bson.M{
+		"conversion.progress":        0,
+		"conversion.error":           repository.Unset,
+		"conversion.errorMessage":    repository.Unset,
+		"job.errorMessage":           repository.Unset,
+		"conversion.completedAt":     repository.Unset,
+		"conversion.cancelRequested": false,
+		// a new job, so that late deliveries of the failed one are told apart
+		"job.id":      jobID,
+		"job.attempt": 0,
+		"outbox":      newOutboxMessage(jobID),
+	}, domain.ConversionFailed)
+	if errors.Is(err, domain.ErrIllegalTransition) {
+		return schema.ConversionResponse{}, ErrConversionNotFailed
+	}
+	if errors.Is(err, domain.ErrConversionNotFound) {
+		return schema.ConversionResponse{}, fiber.ErrNotFound
+	}
+	if err != nil {
+		return schema.ConversionResponse{}, repositoryError(err)
+	}
+
+	conversion.Conversion.Status = domain.ConversionPending
+	conversion.Conversion.Progress = 0
+	conversion.Conversion.CancelRequested = false
+	conversion.Conversion.Error = nil
+
+	return toConversionResponse(conversion), nil
+}
+
+// GetAnalysisByConversionID returns the geometry analysis of a conversion's model
+func (s *ConversionServiceHandler) GetAnalysisByConversionID(ctx context.Context, id string) (domain.Analysis, error) {
+	conversion, err := s.repo.GetConversionByID(ctx, id)
+	if err != nil {
+		return domain.Analysis{}, err
+	}
+	if conversion == nil {
+		return domain.Analysis{}, fiber.ErrNotFound
+	}
+	if conversion.Analysis == nil {
+		return domain.Analysis{}, ErrAnalysisUnavailable
+	}
+
+	return *conversion.Analysis, nil
+}
+
+// GetUploadByID returns an upload with its conversions and their combined status
+func (s *ConversionServiceHandler) GetUploadByID(ctx context.Context, id string) (schema.UploadResponse, error) {
+	upload, err := s.uploads.GetUploadByID(ctx, id)
+	if err != nil {
+		return schema.UploadResponse{}, err
+	}
+	if upload == nil {
+		return schema.UploadResponse{}, fiber.ErrNotFound
+	}
+
+	conversions, err := s.repo.ListConversionsByUploadID(ctx, id)
+	if err != nil {
+		return schema.UploadResponse{}, err
+	}
+
+	responses := make([]schema.ConversionResponse, len(conversions))
+	for i, conversion := range conversions {
+		responses[i] = toConversionResponse(conversion)
+	}
+
+	status, progress := aggregateStatus(conversions)
+
+	return schema.UploadResponse{
+		ID:               upload.ID,
+		OriginalFileName: upload.File.OriginalName,
+		OriginalFilePath: upload.File.OriginalPath,
+		SourceFormat:     upload.File.SourceFormat,
+		Status:           status,
+		Progress:         progress,
+		Conversions:      responses,
+		CreatedAt:        upload.CreatedAt,
+	}, nil
+}
+
+// GetBatchByID returns a batch with the status of every entry and a summary of its conversions
+func (s *ConversionServiceHandler) GetBatchByID(ctx context.Context, id string) (schema.BatchResponse, error) {
+	batch, err := s.batches.GetBatchByID(ctx, id)
+	if err != nil {
+		return schema.BatchResponse{}, err
+	}
+	if batch == nil {
+		return schema.BatchResponse{}, fiber.ErrNotFound
+	}
+
+	conversions, err := s.repo.ListConversionsByBatchID(ctx, id)
+	if err != nil {
+		return schema.BatchResponse{}, err
+	}
+
+	return summarizeBatch(batch, conversions), nil
+}
+
+// summarizeBatch combines the state of a batch's conversions per entry and over the whole batch
+func summarizeBatch(batch *domain.Batch, conversions []*domain.Conversion) schema.BatchResponse {
+	byUpload := make(map[string][]*domain.Conversion)
+	for _, conversion := range conversions {
+		byUpload[conversion.UploadID] = append(byUpload[conversion.UploadID], conversion)
+	}
+
+	summary := schema.BatchSummary{Entries: len(batch.Entries), Total: len(conversions)}
+	for _, conversion := range conversions {
+		switch conversion.Conversion.Status {
+		case domain.ConversionPending:
+			summary.Pending++
+		case domain.ConversionInProgress:
+			summary.InProgress++
+		case domain.ConversionCompleted:
+			summary.Completed++
+		case domain.ConversionFailed:
+			summary.Failed++
+		case domain.ConversionCancelled:
+			summary.Cancelled++
+		}
+	}
+
+	entries := make([]schema.BatchEntryResponse, len(batch.Entries))
+	for i, entry := range batch.Entries {
+		entries[i] = schema.BatchEntryResponse{
+			Name:     entry.Name,
+			UploadID: entry.UploadID,
+			Error:    entry.Error,
+		}
+		if entry.UploadID == "" {
+			summary.Rejected++
+			continue
+		}
+
+		entryConversions := byUpload[entry.UploadID]
+		entries[i].Status, _ = aggregateStatus(entryConversions)
+		for _, conversion := range entryConversions {
+			entries[i].Conversions = append(entries[i].Conversions, schema.CreatedConversion{
+				ID:           conversion.ID,
+				TargetFormat: conversion.Conversion.TargetFormat,
+				Status:       conversion.Conversion.Status,
+			})
+		}
+	}
+
+	status, progress := aggregateStatus(conversions)
+	// a batch none of whose entries could be converted has nothing left to wait for
+	if len(conversions) == 0 && summary.Rejected > 0 {
+		status = domain.ConversionFailed
+	}
+
+	return schema.BatchResponse{
+		ID:        batch.ID,
+		Status:    status,
+		Progress:  progress,
+		Summary:   summary,
+		Entries:   entries,
+		CreatedAt: batch.CreatedAt,
+	}
+}
+
+// aggregateStatus combines the statuses of an upload's conversions: cancelled once all of them were
+// cancelled, failed once all of them finished and any failed, completed once all of them finished
+// and the others were cancelled, pending while none has started, and in progress otherwise.
+// Progress is the average over the conversions.
+func aggregateStatus(conversions []*domain.Conversion) (domain.ConversionStatus, int) {
+	if len(conversions) == 0 {
+		return domain.ConversionPending, 0
+	}
+
+	counts := make(map[domain.ConversionStatus]int)
+	progress := 0
+	for _, conversion := range conversions {
+		counts[conversion.Conversion.Status]++
+		progress += conversion.Conversion.Progress
+	}
+	progress /= len(conversions)
+
+	finished := counts[domain.ConversionCompleted] + counts[domain.ConversionFailed] + counts[domain.ConversionCancelled]
+
+	switch {
+	case counts[domain.ConversionCancelled] == len(conversions):
+		return domain.ConversionCancelled, progress
+	case finished == len(conversions) && counts[domain.ConversionFailed] > 0:
+		return domain.ConversionFailed, progress
+	case finished == len(conversions):
+		return domain.ConversionCompleted, progress
+	case counts[domain.ConversionPending] == len(conversions):
+		return domain.ConversionPending, progress
+	default:
+		return domain.ConversionInProgress, progress
+	}
+}
+
+// toConversionResponse maps a conversion document to its API representation
+func toConversionResponse(conversion *domain.Conversion) schema.ConversionResponse {
 	return schema.ConversionResponse{
 		ID:                conversion.ID,
+		UploadID:          conversion.UploadID,
+		TargetFormat:      conversion.Conversion.TargetFormat,
 		Status:            conversion.Conversion.Status,
 		Progress:          conversion.Conversion.Progress,
+		CancelRequested:   conversion.Conversion.CancelRequested,
+		Error:             conversion.Conversion.Error,
+		Attempts:          conversion.Conversion.Attempts,
+		Options:           conversion.Conversion.Options,
 		OriginalFilePath:  conversion.File.OriginalPath,
 		ConvertedFilePath: conversion.File.ConvertedPath,
-	}, nil
+		PreviewFilePath:   conversion.File.PreviewPath,
+		Artifacts:         conversion.File.Artifacts,
+		Validation:        conversion.Validation,
+		Analysis:          conversion.Analysis,
+	}
 }
 
-// GetFileByConversionIdAndType returns the file path and name based on conversion ID and file type
-func (s *ConversionServiceHandler) GetFileByConversionIdAndType(ctx context.Context, id string, fileType string) (schema.GetFileByConversionId, error) {
+// GetFileByConversionIdAndType returns the file path and name based on conversion ID and file type.
+// For converted files, name selects one of the conversion's artifacts; the primary artifact is returned when it is empty.
+func (s *ConversionServiceHandler) GetFileByConversionIdAndType(ctx context.Context, id string, fileType string, name string) (schema.GetFileByConversionId, error) {
 	conversion, err := s.repo.GetConversionByID(ctx, id)
 	if err != nil {
 		return schema.GetFileByConversionId{
@@ -194,13 +629,45 @@ func (s *ConversionServiceHandler) GetFileByConversionIdAndType(ctx context.Cont
 	switch fileType {
 	case "original":
 		return schema.GetFileByConversionId{
-			Path:     conversion.File.OriginalPath,
-			FileName: conversion.File.OriginalName,
+			Path:        conversion.File.OriginalPath,
+			FileName:    conversion.File.OriginalName,
+			ContentType: format.ContentType(conversion.File.OriginalName),
 		}, nil
 	case "converted":
+		if name == "" {
+			if conversion.File.ConvertedPath == "" {
+				return schema.GetFileByConversionId{}, fiber.ErrNotFound
+			}
+			return schema.GetFileByConversionId{
+				Path:        conversion.File.ConvertedPath,
+				FileName:    conversion.File.ConvertedName,
+				ContentType: format.ContentType(conversion.File.ConvertedName),
+			}, nil
+		}
+
+		for _, artifact := range conversion.File.Artifacts {
+			if artifact.Name == name {
+				contentType := artifact.ContentType
+				if contentType == "" {
+					contentType = format.ContentType(artifact.Name)
+				}
+				return schema.GetFileByConversionId{
+					Path:        artifact.Path,
+					FileName:    artifact.Name,
+					ContentType: contentType,
+				}, nil
+			}
+		}
+		return schema.GetFileByConversionId{}, fiber.ErrNotFound
+	case "preview":
+		if conversion.File.PreviewPath == "" {
+			return schema.GetFileByConversionId{}, fiber.ErrNotFound
+		}
+		name := strings.TrimSuffix(conversion.File.OriginalName, filepath.Ext(conversion.File.OriginalName)) + ".png"
 		return schema.GetFileByConversionId{
-			Path:     conversion.File.ConvertedPath,
-			FileName: conversion.File.ConvertedName,
+			Path:        conversion.File.PreviewPath,
+			FileName:    name,
+			ContentType: format.ContentType(name),
 		}, nil
 	default:
 		return schema.GetFileByConversionId{
@@ -209,3 +676,18 @@ func (s *ConversionServiceHandler) GetFileByConversionIdAndType(ctx context.Cont
 		}, nil
 	}
 }
+
+// SupportedTargetFormats returns the target formats a converter is registered for
+func (s *ConversionServiceHandler) SupportedTargetFormats() []string {
+	return s.converters.Formats()
+}
+
+// SupportsConversion reports whether a registered converter turns the source format into the target format
+func (s *ConversionServiceHandler) SupportsConversion(sourceFormat, targetFormat string) bool {
+	return s.converters.Supports(sourceFormat, targetFormat)
+}
+
+// ValidateOptions checks the conversion options against the target format's schema
+func (s *ConversionServiceHandler) ValidateOptions(targetFormat string, options map[string]any) error {
+	return s.converters.ValidateOptions(targetFormat, options)
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/wildan3105/converto/pkg/api/schema"
+	"github.com/wildan3105/converto/pkg/infrastructure/rabbitmq"
+)
+
+// ErrDeadLetterStale is returned when replaying the job of a conversion that is no longer failed
+var ErrDeadLetterStale = errors.New("conversion of the dead letter is no longer failed")
+
+// DeadLetterService defines the administration of the messages that could not be processed
+type DeadLetterService interface {
+	ListDeadLetters(ctx context.Context, limit int) (schema.ListDeadLettersResponse, error)
+	GetDeadLetter(ctx context.Context, id string) (schema.DeadLetterResponse, error)
+	ReplayDeadLetter(ctx context.Context, id string) error
+	DeleteDeadLetter(ctx context.Context, id string) error
+	PurgeDeadLetters(ctx context.Context) (schema.PurgeDeadLettersResponse, error)
+}
+
+// DeadLetterServiceHandler is the concrete implementation of DeadLetterService
+type DeadLetterServiceHandler struct {
+	queue       *rabbitmq.DeadLetterQueue
+	conversions ConversionService
+}
+
+// NewDeadLetterService creates a new instance of DeadLetterService. Conversion jobs are replayed
+// through the conversion service, so that the conversion is reset like on a manual retry.
+func NewDeadLetterService(queue *rabbitmq.DeadLetterQueue, conversions ConversionService) *DeadLetterServiceHandler {
+	return &DeadLetterServiceHandler{
+		queue:       queue,
+		conversions: conversions,
+	}
+}
+
+// ListDeadLetters returns the oldest dead letters, without their headers
+func (s *DeadLetterServiceHandler) ListDeadLetters(ctx context.Context, limit int) (schema.ListDeadLettersResponse, error) {
+	letters, total, err := s.queue.List(limit)
+	if err != nil {
+		return schema.ListDeadLettersResponse{}, err
+	}
+
+	responses := make([]schema.DeadLetterResponse, len(letters))
+	for i, letter := range letters {
+		responses[i] = toDeadLetterResponse(letter)
+		responses[i].Headers = nil
+	}
+
+	return schema.ListDeadLettersResponse{
+		Total: total,
+		Limit: limit,
+		Data:  responses,
+	}, nil
+}
+
+// GetDeadLetter returns a dead letter with its headers
+func (s *DeadLetterServiceHandler) GetDeadLetter(ctx context.Context, id string) (schema.DeadLetterResponse, error) {
+	letter, err := s.queue.Get(id)
+	if err != nil {
+		return schema.DeadLetterResponse{}, deadLetterError(err)
+	}
+
+	return toDeadLetterResponse(*letter), nil
+}
+
+// ReplayDeadLetter processes a dead letter again and removes it from the queue. The job of a failed
+// conversion is retried; any other message is published again unchanged, e.g. once the consumer
+// has been fixed to decode it.
+func (s *DeadLetterServiceHandler) ReplayDeadLetter(ctx context.Context, id string) error {
+	letter, err := s.queue.Get(id)
+	if err != nil {
+		return deadLetterError(err)
+	}
+
+	var event schema.ConversionEvent
+	if json.Unmarshal(letter.Body, &event) != nil || event.ConversionID == "" {
+		return deadLetterError(s.queue.Republish(ctx, id))
+	}
+
+	if _, err := s.conversions.RetryConversion(ctx, event.ConversionID); err != nil {
+		if errors.Is(err, fiber.ErrNotFound) || errors.Is(err, ErrConversionNotFailed) {
+			return fmt.Errorf("%w: conversion %s", ErrDeadLetterStale, event.ConversionID)
+		}
+		return err
+	}
+
+	return deadLetterError(s.queue.Remove(id))
+}
+
+// DeleteDeadLetter removes a dead letter without processing it
+func (s *DeadLetterServiceHandler) DeleteDeadLetter(ctx context.Context, id string) error {
+	return deadLetterError(s.queue.Remove(id))
+}
+
+// PurgeDeadLetters removes every dead letter
+func (s *DeadLetterServiceHandler) PurgeDeadLetters(ctx context.Context) (schema.PurgeDeadLettersResponse, error) {
+	purged, err := s.queue.Purge()
+	if err != nil {
+		return schema.PurgeDeadLettersResponse{}, err
+	}
+
+	return schema.PurgeDeadLettersResponse{Purged: purged}, nil
+}
+
+// deadLetterError maps a missing dead letter to fiber.ErrNotFound
+func deadLetterError(err error) error {
+	if errors.Is(err, rabbitmq.ErrDeadLetterNotFound) {
+		return fiber.ErrNotFound
+	}
+	return err
+}
+
+// toDeadLetterResponse maps a dead letter to its API representation. Jobs are decoded to show the
+// conversion they belong to.
+func toDeadLetterResponse(letter rabbitmq.DeadLetter) schema.DeadLetterResponse {
+	response := schema.DeadLetterResponse{
+		ID:             letter.ID,
+		Reason:         string(letter.Reason),
+		Error:          letter.Error,
+		DeadLetteredAt: letter.DeadLetteredAt,
+		Headers:        letter.Headers,
+		Body:           string(letter.Body),
+	}
+
+	if json.Valid(letter.Body) {
+		response.Body = json.RawMessage(letter.Body)
+	}
+
+	var event schema.ConversionEvent
+	if json.Unmarshal(letter.Body, &event) == nil {
+		response.ConversionID = event.ConversionID
+		response.Attempt = event.Attempt
+	}
+
+	return response
+}
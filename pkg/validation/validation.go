@@ -0,0 +1,260 @@
+// Package validation checks uploaded models. Structure is a fast check of the
+// container run by the API before a file is stored; Validate is the in-depth
+// check run by the worker, which decodes the model and inspects its geometry.
+package validation
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/wildan3105/converto/pkg/converter"
+	"github.com/wildan3105/converto/pkg/domain"
+	"github.com/wildan3105/converto/pkg/format/gltf"
+	"github.com/wildan3105/converto/pkg/format/shapr"
+	"github.com/wildan3105/converto/pkg/scene"
+)
+
+// Problem codes
+const (
+	CodeInvalidMagic        = "invalid_magic"
+	CodeUnsupportedVersion  = "unsupported_version"
+	CodeChecksumMismatch    = "checksum_mismatch"
+	CodeTruncated           = "truncated"
+	CodeMalformed           = "malformed"
+	CodeDecodeFailed        = "decode_failed"
+	CodeEmptyScene          = "empty_scene"
+	CodeInvalidMesh         = "invalid_mesh"
+	CodeNonFiniteVertex     = "non_finite_vertex"
+	CodeInvalidTransform    = "invalid_transform"
+	CodeDegenerateTriangles = "degenerate_triangles"
+	CodeEmptyBody           = "empty_body"
+	CodeUnusedMaterial      = "unused_material"
+)
+
+// Structure checks the container structure of a file (header, declared sizes,
+// checksums) without decoding it. Text formats have no container and pass unchecked.
+// size is the total file size, or -1 when unknown.
+func Structure(r io.Reader, sourceFormat string, size int64) []domain.ValidationProblem {
+	var problems []domain.ValidationProblem
+
+	switch sourceFormat {
+	case ".shapr":
+		for _, err := range shapr.Check(r, size) {
+			offset := err.Offset
+			problems = append(problems, domain.ValidationProblem{
+				Severity: domain.ValidationError,
+				Code:     shaprCode(err),
+				Message:  err.Error(),
+				Offset:   &offset,
+			})
+		}
+	case ".glb":
+		for _, err := range gltf.CheckGLB(r, size) {
+			problems = append(problems, domain.ValidationProblem{
+				Severity: domain.ValidationError,
+				Code:     CodeMalformed,
+				Message:  err.Error(),
+			})
+		}
+	}
+
+	return problems
+}
+
+// Validate runs the structural checks, decodes the file and checks the decoded scene.
+// The file is streamed twice, by the structural checks and by the decoder, rather
+// than held in memory. The report is valid when no problem has error severity. The
+// decoded scene is returned for further inspection and conversion; it is nil when the
+// file could not be decoded.
+func Validate(r io.ReadSeeker, sourceFormat string) (domain.ValidationReport, *scene.Scene, error) {
+	size, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return domain.ValidationReport{}, nil, err
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return domain.ValidationReport{}, nil, err
+	}
+
+	problems := Structure(bufio.NewReader(r), sourceFormat, size)
+
+	// a damaged container cannot be decoded meaningfully
+	var s *scene.Scene
+	if len(problems) == 0 {
+		if _, err := r.Seek(0, io.SeekStart); err != nil {
+			return domain.ValidationReport{}, nil, err
+		}
+		var sceneProblems []domain.ValidationProblem
+		s, sceneProblems = decodeAndCheck(bufio.NewReader(r), sourceFormat)
+		problems = append(problems, sceneProblems...)
+	}
+
+	return NewReport(problems), s, nil
+}
+
+// NewReport builds a report from a list of problems
+func NewReport(problems []domain.ValidationProblem) domain.ValidationReport {
+	report := domain.ValidationReport{
+		Valid:       true,
+		Problems:    problems,
+		ValidatedAt: time.Now(),
+	}
+	if report.Problems == nil {
+		report.Problems = []domain.ValidationProblem{}
+	}
+
+	for _, problem := range problems {
+		if problem.Severity == domain.ValidationError {
+			report.Valid = false
+		}
+	}
+
+	return report
+}
+
+// decodeAndCheck decodes the file and inspects the scene
+func decodeAndCheck(r io.Reader, sourceFormat string) (*scene.Scene, []domain.ValidationProblem) {
+	decode, ok := converter.Decoder(sourceFormat)
+	if !ok {
+		return nil, []domain.ValidationProblem{newError(CodeDecodeFailed, fmt.Sprintf("no decoder for %s", sourceFormat))}
+	}
+
+	s, err := decode(r)
+	if err != nil {
+		problem := newError(CodeDecodeFailed, err.Error())
+		var formatErr *shapr.FormatError
+		if errors.As(err, &formatErr) {
+			problem.Code = shaprCode(formatErr)
+			offset := formatErr.Offset
+			problem.Offset = &offset
+		}
+		return nil, []domain.ValidationProblem{problem}
+	}
+
+	return s, Scene(s)
+}
+
+// Scene checks a decoded scene for problems that would break or degrade a conversion
+func Scene(s *scene.Scene) []domain.ValidationProblem {
+	var problems []domain.ValidationProblem
+
+	if err := s.Validate(); err != nil {
+		problems = append(problems, newError(CodeInvalidMesh, err.Error()))
+	}
+
+	if s.TriangleCount() == 0 {
+		problems = append(problems, newError(CodeEmptyScene, "the model contains no triangles"))
+	}
+
+	usedMaterials := make([]bool, len(s.Materials))
+	for b := range s.Bodies {
+		body := &s.Bodies[b]
+
+		if !finiteMatrix(body.Transform) {
+			problems = append(problems, newError(CodeInvalidTransform, fmt.Sprintf("body %q has a non-finite transform", body.Name)))
+		}
+
+		triangles := 0
+		for m := range body.Meshes {
+			mesh := &body.Meshes[m]
+			triangles += mesh.TriangleCount()
+
+			if mesh.Material >= 0 && mesh.Material < len(usedMaterials) {
+				usedMaterials[mesh.Material] = true
+			}
+
+			if n := nonFinitePositions(mesh); n > 0 {
+				problems = append(problems, newError(CodeNonFiniteVertex,
+					fmt.Sprintf("body %q mesh %q has %d non-finite vertex positions", body.Name, mesh.Name, n)))
+			}
+			if mesh.Validate() != nil {
+				// out-of-range indices were reported above, triangles cannot be inspected
+				continue
+			}
+			if n := degenerateTriangles(mesh); n > 0 {
+				problems = append(problems, newWarning(CodeDegenerateTriangles,
+					fmt.Sprintf("body %q mesh %q has %d degenerate triangles", body.Name, mesh.Name, n)))
+			}
+		}
+
+		if triangles == 0 {
+			problems = append(problems, newWarning(CodeEmptyBody, fmt.Sprintf("body %q has no triangles", body.Name)))
+		}
+	}
+
+	for i, used := range usedMaterials {
+		if !used {
+			problems = append(problems, newWarning(CodeUnusedMaterial, fmt.Sprintf("material %q is not used by any mesh", s.Materials[i].Name)))
+		}
+	}
+
+	return problems
+}
+
+// shaprCode maps a .shapr format error to a problem code
+func shaprCode(err *shapr.FormatError) string {
+	switch {
+	case errors.Is(err, shapr.ErrInvalidMagic):
+		return CodeInvalidMagic
+	case errors.Is(err, shapr.ErrUnsupportedVersion):
+		return CodeUnsupportedVersion
+	case errors.Is(err, shapr.ErrChecksumMismatch):
+		return CodeChecksumMismatch
+	case errors.Is(err, shapr.ErrTruncated):
+		return CodeTruncated
+	default:
+		return CodeMalformed
+	}
+}
+
+func nonFinitePositions(mesh *scene.Mesh) int {
+	count := 0
+	for _, p := range mesh.Positions {
+		if !finite(p[0]) || !finite(p[1]) || !finite(p[2]) {
+			count++
+		}
+	}
+	return count
+}
+
+// degenerateTriangles counts triangles with repeated corners or zero area
+func degenerateTriangles(mesh *scene.Mesh) int {
+	count := 0
+	for i := 0; i < mesh.TriangleCount(); i++ {
+		i0, i1, i2 := mesh.Indices[3*i], mesh.Indices[3*i+1], mesh.Indices[3*i+2]
+		if i0 == i1 || i1 == i2 || i0 == i2 {
+			count++
+			continue
+		}
+
+		a, b, c := mesh.Triangle(i)
+		if b.Sub(a).Cross(c.Sub(a)).Length() == 0 {
+			count++
+		}
+	}
+	return count
+}
+
+func finiteMatrix(m scene.Mat4) bool {
+	for _, v := range m {
+		if !finite(v) {
+			return false
+		}
+	}
+	return true
+}
+
+func finite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
+
+func newError(code, message string) domain.ValidationProblem {
+	return domain.ValidationProblem{Severity: domain.ValidationError, Code: code, Message: message}
+}
+
+func newWarning(code, message string) domain.ValidationProblem {
+	return domain.ValidationProblem{Severity: domain.ValidationWarning, Code: code, Message: message}
+}
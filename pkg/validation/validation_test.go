@@ -0,0 +1,77 @@
+package validation
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wildan3105/converto/pkg/domain"
+	"github.com/wildan3105/converto/pkg/format/shapr"
+	"github.com/wildan3105/converto/pkg/scene"
+	"github.com/wildan3105/converto/pkg/scene/scenetest"
+)
+
+func encode(t *testing.T, s *scene.Scene) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	require.NoError(t, shapr.Encode(&buf, s))
+	return buf.Bytes()
+}
+
+func codes(problems []domain.ValidationProblem) []string {
+	var result []string
+	for _, problem := range problems {
+		result = append(result, problem.Code)
+	}
+	return result
+}
+
+func TestValidateValidFile(t *testing.T) {
+	report, s, err := Validate(bytes.NewReader(encode(t, scenetest.Cube(10))), ".shapr")
+	require.NoError(t, err)
+	require.NotNil(t, s)
+
+	assert.True(t, report.Valid)
+	assert.Empty(t, report.Problems)
+	assert.False(t, report.ValidatedAt.IsZero())
+}
+
+func TestStructureReportsDamage(t *testing.T) {
+	data := encode(t, scenetest.Cube(10))
+	data[shapr.HeaderSize+shapr.ChunkHeaderSize] ^= 0xff
+
+	problems := Structure(bytes.NewReader(data), ".shapr", int64(len(data)))
+	require.Len(t, problems, 1)
+	assert.Equal(t, CodeChecksumMismatch, problems[0].Code)
+	assert.Equal(t, domain.ValidationError, problems[0].Severity)
+	require.NotNil(t, problems[0].Offset)
+	assert.Equal(t, int64(shapr.HeaderSize+8), *problems[0].Offset)
+
+	assert.Empty(t, Structure(bytes.NewReader([]byte("v 0 0 0")), ".obj", 7))
+}
+
+func TestValidateGeometry(t *testing.T) {
+	s := scenetest.Cube(10)
+	s.Materials = append(s.Materials, scene.Material{Name: "unused"})
+	mesh := &s.Bodies[0].Meshes[0]
+	mesh.Indices = append(mesh.Indices, 0, 0, 1)
+	mesh.Positions[7] = scene.Vec3{math.NaN(), 0, 0}
+	s.Bodies = append(s.Bodies, scene.Body{Name: "empty", Transform: scene.Identity()})
+
+	report, _, err := Validate(bytes.NewReader(encode(t, s)), ".shapr")
+	require.NoError(t, err)
+
+	assert.False(t, report.Valid)
+	assert.Equal(t, []string{CodeNonFiniteVertex, CodeDegenerateTriangles, CodeEmptyBody, CodeUnusedMaterial}, codes(report.Problems))
+}
+
+func TestValidateUndecodableFile(t *testing.T) {
+	report, _, err := Validate(bytes.NewReader([]byte("solid broken\nfacet normal 0 0 1\nouter loop\nvertex 0 0\n")), ".stl")
+	require.NoError(t, err)
+
+	assert.False(t, report.Valid)
+	assert.Equal(t, []string{CodeDecodeFailed}, codes(report.Problems))
+}
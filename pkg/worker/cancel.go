@@ -0,0 +1,68 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	config "github.com/wildan3105/converto/configs"
+	"github.com/wildan3105/converto/pkg/domain"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// activeStatuses are the statuses of a conversion that has not finished nor been cancelled
+var activeStatuses = []domain.ConversionStatus{domain.ConversionPending, domain.ConversionInProgress}
+
+// watchCancellation polls a running conversion and calls cancel once it is asked to stop, so that
+// the converter stops at its next check of the context. It returns when ctx is done.
+func (w *Worker) watchCancellation(ctx context.Context, id string, cancel context.CancelFunc) {
+	interval := config.AppConfig.CancelPollInterval
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			conversion, err := w.repo.GetConversionByID(ctx, id)
+			if err != nil {
+				log.Warn("Failed to check cancellation of conversion ID %s: %v", id, err)
+				continue
+			}
+			if conversion == nil || conversion.Conversion.CancelRequested || conversion.Conversion.Status == domain.ConversionCancelled {
+				log.Info("Cancellation requested for conversion ID: %s", id)
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+// stopCancelled removes the partial output of a cancelled conversion and records the cancelled status
+func (w *Worker) stopCancelled(ctx context.Context, id string, output *artifactOutput) error {
+	if err := output.Close(); err != nil {
+		log.Warn("Failed to close partial output of conversion ID %s: %v", id, err)
+	}
+
+	for _, category := range []domain.FileCategory{domain.FileCategoryConverted, domain.FileCategoryPreview} {
+		if err := w.storage.DeleteFiles(category, id); err != nil {
+			log.Warn("Failed to remove %s files of cancelled conversion ID %s: %v", category, id, err)
+		}
+	}
+
+	// a conversion cancelled while pending already has its status
+	err := w.repo.TransitionConversion(ctx, id, domain.ConversionCancelled, bson.M{
+		"conversion.completedAt": time.Now(),
+	}, activeStatuses...)
+	if err != nil && !errors.Is(err, domain.ErrIllegalTransition) {
+		return err
+	}
+
+	log.Info("Conversion cancelled: %s", id)
+	return nil
+}
@@ -0,0 +1,68 @@
+package worker
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/wildan3105/converto/pkg/converter"
+	"github.com/wildan3105/converto/pkg/domain"
+)
+
+// stageError is the failure of one stage of a conversion, classified for the conversion's error record
+type stageError struct {
+	stage     domain.ErrorStage
+	code      string
+	retryable bool
+	err       error
+}
+
+func (e *stageError) Error() string {
+	return e.err.Error()
+}
+
+func (e *stageError) Unwrap() error {
+	return e.err
+}
+
+// failure classifies err as a failure of stage. Retryable tells whether another attempt may succeed.
+func failure(stage domain.ErrorStage, code string, retryable bool, err error) error {
+	return &stageError{stage: stage, code: code, retryable: retryable, err: err}
+}
+
+// conversionFailure classifies an error returned by a converter
+func conversionFailure(err error) error {
+	err = fmt.Errorf("failed to convert file: %w", err)
+
+	var optionsErr *converter.OptionsError
+	var execErr *converter.ExecError
+	switch {
+	case errors.As(err, &optionsErr):
+		return failure(domain.ErrorStageConversion, domain.ErrorCodeInvalidOptions, false, err)
+	case errors.As(err, &execErr) && execErr.TimedOut:
+		return failure(domain.ErrorStageConversion, domain.ErrorCodeConverterTimeout, true, err)
+	default:
+		return failure(domain.ErrorStageConversion, domain.ErrorCodeConversionFailed, true, err)
+	}
+}
+
+// errorRecord builds the error record of a failure. Failures that were not classified are recorded
+// as retryable internal errors.
+func errorRecord(err error, at time.Time) *domain.ConversionError {
+	record := &domain.ConversionError{
+		Code:       domain.ErrorCodeInternal,
+		Message:    err.Error(),
+		Stage:      domain.ErrorStageConversion,
+		Retryable:  true,
+		OccurredAt: at,
+	}
+
+	var stageErr *stageError
+	if errors.As(err, &stageErr) {
+		record.Code = stageErr.code
+		record.Stage = stageErr.stage
+		record.Retryable = stageErr.retryable
+	}
+
+	return record
+}
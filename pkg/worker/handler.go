@@ -2,62 +2,263 @@ package worker
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"time"
 
+	"github.com/wildan3105/converto/pkg/analysis"
 	"github.com/wildan3105/converto/pkg/api/schema"
+	"github.com/wildan3105/converto/pkg/converter"
 	"github.com/wildan3105/converto/pkg/domain"
+	"github.com/wildan3105/converto/pkg/infrastructure/rabbitmq"
+	"github.com/wildan3105/converto/pkg/repository"
+	"github.com/wildan3105/converto/pkg/validation"
 	"go.mongodb.org/mongo-driver/bson"
 )
 
-// Handle processes a conversion job
+// Handle processes a conversion job and records the attempt on the conversion. A failed attempt is
+// retried after a backoff until the job runs out of attempts or fails for a reason retrying cannot
+// fix; the conversion is then marked as failed. A conversion cancelled while it runs is stopped and
+// its partial output removed. The worker claims the attempt of the job atomically before running it
+// and holds a lease on the conversion meanwhile, so that a delivery of an attempt that is claimed,
+// already ran or belongs to a finished conversion is skipped and acknowledged. A worker that lost
+// its lease to the reaper stops without recording anything, since the reaper already did.
 func (w *Worker) Handle(ctx context.Context, event schema.ConversionEvent) error {
-	if w == nil || w.repo == nil || w.storage == nil {
-		return fmt.Errorf("worker, repository, or storage is nil")
+	if w == nil || w.publisher == nil || w.repo == nil || w.storage == nil || w.converters == nil {
+		return fmt.Errorf("worker, publisher, repository, storage, or converter registry is nil")
+	}
+
+	if event.Attempt < 1 {
+		event.Attempt = 1
 	}
 
 	conversion, err := w.repo.GetConversionByID(ctx, event.ConversionID)
 	if err != nil {
-		return fmt.Errorf("failed to fetch conversion: %w", err)
+		return w.retryUnreachable(ctx, event, fmt.Errorf("failed to fetch conversion: %w", err))
+	}
+	if conversion == nil {
+		return fmt.Errorf("conversion %s not found", event.ConversionID)
+	}
+
+	if conversion.Conversion.Status.IsFinished() {
+		log.Info("Skipping %s conversion: %s", conversion.Conversion.Status, conversion.ID)
+		return nil
 	}
 
-	log.Info("Processing conversion: %s", conversion.ID)
+	lease, err := w.claim(ctx, event)
+	if err != nil {
+		return w.retryUnreachable(ctx, event, fmt.Errorf("failed to claim job: %w", err))
+	}
+	if lease == nil {
+		log.Info("Skipping duplicate delivery of job %s (attempt %d) for conversion: %s", event.JobID, event.Attempt, conversion.ID)
+		return nil
+	}
+	defer lease.release(ctx)
 
-	originalPath := conversion.File.OriginalPath
-	convertedName := conversion.File.ConvertedName
-	fileID := conversion.File.ID
+	log.Info("Processing conversion: %s (attempt %d)", conversion.ID, event.Attempt)
 
-	convertedPath := w.storage.GetFullPath(domain.FileCategoryConverted, fileID, convertedName)
+	attempt := domain.Attempt{
+		Number:    len(conversion.Conversion.Attempts) + 1,
+		StartedAt: time.Now(),
+	}
 
-	progressCb := func(progress int) {
-		status := domain.ConversionInProgress
-		if progress == 100 {
-			status = domain.ConversionCompleted
+	err = w.process(ctx, event, conversion, lease)
+	attempt.FinishedAt = time.Now()
+
+	if err != nil && (errors.Is(err, errLeaseLost) || !lease.confirm(ctx)) {
+		log.Warn("Abandoning conversion ID %s reclaimed by the reaper: %v", conversion.ID, err)
+		return fmt.Errorf("attempt %d abandoned: %w", event.Attempt, errLeaseLost)
+	}
+
+	if err != nil {
+		attempt.Error = errorRecord(err, attempt.FinishedAt)
+		err = w.retryOrFail(ctx, event, &attempt, err)
+	}
+
+	if recordErr := w.repo.AppendAttempt(ctx, conversion.ID, attempt); recordErr != nil {
+		log.Warn("Failed to record attempt %d of conversion ID %s: %v", attempt.Number, conversion.ID, recordErr)
+	}
+
+	return err
+}
+
+// retryUnreachable handles a job that could not be started because the database could not be
+// reached: it is retried after a backoff, or dead-lettered once it has no attempts left
+func (w *Worker) retryUnreachable(ctx context.Context, event schema.ConversionEvent, cause error) error {
+	if _, err := w.scheduleRetry(ctx, event); err != nil {
+		log.Warn("Failed to schedule retry of conversion ID %s: %v", event.ConversionID, err)
+		w.deadLetter(ctx, event, rabbitmq.DeadLetterExhausted, cause)
+	}
+	return cause
+}
+
+// process runs one attempt of a conversion: it validates and analyzes the original, converts it
+// and stores the converted files with a preview. The original is decoded once, by the validation,
+// and the decoded model is handed to the converter. The lease is renewed as the conversion progresses.
+func (w *Worker) process(ctx context.Context, event schema.ConversionEvent, conversion *domain.Conversion, lease *lease) error {
+	conv, err := w.converters.Get(conversion.Conversion.TargetFormat)
+	if err != nil {
+		return failure(domain.ErrorStagePrepare, domain.ErrorCodeUnsupportedFormat, false, fmt.Errorf("failed to select converter: %w", err))
+	}
+
+	// conversions created before format detection only accepted .shapr uploads
+	sourceFormat := conversion.File.SourceFormat
+	if sourceFormat == "" {
+		sourceFormat = ".shapr"
+	}
+
+	input, err := w.storage.OpenFile(conversion.File.OriginalPath)
+	if err != nil {
+		return failure(domain.ErrorStagePrepare, domain.ErrorCodeStorageUnavailable, true, fmt.Errorf("failed to open original file: %w", err))
+	}
+	defer input.Close()
+
+	report, model, err := validation.Validate(input, sourceFormat)
+	if err != nil {
+		return failure(domain.ErrorStageValidation, domain.ErrorCodeStorageUnavailable, true, fmt.Errorf("failed to validate original file: %w", err))
+	}
+
+	if !report.Valid {
+		if err := w.repo.UpdateConversion(ctx, conversion.ID, bson.M{"validation": report}); err != nil {
+			log.Warn("Failed to store validation report: %v", err)
 		}
+		return failure(domain.ErrorStageValidation, domain.ErrorCodeValidationFailed, false, fmt.Errorf("validation failed: %s", firstError(report)))
+	}
+
+	updateData := bson.M{
+		"validation": report,
+		"analysis":   analysis.Analyze(model),
+	}
+
+	if err := w.repo.UpdateConversion(ctx, conversion.ID, updateData); err != nil {
+		log.Warn("Failed to store validation report and analysis: %v", err)
+	}
+
+	// converters not working on the scene model read the original again
+	if _, err := input.Seek(0, io.SeekStart); err != nil {
+		return failure(domain.ErrorStagePrepare, domain.ErrorCodeStorageUnavailable, true, fmt.Errorf("failed to rewind original file: %w", err))
+	}
+
+	// the converter may modify the model, so the preview is drawn first; a missing preview does not fail the conversion
+	previewPath, err := w.renderPreview(conversion.ID, model)
+	if err != nil {
+		log.Warn("Failed to render preview for conversion ID %s: %v", conversion.ID, err)
+	}
+
+	// the original may be shared by the conversions of an upload, so outputs are stored per conversion
+	output := newArtifactOutput(w.storage, conversion.ID)
+	defer output.Close()
+
+	// jobCtx is cancelled when the conversion is cancelled or the lease lost; converters check it between chunks
+	jobCtx, cancelJob := context.WithCancel(ctx)
+	defer cancelJob()
+	go w.watchCancellation(jobCtx, conversion.ID, cancelJob)
+	go lease.keepLease(jobCtx, cancelJob)
 
+	progressCb := func(progress int) {
 		updateData := bson.M{
 			"conversion.progress": progress,
-			"conversion.status":   status,
-		}
-
-		if progress == 100 {
-			updateData["file.convertedPath"] = convertedPath
-			updateData["conversion.completedAt"] = time.Now()
 		}
 
 		log.Info("Conversion progress: %d%% for conversion ID: %s", progress, conversion.ID)
 
-		if err := w.repo.UpdateConversion(ctx, conversion.ID, updateData); err != nil {
+		if !lease.renew(ctx) {
+			cancelJob()
+			return
+		}
+
+		err := w.repo.TransitionConversion(ctx, conversion.ID, domain.ConversionInProgress, updateData, domain.ConversionInProgress)
+		if errors.Is(err, domain.ErrIllegalTransition) {
+			// cancelled or reclaimed since the job was picked up
+			cancelJob()
+			return
+		}
+		if err != nil {
 			log.Warn("Failed to update progress to %d%%: %v", progress, err)
 		}
 	}
 
-	convertedFilePath, err := w.storage.CopyFile(originalPath, convertedPath, progressCb)
+	progressCb(0)
+
+	// the event carries the options; fall back to the stored ones for events published without them
+	options := event.Options
+	if options == nil {
+		options = conversion.Conversion.Options
+	}
+
+	job := converter.Job{
+		Input:        input,
+		Scene:        model,
+		InputSize:    conversion.File.SizeInBytes,
+		SourceFormat: sourceFormat,
+		Output:       output,
+		OutputName:   conversion.File.ConvertedName,
+		TargetFormat: conversion.Conversion.TargetFormat,
+		Options:      options,
+		Progress:     progressCb,
+	}
+
+	err = conv.Convert(jobCtx, job)
+	if jobCtx.Err() != nil && ctx.Err() == nil {
+		if lease.isLost() {
+			return errLeaseLost
+		}
+		return w.stopCancelled(ctx, conversion.ID, output)
+	}
+	if err != nil {
+		return conversionFailure(err)
+	}
+
+	if err := output.Close(); err != nil {
+		return failure(domain.ErrorStageStorage, domain.ErrorCodeStorageUnavailable, true, fmt.Errorf("failed to finalize converted files: %w", err))
+	}
+
+	artifacts := output.Artifacts()
+	if len(artifacts) == 0 {
+		return failure(domain.ErrorStageConversion, domain.ErrorCodeEmptyOutput, false, fmt.Errorf("converter produced no output"))
+	}
+	convertedPath := artifacts[0].Path
+
+	updateData = bson.M{
+		"conversion.progress":    100,
+		"conversion.completedAt": time.Now(),
+		"file.convertedName":     artifacts[0].Name,
+		"file.convertedPath":     convertedPath,
+		"file.artifacts":         artifacts,
+		// errors of earlier attempts no longer describe the conversion
+		"conversion.error":        repository.Unset,
+		"conversion.errorMessage": repository.Unset,
+		"job.errorMessage":        repository.Unset,
+	}
+
+	if previewPath != "" {
+		updateData["file.previewPath"] = previewPath
+	}
+
+	err = w.repo.TransitionConversion(ctx, conversion.ID, domain.ConversionCompleted, updateData, domain.ConversionInProgress)
+	if err != nil && !errors.Is(err, domain.ErrIllegalTransition) {
+		return failure(domain.ErrorStageStorage, domain.ErrorCodeDatabaseError, true, fmt.Errorf("failed to mark conversion as completed: %w", err))
+	}
 	if err != nil {
-		return fmt.Errorf("failed to emulate file conversion: %w", err)
+		// the files now belong to the worker the conversion was handed to
+		if !lease.confirm(ctx) {
+			return errLeaseLost
+		}
+		return w.stopCancelled(ctx, conversion.ID, output)
 	}
 
-	log.Info("Converted file stored at: %s", convertedFilePath)
+	log.Info("Converted file stored at: %s (%d artifacts)", convertedPath, len(artifacts))
 
 	return nil
 }
+
+// firstError returns the message of the first error-severity problem of a report
+func firstError(report domain.ValidationReport) string {
+	for _, problem := range report.Problems {
+		if problem.Severity == domain.ValidationError {
+			return problem.Message
+		}
+	}
+	return "unknown problem"
+}
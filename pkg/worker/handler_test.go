@@ -0,0 +1,424 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	config "github.com/wildan3105/converto/configs"
+	"github.com/wildan3105/converto/pkg/api/schema"
+	"github.com/wildan3105/converto/pkg/converter"
+	"github.com/wildan3105/converto/pkg/domain"
+	"github.com/wildan3105/converto/pkg/infrastructure/filestorage"
+	"github.com/wildan3105/converto/pkg/infrastructure/rabbitmq"
+	"github.com/wildan3105/converto/pkg/repository"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// memoryRepository keeps conversions in memory and claims jobs with the rule of the Mongo repository,
+// which test/integration runs against MongoDB
+type memoryRepository struct {
+	mu          sync.Mutex
+	conversions map[string]*domain.Conversion
+	claims      int
+}
+
+func newMemoryRepository(conversions ...*domain.Conversion) *memoryRepository {
+	repo := &memoryRepository{conversions: map[string]*domain.Conversion{}}
+	for _, conversion := range conversions {
+		repo.conversions[conversion.ID] = conversion
+	}
+	return repo
+}
+
+func (r *memoryRepository) get(id string) domain.Conversion {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return *r.conversions[id]
+}
+
+func (r *memoryRepository) CreateConversion(_ context.Context, conversion *domain.Conversion) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.conversions[conversion.ID] = conversion
+	return conversion.ID, nil
+}
+
+func (r *memoryRepository) GetConversionByID(_ context.Context, id string) (*domain.Conversion, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	conversion, ok := r.conversions[id]
+	if !ok {
+		return nil, nil
+	}
+	return snapshot(conversion), nil
+}
+
+func (r *memoryRepository) UpdateConversion(_ context.Context, _ string, _ bson.M) error {
+	return nil
+}
+
+func (r *memoryRepository) TransitionConversion(_ context.Context, id string, to domain.ConversionStatus, _ bson.M, from ...domain.ConversionStatus) error {
+	if err := domain.ValidateTransition(to, from...); err != nil {
+		return err
+	}
+	if len(from) == 0 {
+		from = domain.TransitionSources(to)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	conversion, ok := r.conversions[id]
+	if !ok {
+		return domain.ErrConversionNotFound
+	}
+	for _, status := range from {
+		if conversion.Conversion.Status == status {
+			conversion.Conversion.Status = to
+			return nil
+		}
+	}
+	return &domain.TransitionError{ConversionID: id, From: conversion.Conversion.Status, To: to}
+}
+
+func (r *memoryRepository) AppendAttempt(_ context.Context, id string, attempt domain.Attempt) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	conversion := r.conversions[id]
+	conversion.Conversion.Attempts = append(conversion.Conversion.Attempts, attempt)
+	return nil
+}
+
+func (r *memoryRepository) ClaimJob(_ context.Context, id, jobID string, lease domain.Lease) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	conversion, ok := r.conversions[id]
+	if !ok || !claimable(conversion, jobID, lease) {
+		return false, nil
+	}
+	r.claims++
+	conversion.Job.Lease = &lease
+	conversion.Job.Attempt = lease.Attempt
+	conversion.Conversion.Status = domain.ConversionInProgress
+	return true, nil
+}
+
+// claimable mirrors the filter of the Mongo ClaimJob
+func claimable(c *domain.Conversion, jobID string, lease domain.Lease) bool {
+	if jobID != "" && c.Job.ID != jobID {
+		return false
+	}
+	if !c.Conversion.Status.CanTransitionTo(domain.ConversionInProgress) {
+		return false
+	}
+	if c.Job.Lease != nil && c.Job.Lease.ExpiresAt.After(lease.AcquiredAt) {
+		return false
+	}
+	if lease.Attempt > c.Job.Attempt {
+		return true
+	}
+	return lease.Attempt == c.Job.Attempt && c.Conversion.Status == domain.ConversionInProgress
+}
+
+func (r *memoryRepository) RenewLease(_ context.Context, id, owner string, expiresAt time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	lease := r.conversions[id].Job.Lease
+	if lease == nil || lease.Owner != owner {
+		return false, nil
+	}
+	lease.ExpiresAt = expiresAt
+	return true, nil
+}
+
+func (r *memoryRepository) ReleaseLease(_ context.Context, id, owner string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	conversion := r.conversions[id]
+	if conversion.Job.Lease != nil && conversion.Job.Lease.Owner == owner {
+		conversion.Job.Lease = nil
+	}
+	return nil
+}
+
+func (r *memoryRepository) ListExpiredLeases(_ context.Context, now, unleasedBefore time.Time, _ int) ([]*domain.Conversion, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var conversions []*domain.Conversion
+	for _, conversion := range r.conversions {
+		if conversion.Conversion.Status != domain.ConversionInProgress {
+			continue
+		}
+		lease := conversion.Job.Lease
+		if lease != nil && lease.ExpiresAt.Before(now) || lease == nil && conversion.Job.UpdatedAt.Before(unleasedBefore) {
+			conversions = append(conversions, snapshot(conversion))
+		}
+	}
+	return conversions, nil
+}
+
+func (r *memoryRepository) ReclaimConversion(_ context.Context, id string, lease *domain.Lease, to domain.ConversionStatus, _ bson.M) (bool, error) {
+	if err := domain.ValidateTransition(to, domain.ConversionInProgress); err != nil {
+		return false, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	conversion := r.conversions[id]
+	if conversion.Conversion.Status != domain.ConversionInProgress {
+		return false, nil
+	}
+	current := conversion.Job.Lease
+	if (lease == nil) != (current == nil) || lease != nil && (lease.Owner != current.Owner || !lease.ExpiresAt.Equal(current.ExpiresAt)) {
+		return false, nil
+	}
+	conversion.Conversion.Status = to
+	conversion.Job.Lease = nil
+	return true, nil
+}
+
+func (r *memoryRepository) ListPendingOutbox(_ context.Context, _ time.Time, _ int) ([]*domain.Conversion, error) {
+	return nil, nil
+}
+
+func (r *memoryRepository) UpdateOutbox(_ context.Context, _, _ string, _ bson.M) (bool, error) {
+	return false, nil
+}
+
+func (r *memoryRepository) ListConversions(_ context.Context, _ string, _, _ int) ([]*domain.Conversion, error) {
+	return nil, nil
+}
+
+func (r *memoryRepository) ListConversionsByUploadID(_ context.Context, _ string) ([]*domain.Conversion, error) {
+	return nil, nil
+}
+
+func (r *memoryRepository) ListConversionsByBatchID(_ context.Context, _ string) ([]*domain.Conversion, error) {
+	return nil, nil
+}
+
+// snapshot copies a conversion as read from the database, so that later writes do not change it
+func snapshot(conversion *domain.Conversion) *domain.Conversion {
+	copied := *conversion
+	if conversion.Job.Lease != nil {
+		lease := *conversion.Job.Lease
+		copied.Job.Lease = &lease
+	}
+	return &copied
+}
+
+// memoryLocks keeps named locks in memory with the rule of the Mongo lock repository
+type memoryLocks struct {
+	mu     sync.Mutex
+	owners map[string]string
+	expiry map[string]time.Time
+}
+
+func newMemoryLocks() *memoryLocks {
+	return &memoryLocks{owners: map[string]string{}, expiry: map[string]time.Time{}}
+}
+
+func (l *memoryLocks) AcquireLock(_ context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if current, ok := l.owners[name]; ok && current != owner && l.expiry[name].After(now) {
+		return false, nil
+	}
+	l.owners[name] = owner
+	l.expiry[name] = now.Add(ttl)
+	return true, nil
+}
+
+func (l *memoryLocks) ReleaseLock(_ context.Context, name, owner string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.owners[name] == owner {
+		delete(l.owners, name)
+		delete(l.expiry, name)
+	}
+	return nil
+}
+
+func (l *memoryLocks) owner(name string) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.owners[name]
+}
+
+// memoryPublisher records the published jobs and dead letters
+type memoryPublisher struct {
+	mu          sync.Mutex
+	jobs        []schema.ConversionEvent
+	deadLetters []schema.ConversionEvent
+}
+
+func (p *memoryPublisher) PublishConversionJob(_ context.Context, event schema.ConversionEvent, _, _ string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.jobs = append(p.jobs, event)
+	return nil
+}
+
+func (p *memoryPublisher) PublishDeadLetter(_ context.Context, event schema.ConversionEvent, _, _ string, _ rabbitmq.DeadLetterReason, _ error) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.deadLetters = append(p.deadLetters, event)
+	return nil
+}
+
+func (p *memoryPublisher) published() ([]schema.ConversionEvent, []schema.ConversionEvent) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]schema.ConversionEvent(nil), p.jobs...), append([]schema.ConversionEvent(nil), p.deadLetters...)
+}
+
+// newTestWorker creates a worker without converters, so that every attempt it runs fails at once
+// without being retried
+func newTestWorker(t *testing.T, repo *memoryRepository) *Worker {
+	config.AppConfig.LeaseDuration = time.Minute
+	config.AppConfig.RetryMaxAttempts = 3
+
+	return &Worker{
+		id:         repository.NewOwnerID(),
+		publisher:  &memoryPublisher{},
+		repo:       repo,
+		storage:    filestorage.NewLocalFileStorage(t.TempDir()),
+		converters: converter.NewRegistry(),
+	}
+}
+
+func pendingConversion() *domain.Conversion {
+	return &domain.Conversion{
+		ID:         "conversion-1",
+		Conversion: domain.ConversionData{TargetFormat: ".unknown", Status: domain.ConversionPending},
+		Job:        domain.ConversionJob{ID: "job-1"},
+	}
+}
+
+func event(attempt int) schema.ConversionEvent {
+	return schema.ConversionEvent{JobID: "job-1", ConversionID: "conversion-1", Attempt: attempt}
+}
+
+func TestHandleSkipsDuplicateOfFinishedAttempt(t *testing.T) {
+	repo := newMemoryRepository(pendingConversion())
+	w := newTestWorker(t, repo)
+
+	assert.Error(t, w.Handle(context.Background(), event(1)))
+	assert.NoError(t, w.Handle(context.Background(), event(1)))
+
+	conversion := repo.get("conversion-1")
+	assert.Equal(t, domain.ConversionFailed, conversion.Conversion.Status)
+	assert.Len(t, conversion.Conversion.Attempts, 1)
+	assert.Equal(t, 1, repo.claims)
+}
+
+func TestHandleSkipsDuplicateWhileAnotherWorkerRunsIt(t *testing.T) {
+	conversion := pendingConversion()
+	conversion.Conversion.Status = domain.ConversionInProgress
+	conversion.Job.Attempt = 1
+	conversion.Job.Lease = &domain.Lease{Owner: "other-worker", Attempt: 1, ExpiresAt: time.Now().Add(time.Minute)}
+
+	repo := newMemoryRepository(conversion)
+	w := newTestWorker(t, repo)
+
+	assert.NoError(t, w.Handle(context.Background(), event(1)))
+
+	stored := repo.get("conversion-1")
+	assert.Equal(t, domain.ConversionInProgress, stored.Conversion.Status)
+	assert.Equal(t, "other-worker", stored.Job.Lease.Owner)
+	assert.Empty(t, stored.Conversion.Attempts)
+}
+
+func TestHandleSkipsDuplicateOfAttemptWaitingForRetry(t *testing.T) {
+	conversion := pendingConversion()
+	conversion.Job.Attempt = 1
+	conversion.Conversion.Attempts = []domain.Attempt{{Number: 1}}
+
+	repo := newMemoryRepository(conversion)
+	w := newTestWorker(t, repo)
+
+	assert.NoError(t, w.Handle(context.Background(), event(1)))
+	assert.Equal(t, domain.ConversionPending, repo.get("conversion-1").Conversion.Status)
+
+	// the retry itself runs
+	assert.Error(t, w.Handle(context.Background(), event(2)))
+	assert.Len(t, repo.get("conversion-1").Conversion.Attempts, 2)
+}
+
+func TestHandleSkipsJobReplacedByManualRetry(t *testing.T) {
+	conversion := pendingConversion()
+	conversion.Job.ID = "job-2"
+
+	repo := newMemoryRepository(conversion)
+	w := newTestWorker(t, repo)
+
+	assert.NoError(t, w.Handle(context.Background(), event(1)))
+	assert.Equal(t, domain.ConversionPending, repo.get("conversion-1").Conversion.Status)
+	assert.Zero(t, repo.claims)
+}
+
+func TestHandleRunsConcurrentDeliveriesOnce(t *testing.T) {
+	repo := newMemoryRepository(pendingConversion())
+
+	// each delivery may reach a different worker
+	workers := make([]*Worker, 8)
+	for i := range workers {
+		workers[i] = newTestWorker(t, repo)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(workers))
+	for _, w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- w.Handle(context.Background(), event(1))
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	failed := 0
+	for err := range errs {
+		if err != nil {
+			failed++
+		}
+	}
+
+	require.Equal(t, 1, repo.claims)
+	assert.Equal(t, 1, failed)
+	assert.Len(t, repo.get("conversion-1").Conversion.Attempts, 1)
+}
+
+// panickingRepository panics when a job is claimed, as a crashing decoder would while the job runs
+type panickingRepository struct {
+	*memoryRepository
+}
+
+func (r panickingRepository) ClaimJob(context.Context, string, string, domain.Lease) (bool, error) {
+	panic("index out of range [-1]")
+}
+
+func TestHandleRecoveredFailsPanickedJob(t *testing.T) {
+	repo := newMemoryRepository(pendingConversion())
+	w := newTestWorker(t, repo)
+	w.repo = panickingRepository{repo}
+	publisher := w.publisher.(*memoryPublisher)
+
+	assert.Error(t, w.handleRecovered(context.Background(), event(1)))
+
+	conversion := repo.get("conversion-1")
+	assert.Equal(t, domain.ConversionFailed, conversion.Conversion.Status)
+	require.Len(t, conversion.Conversion.Attempts, 1)
+	assert.Equal(t, domain.ErrorCodeInternal, conversion.Conversion.Attempts[0].Error.Code)
+	assert.False(t, conversion.Conversion.Attempts[0].Error.Retryable)
+
+	jobs, deadLetters := publisher.published()
+	assert.Empty(t, jobs)
+	require.Len(t, deadLetters, 1)
+	assert.Equal(t, "job-1", deadLetters[0].JobID)
+}
@@ -0,0 +1,136 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	config "github.com/wildan3105/converto/configs"
+	"github.com/wildan3105/converto/pkg/api/schema"
+	"github.com/wildan3105/converto/pkg/domain"
+	"github.com/wildan3105/converto/pkg/repository"
+)
+
+// errLeaseLost is returned when a worker no longer holds the lease of the job it runs, because the
+// reaper handed the job to another worker
+var errLeaseLost = errors.New("lease lost")
+
+// lease is the claim of a worker on a running conversion, renewed at most every third of its duration
+type lease struct {
+	repo  repository.ConversionRepository
+	id    string
+	owner string
+	ttl   time.Duration
+
+	mu        sync.Mutex
+	renewedAt time.Time
+	lost      bool
+}
+
+// claim takes the lease on a conversion for the attempt of its job carried by event. It returns nil
+// when the delivery is a duplicate: the attempt is claimed by another worker or already ran, the
+// job was replaced by a manual retry, or the conversion finished.
+func (w *Worker) claim(ctx context.Context, event schema.ConversionEvent) (*lease, error) {
+	ttl := config.AppConfig.LeaseDuration
+	now := time.Now()
+
+	claimed, err := w.repo.ClaimJob(ctx, event.ConversionID, event.JobID, domain.Lease{
+		Owner:      w.id,
+		Attempt:    event.Attempt,
+		AcquiredAt: now,
+		ExpiresAt:  now.Add(ttl),
+	})
+	if err != nil || !claimed {
+		return nil, err
+	}
+
+	return &lease{repo: w.repo, id: event.ConversionID, owner: w.id, ttl: ttl, renewedAt: now}, nil
+}
+
+// renew extends the lease once a third of it has elapsed since it was last extended. It reports
+// whether the lease is still held; a failure to reach the database does not count as losing it.
+func (l *lease) renew(ctx context.Context) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.lost {
+		return false
+	}
+	if time.Since(l.renewedAt) < l.ttl/3 {
+		return true
+	}
+
+	return l.extend(ctx)
+}
+
+// confirm extends the lease right away and reports whether it is still held
+func (l *lease) confirm(ctx context.Context) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.lost {
+		return false
+	}
+
+	return l.extend(ctx)
+}
+
+// extend writes the new expiry of the lease; the caller holds the mutex
+func (l *lease) extend(ctx context.Context) bool {
+	now := time.Now()
+
+	held, err := l.repo.RenewLease(ctx, l.id, l.owner, now.Add(l.ttl))
+	if err != nil {
+		log.Warn("Failed to renew lease on conversion ID %s: %v", l.id, err)
+		return true
+	}
+	if !held {
+		log.Warn("Lost lease on conversion ID %s", l.id)
+		l.lost = true
+		return false
+	}
+
+	l.renewedAt = now
+	return true
+}
+
+// isLost reports whether the lease was found taken away
+func (l *lease) isLost() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.lost
+}
+
+// release gives up the lease, even when the worker is stopping, so that the job can be picked up
+// again without waiting for the lease to expire
+func (l *lease) release(ctx context.Context) {
+	if err := l.repo.ReleaseLease(context.WithoutCancel(ctx), l.id, l.owner); err != nil {
+		log.Warn("Failed to release lease on conversion ID %s: %v", l.id, err)
+	}
+}
+
+// keepLease renews the lease while the converter runs between progress updates, and calls cancel
+// once the lease is lost. It returns when ctx is done.
+func (l *lease) keepLease(ctx context.Context, cancel context.CancelFunc) {
+	interval := l.ttl / 3
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !l.renew(ctx) {
+				cancel()
+				return
+			}
+		}
+	}
+}
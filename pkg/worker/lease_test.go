@@ -0,0 +1,60 @@
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wildan3105/converto/pkg/domain"
+)
+
+func TestLeaseRenewsWhileHeld(t *testing.T) {
+	repo := newMemoryRepository(pendingConversion())
+	w := newTestWorker(t, repo)
+
+	l, err := w.claim(context.Background(), event(1))
+	require.NoError(t, err)
+	require.NotNil(t, l)
+	expiresAt := repo.get("conversion-1").Job.Lease.ExpiresAt
+
+	// renewed at most every third of the lease
+	assert.True(t, l.renew(context.Background()))
+	assert.Equal(t, expiresAt, repo.get("conversion-1").Job.Lease.ExpiresAt)
+
+	assert.True(t, l.confirm(context.Background()))
+	assert.True(t, repo.get("conversion-1").Job.Lease.ExpiresAt.After(expiresAt))
+	assert.False(t, l.isLost())
+
+	l.release(context.Background())
+	assert.Nil(t, repo.get("conversion-1").Job.Lease)
+}
+
+func TestLeaseLostToReaper(t *testing.T) {
+	repo := newMemoryRepository(pendingConversion())
+	w := newTestWorker(t, repo)
+
+	l, err := w.claim(context.Background(), event(1))
+	require.NoError(t, err)
+	require.NotNil(t, l)
+
+	// the worker hangs past its lease and the reaper requeues the job
+	repo.mu.Lock()
+	repo.conversions["conversion-1"].Job.Lease.ExpiresAt = time.Now().Add(-time.Second)
+	repo.mu.Unlock()
+	r, publisher := newTestReaper(repo, newMemoryLocks())
+	r.reap(context.Background())
+	require.Equal(t, domain.ConversionPending, repo.get("conversion-1").Conversion.Status)
+
+	assert.False(t, l.confirm(context.Background()))
+	assert.True(t, l.isLost())
+	assert.False(t, l.renew(context.Background()))
+
+	// the next attempt is claimed by another worker
+	jobs, _ := publisher.published()
+	require.Len(t, jobs, 1)
+	next, err := newTestWorker(t, repo).claim(context.Background(), jobs[0])
+	require.NoError(t, err)
+	assert.NotNil(t, next)
+}
@@ -0,0 +1,93 @@
+package worker
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/wildan3105/converto/pkg/domain"
+	"github.com/wildan3105/converto/pkg/format"
+	"github.com/wildan3105/converto/pkg/infrastructure/filestorage"
+)
+
+// artifactOutput stores the files created by a converter as the converted files of one conversion
+type artifactOutput struct {
+	storage filestorage.FileStorage
+	id      string
+	files   []*artifactFile
+}
+
+// artifactFile tracks the size of a converted file while it is written
+type artifactFile struct {
+	io.WriteCloser
+	name   string
+	path   string
+	size   int64
+	closed bool
+}
+
+func newArtifactOutput(storage filestorage.FileStorage, id string) *artifactOutput {
+	return &artifactOutput{storage: storage, id: id}
+}
+
+// Create creates a converted file; names must be plain file names
+func (o *artifactOutput) Create(name string) (io.WriteCloser, error) {
+	if name == "" || name != filepath.Base(name) {
+		return nil, fmt.Errorf("invalid artifact name %q", name)
+	}
+
+	for _, file := range o.files {
+		if file.name == name {
+			return nil, fmt.Errorf("artifact %q already created", name)
+		}
+	}
+
+	writer, path, err := o.storage.CreateFile(domain.FileCategoryConverted, o.id, name)
+	if err != nil {
+		return nil, err
+	}
+
+	file := &artifactFile{WriteCloser: writer, name: name, path: path}
+	o.files = append(o.files, file)
+
+	return file, nil
+}
+
+// Close closes every file that the converter left open
+func (o *artifactOutput) Close() error {
+	var firstErr error
+	for _, file := range o.files {
+		if err := file.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Artifacts returns the created files in creation order
+func (o *artifactOutput) Artifacts() []domain.Artifact {
+	artifacts := make([]domain.Artifact, len(o.files))
+	for i, file := range o.files {
+		artifacts[i] = domain.Artifact{
+			Name:        file.name,
+			Path:        file.path,
+			ContentType: format.ContentType(file.name),
+			SizeInBytes: file.size,
+		}
+	}
+	return artifacts
+}
+
+func (f *artifactFile) Write(p []byte) (int, error) {
+	n, err := f.WriteCloser.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+func (f *artifactFile) Close() error {
+	if f.closed {
+		return nil
+	}
+	f.closed = true
+	return f.WriteCloser.Close()
+}
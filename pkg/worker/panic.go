@@ -0,0 +1,55 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/wildan3105/converto/pkg/api/schema"
+	"github.com/wildan3105/converto/pkg/domain"
+	"github.com/wildan3105/converto/pkg/infrastructure/rabbitmq"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// handleRecovered runs Handle and turns a panic into the failure of the conversion, so that a job
+// crashing the worker, such as a malformed model a decoder does not guard against, is not redelivered
+// to every worker in turn
+func (w *Worker) handleRecovered(ctx context.Context, event schema.ConversionEvent) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error("Panic while handling conversion ID %s: %v\n%s", event.ConversionID, r, debug.Stack())
+			err = w.failPanicked(ctx, event, fmt.Errorf("worker panicked: %v", r))
+		}
+	}()
+
+	return w.Handle(ctx, event)
+}
+
+// failPanicked marks the conversion of a job whose handling panicked as failed, records the attempt
+// and moves the job to the dead-letter queue. Another attempt would panic again, so it is not retried.
+func (w *Worker) failPanicked(ctx context.Context, event schema.ConversionEvent, cause error) error {
+	ctx = context.WithoutCancel(ctx)
+	now := time.Now()
+	record := errorRecord(failure(domain.ErrorStageConversion, domain.ErrorCodeInternal, false, cause), now)
+
+	updateData := bson.M{
+		"conversion.error":        record,
+		"conversion.errorMessage": record.Message,
+		"job.errorMessage":        record.Message,
+		"conversion.completedAt":  now,
+	}
+
+	err := w.repo.TransitionConversion(ctx, event.ConversionID, domain.ConversionFailed, updateData, activeStatuses...)
+	if err != nil {
+		log.Warn("Failed to mark panicked conversion ID %s as 'failed': %v", event.ConversionID, err)
+	} else if conversion, err := w.repo.GetConversionByID(ctx, event.ConversionID); err == nil && conversion != nil {
+		attempt := domain.Attempt{Number: len(conversion.Conversion.Attempts) + 1, FinishedAt: now, Error: record}
+		if err := w.repo.AppendAttempt(ctx, event.ConversionID, attempt); err != nil {
+			log.Warn("Failed to record attempt %d of conversion ID %s: %v", attempt.Number, event.ConversionID, err)
+		}
+	}
+
+	w.deadLetter(ctx, event, rabbitmq.DeadLetterPanicked, cause)
+	return cause
+}
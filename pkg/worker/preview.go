@@ -0,0 +1,37 @@
+package worker
+
+import (
+	config "github.com/wildan3105/converto/configs"
+	"github.com/wildan3105/converto/pkg/domain"
+	"github.com/wildan3105/converto/pkg/render"
+	"github.com/wildan3105/converto/pkg/scene"
+)
+
+// previewFileName is the name of the preview image in the preview storage category
+const previewFileName = "preview.png"
+
+// renderPreview draws the model with the configured view and stores it as a PNG, returning its path
+func (w *Worker) renderPreview(id string, model *scene.Scene) (string, error) {
+	file, path, err := w.storage.CreateFile(domain.FileCategoryPreview, id, previewFileName)
+	if err != nil {
+		return "", err
+	}
+
+	opts := render.Options{
+		Width:     config.AppConfig.PreviewWidth,
+		Height:    config.AppConfig.PreviewHeight,
+		Azimuth:   config.AppConfig.PreviewAzimuth,
+		Elevation: config.AppConfig.PreviewElevation,
+	}
+
+	if err := render.EncodePNG(file, model, opts); err != nil {
+		file.Close()
+		return "", err
+	}
+
+	if err := file.Close(); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
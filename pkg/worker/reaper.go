@@ -0,0 +1,171 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	externalLog "log"
+	"time"
+
+	config "github.com/wildan3105/converto/configs"
+	"github.com/wildan3105/converto/pkg/api/schema"
+	"github.com/wildan3105/converto/pkg/domain"
+	"github.com/wildan3105/converto/pkg/infrastructure/rabbitmq"
+	"github.com/wildan3105/converto/pkg/repository"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	// reaperLock is the lock electing the instance that reaps abandoned jobs
+	reaperLock = "reaper"
+	// reapBatchSize bounds the conversions reclaimed on each tick
+	reapBatchSize = 100
+)
+
+// Reaper reclaims the conversions of workers that died or hung while running them: once the lease
+// of a running conversion expired, the conversion goes back to pending and its job is published
+// again, or it is marked as failed when it has no attempts left. Every instance runs a reaper, but
+// only the one holding the reaper lock reclaims conversions.
+type Reaper struct {
+	id        string
+	repo      repository.ConversionRepository
+	locks     repository.LockRepository
+	publisher jobPublisher
+}
+
+// NewReaper creates a new Reaper instance
+func NewReaper(repo repository.ConversionRepository, locks repository.LockRepository, publisher *rabbitmq.Publisher) *Reaper {
+	if repo == nil {
+		externalLog.Fatal("ConversionRepository cannot be nil")
+	}
+	if locks == nil {
+		externalLog.Fatal("LockRepository cannot be nil")
+	}
+	if publisher == nil {
+		externalLog.Fatal("Publisher cannot be nil")
+	}
+
+	return &Reaper{
+		id:        repository.NewOwnerID(),
+		repo:      repo,
+		locks:     locks,
+		publisher: publisher,
+	}
+}
+
+// Run reaps abandoned conversions every reaper interval until ctx is done. The lock outlives two
+// intervals, so that leadership moves to another instance soon after the leader stops.
+func (r *Reaper) Run(ctx context.Context) {
+	interval := config.AppConfig.ReaperInterval
+	if interval <= 0 {
+		log.Info("Reaper disabled")
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	defer func() {
+		if err := r.locks.ReleaseLock(context.WithoutCancel(ctx), reaperLock, r.id); err != nil {
+			log.Warn("Failed to release reaper lock: %v", err)
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			leader, err := r.locks.AcquireLock(ctx, reaperLock, r.id, 2*interval)
+			if err != nil {
+				log.Warn("Failed to acquire reaper lock: %v", err)
+				continue
+			}
+			if leader {
+				r.reap(ctx)
+			}
+		}
+	}
+}
+
+// reap reclaims the conversions whose lease expired. Conversions left running without a lease are
+// reclaimed once they have not been updated for a lease duration.
+func (r *Reaper) reap(ctx context.Context) {
+	now := time.Now()
+
+	conversions, err := r.repo.ListExpiredLeases(ctx, now, now.Add(-config.AppConfig.LeaseDuration), reapBatchSize)
+	if err != nil {
+		log.Warn("Failed to list abandoned conversions: %v", err)
+		return
+	}
+
+	for _, conversion := range conversions {
+		if err := r.reclaim(ctx, conversion); err != nil {
+			log.Warn("Failed to reclaim conversion ID %s: %v", conversion.ID, err)
+		}
+	}
+}
+
+// reclaim records the abandoned attempt of a conversion, then hands its job to another worker or
+// fails it when it has no attempts left. A worker renewing its lease in the meantime keeps the job.
+func (r *Reaper) reclaim(ctx context.Context, conversion *domain.Conversion) error {
+	lease := conversion.Job.Lease
+
+	attempt := domain.Attempt{Number: len(conversion.Conversion.Attempts) + 1}
+	eventAttempt := attempt.Number
+	if lease != nil {
+		attempt.StartedAt = lease.AcquiredAt
+		eventAttempt = lease.Attempt
+	}
+
+	now := time.Now()
+	attempt.FinishedAt = now
+	cause := failure(domain.ErrorStageConversion, domain.ErrorCodeLeaseExpired, true, fmt.Errorf("worker stopped renewing its lease"))
+	attempt.Error = errorRecord(cause, now)
+	record := attempt.Error
+
+	exhausted := eventAttempt >= config.AppConfig.RetryMaxAttempts
+
+	updateData := bson.M{
+		"conversion.error":        record,
+		"conversion.errorMessage": record.Message,
+		"job.errorMessage":        record.Message,
+	}
+	status := domain.ConversionPending
+	if exhausted {
+		status = domain.ConversionFailed
+		updateData["conversion.completedAt"] = now
+	} else {
+		updateData["conversion.progress"] = 0
+	}
+
+	reclaimed, err := r.repo.ReclaimConversion(ctx, conversion.ID, lease, status, updateData)
+	if err != nil {
+		return err
+	}
+	if !reclaimed {
+		return nil
+	}
+
+	if err := r.repo.AppendAttempt(ctx, conversion.ID, attempt); err != nil {
+		log.Warn("Failed to record attempt %d of conversion ID %s: %v", attempt.Number, conversion.ID, err)
+	}
+
+	event := schema.ConversionEvent{
+		JobID:        conversion.Job.ID,
+		ConversionID: conversion.ID,
+		Source:       conversion.Job.Source,
+		Options:      conversion.Conversion.Options,
+		Attempt:      eventAttempt,
+		CreatedAt:    conversion.Job.CreatedAt,
+		UpdatedAt:    now,
+	}
+
+	if exhausted {
+		log.Warn("Conversion ID %s abandoned on its last attempt, marked as failed", conversion.ID)
+		return r.publisher.PublishDeadLetter(ctx, event, config.AppConfig.RabbitMQExchangeName, config.AppConfig.RabbitMQRoutingKey, rabbitmq.DeadLetterExhausted, cause)
+	}
+
+	event.Attempt++
+	log.Info("Conversion ID %s abandoned on attempt %d, requeued", conversion.ID, eventAttempt)
+	return r.publisher.PublishConversionJob(ctx, event, config.AppConfig.RabbitMQExchangeName, config.AppConfig.RabbitMQRoutingKey)
+}
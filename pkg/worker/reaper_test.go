@@ -0,0 +1,142 @@
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	config "github.com/wildan3105/converto/configs"
+	"github.com/wildan3105/converto/pkg/domain"
+	"github.com/wildan3105/converto/pkg/repository"
+)
+
+// newTestReaper creates a reaper publishing to a memory publisher, with 3 attempts per job
+func newTestReaper(repo *memoryRepository, locks *memoryLocks) (*Reaper, *memoryPublisher) {
+	config.AppConfig.LeaseDuration = time.Minute
+	config.AppConfig.RetryMaxAttempts = 3
+
+	publisher := &memoryPublisher{}
+	return &Reaper{id: repository.NewOwnerID(), repo: repo, locks: locks, publisher: publisher}, publisher
+}
+
+// runningConversion is a conversion running attempt of its job under a lease expiring after expiresIn
+func runningConversion(attempt int, expiresIn time.Duration) *domain.Conversion {
+	now := time.Now()
+	conversion := pendingConversion()
+	conversion.Conversion.Status = domain.ConversionInProgress
+	conversion.Job.Attempt = attempt
+	conversion.Job.Lease = &domain.Lease{
+		Owner:      "dead-worker",
+		Attempt:    attempt,
+		AcquiredAt: now.Add(expiresIn - time.Minute),
+		ExpiresAt:  now.Add(expiresIn),
+	}
+	return conversion
+}
+
+func TestReaperRequeuesExpiredLease(t *testing.T) {
+	repo := newMemoryRepository(runningConversion(1, -time.Second))
+	r, publisher := newTestReaper(repo, newMemoryLocks())
+
+	r.reap(context.Background())
+
+	conversion := repo.get("conversion-1")
+	assert.Equal(t, domain.ConversionPending, conversion.Conversion.Status)
+	assert.Nil(t, conversion.Job.Lease)
+	require.Len(t, conversion.Conversion.Attempts, 1)
+	assert.Equal(t, domain.ErrorCodeLeaseExpired, conversion.Conversion.Attempts[0].Error.Code)
+
+	jobs, deadLetters := publisher.published()
+	require.Len(t, jobs, 1)
+	assert.Equal(t, 2, jobs[0].Attempt)
+	assert.Equal(t, "job-1", jobs[0].JobID)
+	assert.Empty(t, deadLetters)
+}
+
+func TestReaperFailsExhaustedLease(t *testing.T) {
+	repo := newMemoryRepository(runningConversion(3, -time.Second))
+	r, publisher := newTestReaper(repo, newMemoryLocks())
+
+	r.reap(context.Background())
+
+	conversion := repo.get("conversion-1")
+	assert.Equal(t, domain.ConversionFailed, conversion.Conversion.Status)
+	assert.Len(t, conversion.Conversion.Attempts, 1)
+
+	jobs, deadLetters := publisher.published()
+	assert.Empty(t, jobs)
+	require.Len(t, deadLetters, 1)
+	assert.Equal(t, 3, deadLetters[0].Attempt)
+}
+
+func TestReaperKeepsLiveLease(t *testing.T) {
+	repo := newMemoryRepository(runningConversion(1, time.Minute))
+	r, publisher := newTestReaper(repo, newMemoryLocks())
+
+	r.reap(context.Background())
+
+	conversion := repo.get("conversion-1")
+	assert.Equal(t, domain.ConversionInProgress, conversion.Conversion.Status)
+	assert.Empty(t, conversion.Conversion.Attempts)
+
+	jobs, deadLetters := publisher.published()
+	assert.Empty(t, jobs)
+	assert.Empty(t, deadLetters)
+}
+
+func TestReaperKeepsLeaseRenewedMeanwhile(t *testing.T) {
+	repo := newMemoryRepository(runningConversion(1, -time.Second))
+	r, publisher := newTestReaper(repo, newMemoryLocks())
+
+	expired, err := repo.ListExpiredLeases(context.Background(), time.Now(), time.Now(), reapBatchSize)
+	require.NoError(t, err)
+	require.Len(t, expired, 1)
+
+	// the worker renews its lease between the listing and the reclaim
+	held, err := repo.RenewLease(context.Background(), "conversion-1", "dead-worker", time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	require.True(t, held)
+
+	require.NoError(t, r.reclaim(context.Background(), expired[0]))
+
+	conversion := repo.get("conversion-1")
+	assert.Equal(t, domain.ConversionInProgress, conversion.Conversion.Status)
+	assert.Equal(t, "dead-worker", conversion.Job.Lease.Owner)
+	assert.Empty(t, conversion.Conversion.Attempts)
+
+	jobs, deadLetters := publisher.published()
+	assert.Empty(t, jobs)
+	assert.Empty(t, deadLetters)
+}
+
+func TestReaperReapsOnlyAsLeader(t *testing.T) {
+	repo := newMemoryRepository(runningConversion(1, -time.Second))
+	locks := newMemoryLocks()
+	r, _ := newTestReaper(repo, locks)
+	config.AppConfig.ReaperInterval = 10 * time.Millisecond
+
+	_, err := locks.AcquireLock(context.Background(), reaperLock, "other-reaper", 100*time.Millisecond)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.Run(ctx)
+	}()
+
+	// another instance leads until its lock expires
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, domain.ConversionInProgress, repo.get("conversion-1").Conversion.Status)
+
+	assert.Eventually(t, func() bool {
+		return repo.get("conversion-1").Conversion.Status == domain.ConversionPending
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, r.id, locks.owner(reaperLock))
+
+	cancel()
+	<-done
+	assert.Empty(t, locks.owner(reaperLock))
+}
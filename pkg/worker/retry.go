@@ -0,0 +1,101 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	config "github.com/wildan3105/converto/configs"
+	"github.com/wildan3105/converto/pkg/api/schema"
+	"github.com/wildan3105/converto/pkg/domain"
+	"github.com/wildan3105/converto/pkg/infrastructure/rabbitmq"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// retryable reports whether a job whose attempt failed as recorded has attempts left and may succeed on another one
+func retryable(event schema.ConversionEvent, record *domain.ConversionError) bool {
+	return record.Retryable && event.Attempt < config.AppConfig.RetryMaxAttempts
+}
+
+// retryOrFail handles a failed attempt, whose error is recorded: its partial output is removed, then
+// the conversion waits as pending for another attempt when the failure is retryable, or is marked as
+// failed otherwise. Either way the error record is stored on the conversion. A job that ran out of
+// attempts is moved to the dead-letter queue. The returned error describes the outcome.
+func (w *Worker) retryOrFail(ctx context.Context, event schema.ConversionEvent, attempt *domain.Attempt, cause error) error {
+	record := attempt.Error
+
+	for _, category := range []domain.FileCategory{domain.FileCategoryConverted, domain.FileCategoryPreview} {
+		if err := w.storage.DeleteFiles(category, event.ConversionID); err != nil {
+			log.Warn("Failed to remove %s files of failed conversion ID %s: %v", category, event.ConversionID, err)
+		}
+	}
+
+	if retryable(event, record) {
+		err := w.repo.TransitionConversion(ctx, event.ConversionID, domain.ConversionPending, bson.M{
+			"conversion.progress":     0,
+			"conversion.error":        record,
+			"conversion.errorMessage": record.Message,
+			"job.errorMessage":        record.Message,
+		}, domain.ConversionInProgress)
+		if errors.Is(err, domain.ErrIllegalTransition) {
+			// cancelled during the attempt
+			return cause
+		}
+
+		if err == nil {
+			var delay time.Duration
+			if delay, err = w.scheduleRetry(ctx, event); err == nil {
+				retryAt := attempt.FinishedAt.Add(delay)
+				attempt.RetryAt = &retryAt
+				return fmt.Errorf("attempt %d failed, retrying in %v: %w", event.Attempt, delay, cause)
+			}
+		}
+		log.Warn("Failed to schedule retry of conversion ID %s: %v", event.ConversionID, err)
+	}
+
+	updateData := bson.M{
+		"conversion.error":        record,
+		"conversion.errorMessage": record.Message,
+		"job.errorMessage":        record.Message,
+		"conversion.completedAt":  time.Now(),
+	}
+
+	err := w.repo.TransitionConversion(ctx, event.ConversionID, domain.ConversionFailed, updateData, activeStatuses...)
+	if err != nil && !errors.Is(err, domain.ErrIllegalTransition) {
+		log.Warn("Failed to mark conversion as 'failed': %v", err)
+	}
+
+	if record.Retryable {
+		w.deadLetter(ctx, event, rabbitmq.DeadLetterExhausted, cause)
+	}
+	return cause
+}
+
+// deadLetter moves the job of a conversion that failed for good to the dead-letter queue
+func (w *Worker) deadLetter(ctx context.Context, event schema.ConversionEvent, reason rabbitmq.DeadLetterReason, cause error) {
+	if err := w.publisher.PublishDeadLetter(ctx, event, config.AppConfig.RabbitMQExchangeName, config.AppConfig.RabbitMQRoutingKey, reason, cause); err != nil {
+		log.Error("Failed to dead-letter job of conversion ID %s: %v", event.ConversionID, err)
+	}
+}
+
+// scheduleRetry publishes the next attempt of a job to the retry queue of its backoff, from which
+// it is redelivered to the job queue once the backoff has elapsed. It returns the backoff.
+func (w *Worker) scheduleRetry(ctx context.Context, event schema.ConversionEvent) (time.Duration, error) {
+	if event.Attempt >= config.AppConfig.RetryMaxAttempts {
+		return 0, fmt.Errorf("no attempts left after %d", event.Attempt)
+	}
+
+	delay := rabbitmq.RetryDelay(event.Attempt, config.AppConfig.RetryBaseDelay, config.AppConfig.RetryMaxDelay)
+
+	next := event
+	next.Attempt++
+	next.UpdatedAt = time.Now()
+
+	// the default exchange routes a message to the queue named by its routing key
+	if err := w.publisher.PublishConversionJob(ctx, next, "", rabbitmq.RetryQueueName(config.AppConfig.RabbitMQQueueName, delay)); err != nil {
+		return 0, err
+	}
+
+	return delay, nil
+}
@@ -3,8 +3,10 @@ package worker
 import (
 	"context"
 	externalLog "log"
+	"sync"
 
 	"github.com/wildan3105/converto/pkg/api/schema"
+	"github.com/wildan3105/converto/pkg/converter"
 	"github.com/wildan3105/converto/pkg/infrastructure/filestorage"
 	"github.com/wildan3105/converto/pkg/infrastructure/rabbitmq"
 	"github.com/wildan3105/converto/pkg/logger"
@@ -13,55 +15,101 @@ import (
 
 var log = logger.GetInstance()
 
-// Worker is the core struct for managing job consumption and processing
+// jobPublisher publishes the jobs of conversions and moves the jobs that ran out of attempts to the
+// dead-letter queue. It is implemented by rabbitmq.Publisher.
+type jobPublisher interface {
+	PublishConversionJob(ctx context.Context, conversionEvent schema.ConversionEvent, exchange, routingKey string) error
+	PublishDeadLetter(ctx context.Context, conversionEvent schema.ConversionEvent, exchange, routingKey string, reason rabbitmq.DeadLetterReason, cause error) error
+}
+
+// Worker is the core struct for managing job consumption and processing. Its ID owns the leases it
+// takes on running conversions.
 type Worker struct {
-	consumer *rabbitmq.Consumer
-	repo     repository.ConversionRepository
-	storage  filestorage.FileStorage
+	id         string
+	consumer   *rabbitmq.Consumer
+	publisher  jobPublisher
+	repo       repository.ConversionRepository
+	storage    filestorage.FileStorage
+	converters *converter.Registry
 }
 
-// NewWorker creates a new Worker instance
-func NewWorker(consumer *rabbitmq.Consumer, repo repository.ConversionRepository, storage filestorage.FileStorage) *Worker {
+// NewWorker creates a new Worker instance. The publisher schedules the retries of failed jobs.
+func NewWorker(consumer *rabbitmq.Consumer, publisher *rabbitmq.Publisher, repo repository.ConversionRepository, storage filestorage.FileStorage, converters *converter.Registry) *Worker {
 	if consumer == nil {
 		externalLog.Fatal("Consumer cannot be nil")
 	}
+	if publisher == nil {
+		externalLog.Fatal("Publisher cannot be nil")
+	}
 	if repo == nil {
 		externalLog.Fatal("ConversionRepository cannot be nil")
 	}
 	if storage == nil {
 		externalLog.Fatal("FileStorage cannot be nil")
 	}
+	if converters == nil {
+		externalLog.Fatal("Converter registry cannot be nil")
+	}
 
 	return &Worker{
-		consumer: consumer,
-		repo:     repo,
-		storage:  storage,
+		id:         repository.NewOwnerID(),
+		consumer:   consumer,
+		publisher:  publisher,
+		repo:       repo,
+		storage:    storage,
+		converters: converters,
 	}
 }
 
-// Start begins consuming messages and processing conversion jobs
-func (w *Worker) Start(ctx context.Context, queueName string) error {
-	jobChan, err := w.consumer.Consume(ctx, queueName)
+// Start begins consuming messages and processing conversion jobs with a pool of concurrency
+// goroutines. As many jobs are prefetched, so that jobs are spread across worker instances.
+// A job is acknowledged once handled, or requeued when the worker stops while handling it.
+func (w *Worker) Start(ctx context.Context, queueName string, concurrency int) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	jobChan, err := w.consumer.Consume(ctx, queueName, concurrency)
 	if err != nil {
 		log.Error("Failed to start consuming messages: %v", err)
 		return err
 	}
 
-	for {
-		select {
-		case <-ctx.Done():
-			log.Info("Worker context cancelled, stopping job processing...")
-			return nil
-		case event, ok := <-jobChan:
-			if !ok {
-				log.Info("Job channel closed, exiting worker...")
-				return nil
+	log.Info("Processing jobs with %d concurrent workers", concurrency)
+
+	var wg sync.WaitGroup
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobChan {
+				w.run(ctx, job)
 			}
-			go func(event schema.ConversionEvent) {
-				if err := w.Handle(ctx, event); err != nil {
-					log.Error("Job processing failed: %v", err)
-				}
-			}(event)
+		}()
+	}
+
+	wg.Wait()
+	log.Info("Job channel closed, exiting worker...")
+	return nil
+}
+
+// run handles a job and then acknowledges it. Failures are acknowledged too, since they were
+// retried, dead-lettered or recorded on the conversion, and so are jobs whose handling panicked;
+// only a job interrupted by the worker stopping is requeued, for another worker to run it.
+func (w *Worker) run(ctx context.Context, job rabbitmq.Job) {
+	err := w.handleRecovered(ctx, job.Event)
+	if err != nil {
+		log.Error("Job processing failed: %v", err)
+	}
+
+	if ctx.Err() != nil {
+		if err := job.Nack(true); err != nil {
+			log.Warn("Failed to requeue job of conversion ID %s: %v", job.Event.ConversionID, err)
 		}
+		return
+	}
+
+	if err := job.Ack(); err != nil {
+		log.Warn("Failed to ack job of conversion ID %s: %v", job.Event.ConversionID, err)
 	}
 }
@@ -0,0 +1,74 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	config "github.com/wildan3105/converto/configs"
+	"github.com/wildan3105/converto/pkg/domain"
+	"github.com/wildan3105/converto/pkg/repository"
+)
+
+// TestClaimJob runs the claim rule of the workers against MongoDB: every delivery of a job but the
+// one that may run it is a duplicate
+func TestClaimJob(t *testing.T) {
+	repo := repository.NewMongoRepository(mongoClient, config.AppConfig.MongoDbName)
+	now := time.Now()
+	expired := &domain.Lease{Owner: "worker-a", Attempt: 1, ExpiresAt: now.Add(-time.Second)}
+	held := &domain.Lease{Owner: "worker-a", Attempt: 1, ExpiresAt: now.Add(time.Minute)}
+
+	conversion := func(status domain.ConversionStatus, attempt int, lease *domain.Lease) *domain.Conversion {
+		return &domain.Conversion{
+			Conversion: domain.ConversionData{TargetFormat: ".stl", Status: status},
+			Job:        domain.ConversionJob{ID: "job-1", Attempt: attempt, Lease: lease},
+		}
+	}
+
+	tests := []struct {
+		name       string
+		conversion *domain.Conversion
+		jobID      string
+		attempt    int
+		claimed    bool
+	}{
+		{"first delivery", conversion(domain.ConversionPending, 0, nil), "job-1", 1, true},
+		{"delivery without job ID", conversion(domain.ConversionPending, 0, nil), "", 1, true},
+		{"duplicate while running", conversion(domain.ConversionInProgress, 1, held), "job-1", 1, false},
+		{"redelivery after the worker stopped", conversion(domain.ConversionInProgress, 1, nil), "job-1", 1, true},
+		{"redelivery after the lease expired", conversion(domain.ConversionInProgress, 1, expired), "job-1", 1, true},
+		{"duplicate of an attempt waiting for a retry", conversion(domain.ConversionPending, 1, nil), "job-1", 1, false},
+		{"retry of an attempt", conversion(domain.ConversionPending, 1, nil), "job-1", 2, true},
+		{"duplicate of an older attempt", conversion(domain.ConversionInProgress, 2, nil), "job-1", 1, false},
+		{"job replaced by a manual retry", conversion(domain.ConversionPending, 0, nil), "job-0", 1, false},
+		{"completed conversion", conversion(domain.ConversionCompleted, 1, nil), "job-1", 1, false},
+		{"failed conversion", conversion(domain.ConversionFailed, 1, nil), "job-1", 2, false},
+		{"cancelled conversion", conversion(domain.ConversionCancelled, 0, nil), "job-1", 1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			id, err := repo.CreateConversion(ctx, tt.conversion)
+			require.NoError(t, err)
+
+			lease := domain.Lease{Owner: "worker-b", Attempt: tt.attempt, AcquiredAt: now, ExpiresAt: now.Add(time.Minute)}
+			claimed, err := repo.ClaimJob(ctx, id, tt.jobID, lease)
+			require.NoError(t, err)
+			assert.Equal(t, tt.claimed, claimed)
+
+			stored, err := repo.GetConversionByID(ctx, id)
+			require.NoError(t, err)
+			if tt.claimed {
+				assert.Equal(t, domain.ConversionInProgress, stored.Conversion.Status)
+				assert.Equal(t, tt.attempt, stored.Job.Attempt)
+				assert.Equal(t, "worker-b", stored.Job.Lease.Owner)
+			} else {
+				assert.Equal(t, tt.conversion.Conversion.Status, stored.Conversion.Status)
+				assert.Equal(t, tt.conversion.Job.Attempt, stored.Job.Attempt)
+			}
+		})
+	}
+}
@@ -44,7 +44,7 @@ func TestMain(m *testing.M) {
 
 	cleanup()
 
-	app = api.Setup()
+	app = api.Setup(context.Background())
 
 	code := m.Run()
 
@@ -63,6 +63,11 @@ func cleanup() {
 		log.Fatalf("Failed to cleanup conversions collection: %v", err)
 	}
 
+	uploads := mongoClient.Database(config.AppConfig.MongoDbName).Collection(config.AppConfig.UploadCollection)
+	if _, err := uploads.DeleteMany(ctx, bson.M{}); err != nil {
+		log.Fatalf("Failed to cleanup uploads collection: %v", err)
+	}
+
 	if err := os.RemoveAll(config.AppConfig.BaseDirectory); err != nil {
 		log.Fatalf("Failed to remove base directory %s: %v", config.AppConfig.BaseDirectory, err)
 	}